@@ -2,34 +2,122 @@ package centipede
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/oklog/run"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/ralucas/centipede/internal/aggregate"
+	"github.com/ralucas/centipede/internal/dedup"
 	"github.com/ralucas/centipede/internal/extractor"
+	"github.com/ralucas/centipede/internal/filter"
+	"github.com/ralucas/centipede/internal/flatten"
 	"github.com/ralucas/centipede/internal/loader"
+	"github.com/ralucas/centipede/internal/metrics"
+	"github.com/ralucas/centipede/internal/preview"
+	"github.com/ralucas/centipede/internal/progress"
 	"github.com/ralucas/centipede/internal/streamreader"
-	"github.com/ralucas/centipede/internal/streamreader/custom"
+	// registers the custom source
+	_ "github.com/ralucas/centipede/internal/streamreader/custom"
+	"github.com/ralucas/centipede/internal/tracing"
 	"github.com/ralucas/centipede/internal/transformer"
 	"github.com/ralucas/centipede/pkg/etl"
+	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+var (
+	ErrNoFieldsDiscovered = errors.New("no fields discovered in the input")
+	ErrSinkFieldPatterns  = errors.New("fields of additional sinks can't be patterns")
+)
+
+// traceShutdownTimeout bounds how long exporting the last spans may take.
+const traceShutdownTimeout = 10 * time.Second
+
 type Config struct {
 	Verbose         bool
 	Validate        bool
 	ChunkSize       int
 	UseCustomParser bool
+	NormalizeDates  bool
+	DateLayout      string
+	Timezone        string
+	DiscoverSample  int
+	LateColumns     string
+	Filters         []string
+	Dedup           string
+	DedupKey        []string
+	DedupKeep       string
+	DedupLog        bool
+	DedupDir        string
+	DedupMemory     int
+	DedupExpected   int
+	GroupBy         []string
+	Aggregations    []string
+	AggMaxGroups    int
+	AggDir          string
+	SortBy          []string
+	SortMemory      int
+	SortDir         string
+	Workers         int
+	BatchSize       int
+	BatchTimeout    time.Duration
+	Ordered         bool
+	ReorderBuffer   int
+	OnError         string
+	MaxErrors       int
+	MaxErrorRate    string
+	RejectFile      string
+	ReportFile      string
+	MetricsAddr     string
+	Progress        bool
+	TraceExporter   string
+	TraceFile       string
+	TraceRecordRate float64
+	ReplayRejects   bool
+	Preview         int
+	StrictFields    bool
+	// Source, Extractor and Loader name registered components, the
+	// defaults are picked from the flags when a name is empty.
+	Source    Component
+	Extractor Component
+	Loader    Component
+	// Transforms are registered transformers applied after the built-in
+	// ones.
+	Transforms []Component
+	// Sinks are written alongside the output in the same pass over the
+	// input.
+	Sinks []Sink
+}
+
+// Component names a component registered with etl, with the settings its
+// config is decoded from.
+type Component struct {
+	Name    string
+	Options map[string]interface{}
+}
+
+// Sink is an additional output with fields of its own.
+type Sink struct {
+	Name   string
+	Output string
+	Fields []string
+	Loader Component
 }
 
-func newLogger(level zapcore.Level) *zap.Logger {
+func newLogger(level zapcore.Level, out zapcore.WriteSyncer) *zap.Logger {
 	lvl := zap.NewAtomicLevel()
 	logger := zap.New(zapcore.NewCore(
 		zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig()),
-		zapcore.Lock(os.Stdout),
+		zapcore.Lock(out),
 		lvl,
 	))
 
@@ -44,7 +132,13 @@ func Run(inputFile, outputFile string, fields []string, conf Config) error {
 		level = zapcore.DebugLevel
 	}
 
-	logger := newLogger(level)
+	// the preview table is printed on stdout, so its logs go to stderr
+	out := os.Stdout
+	if conf.Preview > 0 {
+		out = os.Stderr
+	}
+
+	logger := newLogger(level, out)
 
 	// syncing the logger flushes any buffered log entries.
 	defer logger.Sync()
@@ -57,7 +151,13 @@ func Run(inputFile, outputFile string, fields []string, conf Config) error {
 
 	logger.Info("Centripede is running...")
 
-	// Get file descriptors for the input and output files
+	policy, err := newErrorPolicy(conf)
+	if err != nil {
+		logger.Error("failed to parse error policy", zap.Error(err))
+		return err
+	}
+
+	// Get the file descriptor for the input file
 	input, err := os.Open(inputFile)
 	if err != nil {
 		logger.Error("failed to read input file", zap.Error(err))
@@ -66,6 +166,140 @@ func Run(inputFile, outputFile string, fields []string, conf Config) error {
 
 	defer input.Close()
 
+	// counted is read in place of input so the report has the bytes read
+	counted := streamreader.NewCountingReader(input)
+
+	sourceName, sourceConfig := newSourceConfig(conf)
+
+	newStreamIterator := func(r io.Reader) (etl.StreamIterator, error) {
+		return etl.NewSource(sourceName, r, sourceConfig, logger)
+	}
+
+	// fail on a bad source before any pass over the input
+	if err := etl.CheckComponent(etl.KindSource, sourceName, sourceConfig); err != nil {
+		logger.Error("failed to configure source", zap.String("source", sourceName), zap.Error(err))
+		return err
+	}
+
+	var keyer *dedup.Keyer
+	var keeper dedup.Keeper
+
+	if conf.Dedup != "" {
+		keyer = dedup.NewKeyer(conf.DedupKey)
+
+		var closer io.Closer
+		keeper, closer, err = newDedupKeeper(ctx, counted, keyer, newStreamIterator, policy, conf)
+		if err != nil {
+			logger.Error("failed to set up deduplication", zap.Error(err))
+			return err
+		}
+
+		defer closer.Close()
+	}
+
+	si, err := newStreamIterator(counted)
+	if err != nil {
+		logger.Error("failed to create source", zap.String("source", sourceName), zap.Error(err))
+		return err
+	}
+
+	var aggs []aggregate.Aggregation
+
+	if len(conf.GroupBy) > 0 {
+		aggs, err = aggregate.ParseAggregations(conf.Aggregations)
+		if err != nil {
+			logger.Error("failed to parse aggregations", zap.Error(err))
+			return err
+		}
+
+		// only what is grouped or aggregated needs extracting
+		fields = aggregate.Fields(conf.GroupBy, aggs)
+	}
+
+	var extractorConfig interface{} = conf.Extractor.Options
+
+	extractorName := conf.Extractor.Name
+	if extractorName == "" {
+		extractorName, extractorConfig = "map", nil
+		if flatten.IsPattern(fields) {
+			extractorName = "flatten"
+			extractorConfig = extractor.FlattenConfig{Patterns: fields, LateColumns: conf.LateColumns}
+		}
+	}
+
+	// only the map extractor reads fields as paths of the dataset schema
+	if extractorName == "map" {
+		checked := fields
+		for _, sink := range conf.Sinks {
+			if !flatten.IsPattern(sink.Fields) {
+				checked = append(checked[:len(checked):len(checked)], sink.Fields...)
+			}
+		}
+
+		if err := checkFields(checked, conf.StrictFields, logger); err != nil {
+			return err
+		}
+	}
+
+	ex, err := etl.NewExtractor(extractorName, extractorConfig, logger)
+	if err != nil {
+		logger.Error("failed to create extractor", zap.String("extractor", extractorName), zap.Error(err))
+		return err
+	}
+
+	if flatten.IsPattern(fields) {
+		patterns := fields
+
+		fields, si, err = discoverFields(ctx, counted, si, flatten.NewFilter(patterns), conf.DiscoverSample, policy, newStreamIterator)
+		if err != nil {
+			logger.Error("failed to discover fields", zap.Error(err))
+			return err
+		}
+
+		if len(fields) == 0 {
+			logger.Error("no fields matched the patterns", zap.Strings("patterns", patterns))
+			return ErrNoFieldsDiscovered
+		}
+
+		logger.Info("discovered fields", zap.Strings("fields", fields))
+	}
+
+	var dd *dedup.Iterator
+
+	if keeper != nil {
+		var dedupOpts []dedup.IteratorOption
+		if conf.DedupLog {
+			dedupOpts = append(dedupOpts, dedup.WithDropLogging())
+		}
+
+		dd = dedup.NewIterator(si, keyer, keeper, logger, dedupOpts...)
+		si = dd
+	}
+
+	var fi *filter.Iterator
+
+	if len(conf.Filters) > 0 {
+		predicate, err := filter.ParseAll(conf.Filters)
+		if err != nil {
+			logger.Error("failed to parse filters", zap.Error(err))
+			return err
+		}
+
+		fi = filter.NewIterator(si, predicate, logger)
+		si = fi
+	}
+
+	tf, closer, err := newTransformer(fields, conf, logger)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	// a preview writes nothing but the table
+	if conf.Preview > 0 {
+		return runPreview(ctx, ex, tf, preview.Limit(ctx, si, conf.Preview), counted, fields, policy, logger)
+	}
+
 	_, err = os.Stat(outputFile)
 	if os.IsExist(err) {
 		logger.Error("output file already exists")
@@ -80,37 +314,173 @@ func Run(inputFile, outputFile string, fields []string, conf Config) error {
 
 	defer output.Close()
 
-	var si etl.StreamIterator
+	ld, err := newLoader(conf.Loader, logger)
+	if err != nil {
+		return err
+	}
+
+	processorOpts := []etl.ETLProcessorOption{
+		etl.WithWorkers(conf.Workers),
+		etl.WithBatchSize(conf.BatchSize),
+		etl.WithBatchTimeout(conf.BatchTimeout),
+		etl.WithByteCounter(counted),
+		etl.WithErrorPolicy(policy),
+	}
+	var reg *prometheus.Registry
+	if conf.MetricsAddr != "" {
+		reg = prometheus.NewRegistry()
+		reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+		processorOpts = append(processorOpts, etl.WithInstrumentation(metrics.NewPrometheus(reg, metrics.WithBytesRead(counted))))
+	}
+
+	var reporter *progress.Reporter
+	if conf.Progress {
+		var size int64
+		if fi, err := input.Stat(); err == nil && fi.Mode().IsRegular() {
+			size = fi.Size()
+		}
+
+		var progressOpts []progress.ReporterOption
+		if progress.IsTerminal(os.Stderr) {
+			progressOpts = append(progressOpts, progress.WithTerminal(os.Stderr))
+		}
+
+		reporter = progress.NewReporter(counted, size, logger, progressOpts...)
+		processorOpts = append(processorOpts, etl.WithInstrumentation(reporter))
+	}
+
+	if conf.TraceExporter != "" {
+		exporter, err := tracing.ParseExporter(conf.TraceExporter)
+		if err != nil {
+			logger.Error("failed to parse trace exporter", zap.Error(err))
+			return err
+		}
+
+		var traces io.Writer
+		if exporter == tracing.ExporterFile {
+			f, err := os.Create(conf.TraceFile)
+			if err != nil {
+				logger.Error("failed to create trace file", zap.String("name", conf.TraceFile), zap.Error(err))
+				return err
+			}
+			defer f.Close()
 
-	if conf.UseCustomParser {
-		var custOpts []custom.JSONStreamReadIteratorOption
-		if conf.Validate {
-			custOpts = append(custOpts, custom.WithDatasetValidation())
+			traces = f
 		}
-		si = custom.NewCustomJSONStreamReadIterator(input, logger, custOpts...)
-	} else {
-		var readerOpts []streamreader.JSONStreamIteratorOption
-		if conf.Validate {
-			readerOpts = append(readerOpts, streamreader.WithDatasetValidation())
+
+		// export failures are reported through the otel error handler
+		otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+			logger.Warn("failed to export traces", zap.Error(err))
+		}))
+
+		tp, err := tracing.NewTracerProvider(ctx, exporter, traces)
+		if err != nil {
+			logger.Error("failed to set up tracing", zap.String("exporter", conf.TraceExporter), zap.Error(err))
+			return err
 		}
+		defer func() {
+			// flush the spans still buffered, even when the run was cancelled
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), traceShutdownTimeout)
+			defer cancel()
+			if err := tp.Shutdown(shutdownCtx); err != nil {
+				logger.Error("failed to flush traces", zap.Error(err))
+			}
+		}()
 
-		si = streamreader.NewJSONStreamIterator(input, logger, readerOpts...)
+		processorOpts = append(processorOpts, etl.WithTracerProvider(tp), etl.WithRecordSpanRate(conf.TraceRecordRate))
+	}
+
+	if conf.Ordered {
+		processorOpts = append(processorOpts, etl.WithOrderedOutput(conf.ReorderBuffer))
+	}
+
+	var branches []etl.Branch
+	for _, sink := range conf.Sinks {
+		sinkFields := sink.Fields
+		if len(conf.GroupBy) > 0 {
+			sinkFields = fields
+		} else if flatten.IsPattern(sinkFields) {
+			logger.Error("sink fields can't be patterns", zap.String("sink", sink.Name), zap.Strings("fields", sinkFields))
+			return fmt.Errorf("%w: %s", ErrSinkFieldPatterns, sink.Name)
+		}
+
+		out, err := os.Create(sink.Output)
+		if err != nil {
+			logger.Error("failed to create sink output file", zap.String("sink", sink.Name), zap.String("name", sink.Output), zap.Error(err))
+			return err
+		}
+		defer out.Close()
+
+		sinkEx := etl.Extractor(extractor.NewMapExtractor(logger))
+		if conf.Extractor.Name != "" {
+			sinkEx = ex
+		}
+
+		sinkTf, sinkCloser, err := newTransformer(sinkFields, conf, logger)
+		if err != nil {
+			return err
+		}
+		defer sinkCloser.Close()
+
+		sinkLd, err := newLoader(sink.Loader, logger)
+		if err != nil {
+			return err
+		}
+
+		logger.Info(fmt.Sprintf("Writing sink %s to %s", sink.Name, out.Name()))
+
+		branches = append(branches, etl.Branch{
+			Name:        sink.Name,
+			Extractor:   sinkEx,
+			Transformer: sinkTf,
+			Loader:      sinkLd,
+			Fields:      sinkFields,
+			Output:      out,
+		})
+	}
+
+	var rw *loader.RejectWriter
+	if conf.RejectFile != "" {
+		rejects, err := os.Create(conf.RejectFile)
+		if err != nil {
+			logger.Error("failed to create reject file", zap.String("name", conf.RejectFile), zap.Error(err))
+			return err
+		}
+		defer rejects.Close()
+
+		rw = loader.NewRejectWriter(rejects, logger)
 	}
 
 	processor := etl.NewETLProcessor(
-		extractor.NewMapExtractor(logger),
-		transformer.NewRowTransformer(logger),
-		loader.NewCSVLoader(logger),
+		ex,
+		tf,
+		ld,
 		si,
 		logger,
+		processorOpts...,
 	)
 
+	for _, b := range branches {
+		processor.WithBranch(b)
+	}
+	if rw != nil {
+		processor.WithRejecter(rw)
+	}
+
 	// Run groups provide an easy way to manage multiple goroutines
 	var g run.Group
 
 	g.Add(func() error {
 		logger.Info(fmt.Sprintf("Running the etl process from %s to %s", input.Name(), output.Name()))
-		return processor.Process(ctx, output, fields)
+		report, err := processor.Process(ctx, output, fields)
+		if conf.ReportFile != "" {
+			if werr := writeReport(conf.ReportFile, report); werr != nil {
+				logger.Error("failed to write run report", zap.String("name", conf.ReportFile), zap.Error(werr))
+				return errors.Join(err, werr)
+			}
+		}
+		return err
 	}, func(err error) {
 		if err != nil {
 			logger.Error("error in ETL processor, shutting down", zap.Error(err))
@@ -118,6 +488,26 @@ func Run(inputFile, outputFile string, fields []string, conf Config) error {
 		}
 	})
 
+	if reg != nil {
+		metricsCtx, stopMetrics := context.WithCancel(ctx)
+
+		g.Add(func() error {
+			return metrics.Serve(metricsCtx, conf.MetricsAddr, reg, logger)
+		}, func(err error) {
+			stopMetrics()
+		})
+	}
+
+	if reporter != nil {
+		progressCtx, stopProgress := context.WithCancel(ctx)
+
+		g.Add(func() error {
+			return reporter.Run(progressCtx)
+		}, func(err error) {
+			stopProgress()
+		})
+	}
+
 	// Create a watcher to handle SIGTERM or SIGINT and trigger graceful shutdown
 	signalc := make(chan os.Signal, 1)
 	signal.Notify(signalc, syscall.SIGTERM, syscall.SIGINT)
@@ -133,5 +523,204 @@ func Run(inputFile, outputFile string, fields []string, conf Config) error {
 		defer close(signalc)
 	})
 
-	return g.Run()
+	err = g.Run()
+
+	if dd != nil {
+		logger.Info("dropped duplicate records", zap.Int64("count", dd.Dropped()))
+	}
+
+	if fi != nil {
+		logger.Info("filtered out records", zap.Int64("count", fi.Dropped()))
+	}
+
+	if rw != nil {
+		logger.Info("rejected records", zap.Int64("count", rw.Rejected()), zap.String("file", conf.RejectFile))
+	}
+
+	return err
+}
+
+// discoverFields finds the leaf paths selected by the filter. A sample of 0
+// pre-scans the whole input and rewinds it for a fresh iterator, otherwise
+// the first records are buffered and replayed by the returned iterator. A
+// record failing to be read fails the pre-scan unless the policy skips
+// failed records, in which case the processing pass reports it.
+func discoverFields(
+	ctx context.Context,
+	input io.ReadSeeker,
+	si etl.StreamIterator,
+	filter *flatten.Filter,
+	sample int,
+	policy etl.ErrorPolicy,
+	newStreamIterator func(io.Reader) (etl.StreamIterator, error),
+) ([]string, etl.StreamIterator, error) {
+	d := flatten.NewDiscoverer(filter)
+
+	if sample > 0 {
+		bi := streamreader.NewBufferedIterator(si)
+		for _, obj := range bi.Fill(sample) {
+			d.Add(obj)
+		}
+
+		return d.Fields(), bi, nil
+	}
+
+	for obj, err := range etl.Records(ctx, si) {
+		if err != nil {
+			if ctx.Err() != nil || !policy.Skips() {
+				return nil, nil, err
+			}
+			continue
+		}
+		d.Add(obj)
+	}
+
+	if _, err := input.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+
+	si, err := newStreamIterator(input)
+
+	return d.Fields(), si, err
+}
+
+// newTransformer builds the chain of transformers for the rows of fields:
+// rows, or the aggregator when grouping, then date normalization, sorting
+// and any further transforms configured. The closer returned closes the
+// transformers holding resources once the run is over.
+func newTransformer(fields []string, conf Config, logger *zap.Logger) (etl.Transformer, io.Closer, error) {
+	type link struct {
+		name   string
+		config interface{}
+	}
+
+	chain := []link{{name: "rows"}}
+
+	if len(conf.GroupBy) > 0 {
+		chain[0] = link{name: "aggregate", config: aggregate.Config{
+			GroupBy:      conf.GroupBy,
+			Aggregations: conf.Aggregations,
+			MaxGroups:    conf.AggMaxGroups,
+			Dir:          conf.AggDir,
+		}}
+	}
+
+	if conf.NormalizeDates {
+		chain = append(chain, link{name: "normalize_dates", config: transformer.DateConfig{
+			Layout:   conf.DateLayout,
+			Timezone: conf.Timezone,
+		}})
+	}
+
+	if len(conf.SortBy) > 0 {
+		chain = append(chain, link{name: "sort", config: transformer.SortConfig{
+			By:        conf.SortBy,
+			MemoryMiB: conf.SortMemory,
+			Dir:       conf.SortDir,
+		}})
+	}
+
+	for _, c := range conf.Transforms {
+		chain = append(chain, link{name: c.Name, config: c.Options})
+	}
+
+	var tf etl.Transformer
+	var cs closers
+
+	for _, l := range chain {
+		next, err := etl.NewTransformer(l.name, tf, fields, l.config, logger)
+		if err != nil {
+			logger.Error("failed to create transformer", zap.String("transformer", l.name), zap.Strings("fields", fields), zap.Error(err))
+			cs.Close()
+			return nil, nil, err
+		}
+
+		if closer, ok := next.(io.Closer); ok {
+			cs = append(cs, closer)
+		}
+
+		tf = next
+	}
+
+	return tf, cs, nil
+}
+
+// closers closes each of its closers.
+type closers []io.Closer
+
+func (cs closers) Close() error {
+	var errs []error
+	for _, c := range cs {
+		errs = append(errs, c.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// newSourceConfig resolves the source to read the input with and its
+// config, which takes --validate and --chunk-size along.
+func newSourceConfig(conf Config) (string, map[string]interface{}) {
+	name := conf.Source.Name
+	switch {
+	case conf.ReplayRejects:
+		name = "rejects"
+	case conf.UseCustomParser:
+		name = "custom"
+	case name == "":
+		name = "json"
+	}
+
+	config := make(map[string]interface{}, len(conf.Source.Options)+2)
+	for k, v := range conf.Source.Options {
+		config[k] = v
+	}
+
+	if conf.Validate {
+		config["validate"] = true
+	}
+	if conf.ChunkSize > 0 {
+		config["chunk_size"] = conf.ChunkSize
+	}
+
+	return name, config
+}
+
+// newLoader builds the loader c names, csv when it names none.
+func newLoader(c Component, logger *zap.Logger) (etl.Loader, error) {
+	name := c.Name
+	if name == "" {
+		name = "csv"
+	}
+
+	ld, err := etl.NewLoader(name, c.Options, logger)
+	if err != nil {
+		logger.Error("failed to create loader", zap.String("loader", name), zap.Error(err))
+	}
+
+	return ld, err
+}
+
+func newErrorPolicy(conf Config) (etl.ErrorPolicy, error) {
+	mode, err := etl.ParseErrorMode(conf.OnError)
+	if err != nil {
+		return etl.ErrorPolicy{}, err
+	}
+
+	rate, err := etl.ParseErrorRate(conf.MaxErrorRate)
+	if err != nil {
+		return etl.ErrorPolicy{}, err
+	}
+
+	policy := etl.ErrorPolicy{Mode: mode, MaxErrors: conf.MaxErrors, MaxErrorRate: rate}
+
+	return policy, policy.Validate()
+}
+
+func writeReport(name string, report *etl.RunReport) error {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(name, append(b, '\n'), 0o644)
 }
@@ -0,0 +1,75 @@
+package centipede
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/ralucas/centipede/internal/dedup"
+	"github.com/ralucas/centipede/pkg/etl"
+)
+
+const mebibyte = 1 << 20
+
+// newDedupKeeper builds the keeper for the configured dedup mode. Keeping
+// the latest record needs a first pass over the input to index it, after
+// which the input is rewound.
+func newDedupKeeper(
+	ctx context.Context,
+	input io.ReadSeeker,
+	keyer *dedup.Keyer,
+	newStreamIterator func(io.Reader) (etl.StreamIterator, error),
+	policy etl.ErrorPolicy,
+	conf Config,
+) (dedup.Keeper, io.Closer, error) {
+	mode, err := dedup.ParseMode(conf.Dedup)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keep, err := dedup.ParseKeep(conf.DedupKeep)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if keep == dedup.KeepLatest {
+		if mode != dedup.ModeExact {
+			return nil, nil, fmt.Errorf("%w: keeping the latest record requires exact mode", dedup.ErrInvalidMode)
+		}
+
+		index, err := dedup.NewDiskMap(conf.DedupDir)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		si, err := newStreamIterator(input)
+		if err != nil {
+			index.Close()
+			return nil, nil, err
+		}
+
+		if err = dedup.IndexLatest(ctx, si, keyer, index, policy); err != nil {
+			index.Close()
+			return nil, nil, err
+		}
+
+		if _, err = input.Seek(0, io.SeekStart); err != nil {
+			index.Close()
+			return nil, nil, err
+		}
+
+		return dedup.Latest(index), index, nil
+	}
+
+	var set dedup.Set
+	if mode == dedup.ModeBloom {
+		set = dedup.NewBloomFilter(conf.DedupMemory*mebibyte, conf.DedupExpected)
+	} else {
+		set, err = dedup.NewDiskMap(conf.DedupDir)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return dedup.First(set), set, nil
+}
@@ -0,0 +1,190 @@
+package centipede
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ralucas/centipede/internal/preview"
+	"github.com/ralucas/centipede/internal/profile"
+	"github.com/ralucas/centipede/internal/schema"
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var ErrUnknownFormat = errors.New("unknown output format")
+
+// Formats the fields profile is printed in.
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+)
+
+type FieldsConfig struct {
+	Verbose bool
+	// Sample is the number of records profiled, 0 profiles the whole input.
+	Sample int
+	Format string
+	Source Component
+}
+
+// RunFields profiles the leaf paths of the records of inputFile and prints
+// the profile on stdout. Logs go to stderr so the profile can be piped.
+func RunFields(inputFile string, conf FieldsConfig) error {
+	level := zapcore.InfoLevel
+	if conf.Verbose {
+		level = zapcore.DebugLevel
+	}
+
+	logger := newLogger(level, os.Stderr)
+	defer logger.Sync()
+
+	if conf.Format != FormatTable && conf.Format != FormatJSON {
+		err := fmt.Errorf("%w %q, use %s or %s", ErrUnknownFormat, conf.Format, FormatTable, FormatJSON)
+		logger.Error("failed to parse format", zap.Error(err))
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	input, err := os.Open(inputFile)
+	if err != nil {
+		logger.Error("failed to read input file", zap.Error(err))
+		return err
+	}
+
+	defer input.Close()
+
+	sourceName, sourceConfig := newSourceConfig(Config{Source: conf.Source})
+
+	si, err := etl.NewSource(sourceName, input, sourceConfig, logger)
+	if err != nil {
+		logger.Error("failed to create source", zap.String("source", sourceName), zap.Error(err))
+		return err
+	}
+
+	if conf.Sample > 0 {
+		si = preview.Limit(ctx, si, conf.Sample)
+	}
+
+	profiler := profile.NewProfiler()
+
+	for obj, err := range etl.Records(ctx, si) {
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+
+			logger.Warn("failed to read record", zap.Error(err))
+			continue
+		}
+
+		profiler.Observe(obj)
+	}
+
+	prof := profiler.Profile()
+
+	logger.Info("profiled records", zap.Int("records", prof.Records), zap.Int("fields", len(prof.Fields)))
+
+	if conf.Format == FormatJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(prof)
+	}
+
+	return renderProfile(ctx, prof, os.Stdout)
+}
+
+// renderProfile prints a field per row of an aligned table.
+func renderProfile(ctx context.Context, prof profile.Profile, w io.Writer) error {
+	rows := [][]string{{"path", "types", "fill rate", "cardinality", "array", "examples"}}
+
+	for _, f := range prof.Fields {
+		cardinality := strconv.Itoa(f.Cardinality)
+		if !f.CardinalityExact {
+			cardinality = "~" + cardinality
+		}
+
+		array := ""
+		switch {
+		case f.FlattenOnly:
+			// the map extractor can't cross an array of objects
+			array = "flatten only"
+		case f.Array:
+			array = "yes"
+		}
+
+		rows = append(rows, []string{
+			f.Path,
+			strings.Join(f.Types, ","),
+			strconv.FormatFloat(f.FillRate*100, 'f', 1, 64) + "%",
+			cardinality,
+			array,
+			strings.Join(f.Examples, " | "),
+		})
+	}
+
+	table := preview.NewTable(preview.WithUncutColumns("path"))
+	if err := table.Load(ctx, rows, w); err != nil {
+		return err
+	}
+
+	return table.Render(w)
+}
+
+// checkFields warns about the fields naming no field of the dataset schema,
+// which would give empty columns, and fails on them when strict. Fields the
+// map extractor can't read, crossing an array of objects or ending at an
+// object, always fail.
+func checkFields(fields []string, strict bool, logger *zap.Logger) error {
+	var errs []error
+
+	for _, field := range fields {
+		err := schema.CheckPath(field)
+		if err == nil {
+			continue
+		}
+
+		var invalid *schema.InvalidPathError
+		if errors.As(err, &invalid) {
+			logFields := []zap.Field{zap.String("field", field), zap.String("reason", invalid.Reason)}
+			if invalid.Array != "" {
+				hint := fmt.Sprintf("read it with --extractor flatten or a pattern such as %s.*", invalid.Array)
+				logFields = append(logFields, zap.String("hint", hint))
+				err = fmt.Errorf("%w, %s", err, hint)
+			}
+
+			logger.Error("field path can't be read by the map extractor", logFields...)
+			errs = append(errs, err)
+			continue
+		}
+
+		var unknown *schema.UnknownPathError
+		if !errors.As(err, &unknown) {
+			return err
+		}
+
+		logFields := []zap.Field{zap.String("field", field), zap.String("reason", unknown.Reason)}
+		if unknown.Suggestion != "" {
+			logFields = append(logFields, zap.String("didYouMean", unknown.Suggestion))
+		}
+
+		if !strict {
+			logger.Warn("unknown field path, its column will be empty", logFields...)
+			continue
+		}
+
+		logger.Error("unknown field path", logFields...)
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,93 @@
+package centipede
+
+import (
+	"github.com/ralucas/centipede/internal/pipeline"
+)
+
+// RunPipeline runs the pipeline described by the file at path.
+func RunPipeline(path string, verbose bool) error {
+	p, err := pipeline.Load(path)
+	if err != nil {
+		return err
+	}
+
+	conf := pipelineConfig(p)
+	conf.Verbose = verbose
+
+	return Run(p.Source.Path, p.Sinks[0].Path, p.Fields, conf)
+}
+
+// pipelineConfig maps a pipeline onto the configuration of the flags.
+func pipelineConfig(p *pipeline.Pipeline) Config {
+	conf := Config{
+		Validate:        p.Source.Validate,
+		Source:          Component{Name: p.Source.Format, Options: p.Source.Options},
+		Loader:          Component{Name: p.Sinks[0].Format, Options: p.Sinks[0].Options},
+		DiscoverSample:  p.Discover.Sample,
+		LateColumns:     p.Discover.LateColumns,
+		Filters:         p.Filters,
+		Workers:         p.Concurrency.Workers,
+		BatchSize:       p.Concurrency.BatchSize,
+		BatchTimeout:    p.Concurrency.BatchTimeout,
+		Ordered:         p.Concurrency.Ordered,
+		ReorderBuffer:   p.Concurrency.ReorderBuffer,
+		OnError:         p.Errors.OnError,
+		MaxErrors:       p.Errors.MaxErrors,
+		MaxErrorRate:    string(p.Errors.MaxErrorRate),
+		RejectFile:      p.Errors.RejectFile,
+		ReportFile:      p.Report,
+		MetricsAddr:     p.MetricsAddr,
+		Progress:        p.Progress,
+		TraceExporter:   p.Trace.Exporter,
+		TraceFile:       p.Trace.File,
+		TraceRecordRate: p.Trace.RecordRate,
+	}
+
+	if p.Extractor != nil {
+		conf.Extractor = Component{Name: p.Extractor.Name, Options: p.Extractor.Options}
+	}
+
+	if d := p.Dedup; d != nil {
+		conf.Dedup = d.Mode
+		conf.DedupKey = d.Key
+		conf.DedupKeep = d.Keep
+		conf.DedupLog = d.Log
+		conf.DedupDir = d.Dir
+		conf.DedupMemory = d.MemoryMiB
+		conf.DedupExpected = d.Expected
+	}
+
+	if nd := p.Transforms.NormalizeDates; nd != nil {
+		conf.NormalizeDates = true
+		conf.DateLayout = nd.Layout
+		conf.Timezone = nd.Timezone
+	}
+
+	if agg := p.Transforms.Aggregate; agg != nil {
+		conf.GroupBy = agg.GroupBy
+		conf.Aggregations = agg.Aggregations
+		conf.AggMaxGroups = agg.MaxGroups
+		conf.AggDir = agg.Dir
+	}
+
+	if s := p.Transforms.Sort; s != nil {
+		conf.SortBy = s.By
+		conf.SortMemory = s.MemoryMiB
+		conf.SortDir = s.Dir
+	}
+
+	for _, c := range p.Transforms.Custom {
+		conf.Transforms = append(conf.Transforms, Component{Name: c.Name, Options: c.Options})
+	}
+
+	for _, s := range p.Sinks[1:] {
+		conf.Sinks = append(conf.Sinks, Sink{
+			Name:   s.Name,
+			Output: s.Path,
+			Fields: s.Fields,
+			Loader: Component{Name: s.Format, Options: s.Options},
+		})
+	}
+
+	return conf
+}
@@ -0,0 +1,48 @@
+package centipede
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/ralucas/centipede/internal/preview"
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+)
+
+// runPreview processes the records of si into a table printed on stdout,
+// warning about the columns no previewed record filled.
+func runPreview(
+	ctx context.Context,
+	ex etl.Extractor,
+	tf etl.Transformer,
+	si etl.StreamIterator,
+	counter etl.ByteCounter,
+	fields []string,
+	policy etl.ErrorPolicy,
+	logger *zap.Logger,
+) error {
+	table := preview.NewTable()
+
+	// a single worker keeps the rows in the order of the records
+	p := etl.NewETLProcessor(ex, tf, table, si, logger,
+		etl.WithWorkers(1),
+		etl.WithErrorPolicy(policy),
+		etl.WithByteCounter(counter),
+	)
+
+	if _, err := p.Process(ctx, io.Discard, fields); err != nil {
+		logger.Error("failed to preview", zap.Error(err))
+		return err
+	}
+
+	if err := table.Render(os.Stdout); err != nil {
+		return err
+	}
+
+	for _, col := range table.EmptyColumns() {
+		logger.Warn("field is empty in every previewed record, check its name", zap.String("field", col))
+	}
+
+	return nil
+}
@@ -1,7 +1,11 @@
 package cmd
 
 import (
+	"strings"
+	"time"
+
 	"github.com/ralucas/centipede/cmd/centipede"
+	"github.com/ralucas/centipede/pkg/etl"
 	"github.com/spf13/cobra"
 )
 
@@ -12,6 +16,48 @@ func Initialize() *cobra.Command {
 	var fields []string
 	var validate bool
 	var useCustomParser bool
+	var normalizeDates bool
+	var dateLayout string
+	var timezone string
+	var discoverSample int
+	var lateColumns string
+	var filters []string
+	var dedupMode string
+	var dedupKey []string
+	var dedupKeep string
+	var dedupLog bool
+	var dedupDir string
+	var dedupMemory int
+	var dedupExpected int
+	var groupBy []string
+	var aggregations []string
+	var aggMaxGroups int
+	var aggDir string
+	var sortBy []string
+	var sortMemory int
+	var sortDir string
+	var workers int
+	var batchSize int
+	var batchTimeout time.Duration
+	var ordered bool
+	var reorderBuffer int
+	var onError string
+	var maxErrors int
+	var maxErrorRate string
+	var rejectFile string
+	var reportFile string
+	var metricsAddr string
+	var showProgress bool
+	var traceExporter string
+	var traceFile string
+	var traceRecordRate float64
+	var replayRejects bool
+	var previewRecords int
+	var strictFields bool
+	var source string
+	var extractorName string
+	var transforms []string
+	var loaderName string
 
 	rootCmd := &cobra.Command{
 		Use:          "centipede",
@@ -22,6 +68,50 @@ func Initialize() *cobra.Command {
 				Validate:        validate,
 				Verbose:         verbose,
 				UseCustomParser: useCustomParser,
+				NormalizeDates:  normalizeDates,
+				DateLayout:      dateLayout,
+				Timezone:        timezone,
+				DiscoverSample:  discoverSample,
+				LateColumns:     lateColumns,
+				Filters:         filters,
+				Dedup:           dedupMode,
+				DedupKey:        dedupKey,
+				DedupKeep:       dedupKeep,
+				DedupLog:        dedupLog,
+				DedupDir:        dedupDir,
+				DedupMemory:     dedupMemory,
+				DedupExpected:   dedupExpected,
+				GroupBy:         groupBy,
+				Aggregations:    aggregations,
+				AggMaxGroups:    aggMaxGroups,
+				AggDir:          aggDir,
+				SortBy:          sortBy,
+				SortMemory:      sortMemory,
+				SortDir:         sortDir,
+				Workers:         workers,
+				BatchSize:       batchSize,
+				BatchTimeout:    batchTimeout,
+				Ordered:         ordered,
+				ReorderBuffer:   reorderBuffer,
+				OnError:         onError,
+				MaxErrors:       maxErrors,
+				MaxErrorRate:    maxErrorRate,
+				RejectFile:      rejectFile,
+				ReportFile:      reportFile,
+				MetricsAddr:     metricsAddr,
+				Progress:        showProgress,
+				TraceExporter:   traceExporter,
+				TraceFile:       traceFile,
+				TraceRecordRate: traceRecordRate,
+				ReplayRejects:   replayRejects,
+				Preview:         previewRecords,
+				StrictFields:    strictFields,
+				Source:          centipede.Component{Name: source},
+				Extractor:       centipede.Component{Name: extractorName},
+				Loader:          centipede.Component{Name: loaderName},
+			}
+			for _, name := range transforms {
+				conf.Transforms = append(conf.Transforms, centipede.Component{Name: name})
 			}
 			return centipede.Run(input, output, fields, conf)
 		},
@@ -36,13 +126,116 @@ func Initialize() *cobra.Command {
 		"fields",
 		"f",
 		[]string{"modified", "publisher.name", "publisher.subOrganizationOf.name", "contactPoint.fn", "keyword"},
-		"fields to extract from the input for the csv",
+		"fields to extract from the input for the csv, glob patterns such as '*' or 'publisher.**' discover leaf paths and '!' excludes",
 	)
+	rootCmd.Flags().BoolVar(&strictFields, "strict-fields", false, "fail on fields that are not paths of the dataset schema instead of warning about them")
 	rootCmd.Flags().BoolVarP(&validate, "validate", "d", false, "run check that dataset json objects are valid")
 	rootCmd.Flags().BoolVarP(&useCustomParser, "use-custom-parser", "c", false, "use custom parser")
+	rootCmd.Flags().IntVar(&discoverSample, "discover-sample", 1000, "records sampled to discover fields from patterns, 0 pre-scans the whole input")
+	rootCmd.Flags().StringVar(&lateColumns, "late-columns", "drop", "policy for columns discovered after the header is written: drop or fail")
+	rootCmd.Flags().StringArrayVar(&filters, "filter", nil, "keep only records matching every filter: field, !field, field=value, field!=value or field~regexp, e.g. accessLevel=public")
+	rootCmd.Flags().StringVar(&dedupMode, "dedup", "", "drop duplicate records: exact (disk-backed) or bloom (memory-bounded, probabilistic)")
+	rootCmd.Flags().StringSliceVar(&dedupKey, "dedup-key", nil, "fields identifying a duplicate, defaults to a hash of the whole record")
+	rootCmd.Flags().StringVar(&dedupKeep, "dedup-keep", "first", "which duplicate to keep: first or latest (by modified, exact mode only)")
+	rootCmd.Flags().BoolVar(&dedupLog, "dedup-log", false, "log every dropped duplicate")
+	rootCmd.Flags().StringVar(&dedupDir, "dedup-dir", "", "directory for the exact dedup index, defaults to the system temp dir")
+	rootCmd.Flags().IntVar(&dedupMemory, "dedup-memory", 64, "bloom filter size in MiB")
+	rootCmd.Flags().IntVar(&dedupExpected, "dedup-expected", 1000000, "expected distinct records, used to size the bloom filter hashes")
+	rootCmd.Flags().StringSliceVar(&groupBy, "group-by", nil, "fields to group by, emitting one aggregate row per group instead of one row per record")
+	rootCmd.Flags().StringSliceVar(&aggregations, "agg", []string{"count"}, "aggregations per group: count, count_distinct(f), sum(f), avg(f), min(f), max(f)")
+	rootCmd.Flags().IntVar(&aggMaxGroups, "agg-max-groups", 100000, "groups held in memory before spilling to disk")
+	rootCmd.Flags().StringVar(&aggDir, "agg-dir", "", "directory for spilled groups, defaults to the system temp dir")
+	rootCmd.Flags().StringSliceVar(&sortBy, "sort-by", nil, "output columns to sort rows by, e.g. modified:desc,identifier")
+	rootCmd.Flags().IntVar(&sortMemory, "sort-memory", 256, "memory budget in MiB for sorting before spilling to disk")
+	rootCmd.Flags().StringVar(&sortDir, "sort-dir", "", "directory for sorted runs, defaults to the system temp dir")
+	rootCmd.Flags().IntVar(&workers, "workers", 0, "records extracted and transformed concurrently, 0 uses one worker per CPU")
+	rootCmd.Flags().IntVar(&batchSize, "batch-size", 100, "records moved through the pipeline and written together")
+	rootCmd.Flags().DurationVar(&batchTimeout, "batch-timeout", time.Second, "how long a partial batch waits for more records before it is processed")
+	rootCmd.Flags().BoolVar(&ordered, "ordered", false, "write rows in input order while still transforming records concurrently")
+	rootCmd.Flags().IntVar(&reorderBuffer, "reorder-buffer", 1000, "records in flight in ordered mode, bounding rows held back by a slow record")
+	rootCmd.Flags().StringVar(&onError, "on-error", "fail", "what a failed record does to the run: fail, skip, or threshold (skip within --max-errors/--max-error-rate)")
+	rootCmd.Flags().IntVar(&maxErrors, "max-errors", 0, "failed records allowed before the run fails when skipping, 0 is unlimited")
+	rootCmd.Flags().StringVar(&maxErrorRate, "max-error-rate", "", "share of failed records allowed when skipping, e.g. 0.5% or 0.005, checked at the end of the run")
+	rootCmd.Flags().StringVar(&rejectFile, "reject-file", "", "write the raw json of every failed record, with its position, stage and error, to this ndjson file")
+	rootCmd.Flags().StringVar(&reportFile, "report", "", "write a json summary of the run, with counts per stage, errors and throughput, to this file")
+	rootCmd.Flags().BoolVar(&showProgress, "progress", false, "show bytes consumed, throughput, rows written, errors and ETA on stderr, or log them every 10s when stderr is not a terminal")
+	rootCmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "serve prometheus metrics on this address at /metrics while the run executes, e.g. :9090")
+	rootCmd.Flags().StringVar(&traceExporter, "trace", "", "export opentelemetry spans of the run: otlp (configured by OTEL_EXPORTER_OTLP_* variables), stdout or file")
+	rootCmd.Flags().StringVar(&traceFile, "trace-file", "traces.json", "file spans are written to with --trace file")
+	rootCmd.Flags().Float64Var(&traceRecordRate, "trace-record-rate", 1, "share of records given extract and transform spans, lower it for large inputs")
+	rootCmd.Flags().BoolVar(&replayRejects, "replay-rejects", false, "read the input as a reject file, replaying its records")
+	rootCmd.Flags().IntVar(&previewRecords, "preview", 0, "print the rows of the first N records as a table instead of writing the output file")
+	rootCmd.Flags().StringVar(&source, "source", "json", "source component reading the input: "+components(etl.KindSource))
+	rootCmd.Flags().StringVar(&extractorName, "extractor", "", "extractor component, defaults to map, or flatten for field patterns: "+components(etl.KindExtractor))
+	rootCmd.Flags().StringArrayVar(&transforms, "transform", nil, "transformer component applied after the built-in transforms, may be repeated: "+components(etl.KindTransformer))
+	rootCmd.Flags().StringVar(&loaderName, "loader", "csv", "loader component writing the output: "+components(etl.KindLoader))
+	rootCmd.Flags().BoolVar(&normalizeDates, "normalize-dates", false, "normalize modified, issued and temporal dates and decode accrualPeriodicity")
+	rootCmd.Flags().StringVar(&dateLayout, "date-layout", time.RFC3339, "go time layout for normalized dates")
+	rootCmd.Flags().StringVar(&timezone, "timezone", "UTC", "timezone for normalized dates")
 
 	// required flags
 	rootCmd.MarkFlagRequired("input")
 
+	rootCmd.AddCommand(newRunCommand())
+	rootCmd.AddCommand(newFieldsCommand())
+
 	return rootCmd
 }
+
+// components lists the names registered for kind.
+func components(kind etl.Kind) string {
+	return strings.Join(etl.Components(kind), ", ")
+}
+
+func newRunCommand() *cobra.Command {
+	var verbose bool
+	var file string
+
+	runCmd := &cobra.Command{
+		Use:          "run",
+		Short:        "run the pipeline described by a yaml file",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return centipede.RunPipeline(file, verbose)
+		},
+	}
+
+	runCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "verbose stdout logging (i.e. debug level)")
+	runCmd.Flags().StringVarP(&file, "file", "f", "pipeline.yaml", "pipeline file, validated against internal/pipeline/pipeline.schema.json")
+
+	return runCmd
+}
+
+func newFieldsCommand() *cobra.Command {
+	var verbose bool
+	var input string
+	var sample int
+	var format string
+	var source string
+
+	fieldsCmd := &cobra.Command{
+		Use:          "fields",
+		Short:        "profile the leaf paths of the input to choose --fields from",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return centipede.RunFields(input, centipede.FieldsConfig{
+				Verbose: verbose,
+				Sample:  sample,
+				Format:  format,
+				Source:  centipede.Component{Name: source},
+			})
+		},
+	}
+
+	fieldsCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "verbose stderr logging (i.e. debug level)")
+	fieldsCmd.Flags().StringVarP(&input, "input", "i", "", "input file")
+	fieldsCmd.Flags().IntVar(&sample, "sample", 0, "records profiled, 0 profiles the whole input")
+	fieldsCmd.Flags().StringVar(&format, "format", centipede.FormatTable, "output format: table or json")
+	fieldsCmd.Flags().StringVar(&source, "source", "json", "source component reading the input: "+components(etl.KindSource))
+
+	fieldsCmd.MarkFlagRequired("input")
+
+	return fieldsCmd
+}
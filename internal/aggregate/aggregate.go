@@ -0,0 +1,384 @@
+package aggregate
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxGroups = 100000
+	// flushBatch is the number of aggregate rows handed to emit at a time.
+	flushBatch = 1000
+)
+
+// group is the partial state of every aggregation for one group key.
+type group struct {
+	Key    []string `json:"k"`
+	States []*state `json:"s"`
+}
+
+// Aggregator is a transformer that folds records into per-group aggregate
+// state instead of emitting rows. The aggregate rows, sorted by group key,
+// are produced by Flush at the end of the stream. When more than maxGroups
+// groups are held in memory they are spilled to a sorted run file on disk
+// and merged back during Flush.
+type Aggregator struct {
+	mu        sync.Mutex
+	groupBy   []string
+	aggs      []Aggregation
+	groups    map[string]*group
+	maxGroups int
+	spillDir  string
+	runs      []string
+	logger    *zap.Logger
+}
+
+type AggregatorOption func(*Aggregator)
+
+// WithMaxGroups sets how many groups are held in memory before spilling.
+func WithMaxGroups(n int) AggregatorOption {
+	return func(a *Aggregator) {
+		if n > 0 {
+			a.maxGroups = n
+		}
+	}
+}
+
+// WithSpillDir sets the directory for spilled runs, defaults to the system
+// temporary directory.
+func WithSpillDir(dir string) AggregatorOption {
+	return func(a *Aggregator) {
+		a.spillDir = dir
+	}
+}
+
+func NewAggregator(groupBy []string, aggs []Aggregation, log *zap.Logger, opts ...AggregatorOption) *Aggregator {
+	a := &Aggregator{
+		groupBy:   groupBy,
+		aggs:      aggs,
+		groups:    make(map[string]*group),
+		maxGroups: defaultMaxGroups,
+		logger:    log,
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// Columns returns the group-by fields followed by the aggregation names.
+func (a *Aggregator) Columns(_ []string) []string {
+	columns := make([]string, 0, len(a.groupBy)+len(a.aggs))
+	columns = append(columns, a.groupBy...)
+	for _, agg := range a.aggs {
+		columns = append(columns, agg.Name())
+	}
+
+	return columns
+}
+
+// Transform folds the record into its groups and returns no rows. A list
+// value in a group-by field contributes the record to one group per item.
+func (a *Aggregator) Transform(ctx context.Context, data map[string]interface{}, _ []string) ([][]string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, key := range groupKeys(data, a.groupBy) {
+		id := encodeKey(key)
+
+		g, ok := a.groups[id]
+		if !ok {
+			g = &group{Key: key, States: make([]*state, len(a.aggs))}
+			for i := range g.States {
+				g.States[i] = &state{}
+			}
+			a.groups[id] = g
+		}
+
+		for i, agg := range a.aggs {
+			g.States[i].add(agg, values(data[agg.Field]))
+		}
+	}
+
+	if len(a.groups) > a.maxGroups {
+		if err := a.spill(); err != nil {
+			a.logger.Error("failed to spill groups", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// Flush emits the aggregate rows in group key order and releases any
+// spilled runs.
+func (a *Aggregator) Flush(ctx context.Context, emit func([][]string) error) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	defer a.cleanup()
+
+	if len(a.runs) == 0 {
+		ids := make([]string, 0, len(a.groups))
+		for id := range a.groups {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		rows := make([][]string, 0, flushBatch)
+		for _, id := range ids {
+			rows = append(rows, a.row(a.groups[id]))
+			if len(rows) == flushBatch {
+				if err := emit(rows); err != nil {
+					return err
+				}
+				rows = make([][]string, 0, flushBatch)
+			}
+		}
+
+		if len(rows) > 0 {
+			return emit(rows)
+		}
+
+		return nil
+	}
+
+	if len(a.groups) > 0 {
+		if err := a.spill(); err != nil {
+			return err
+		}
+	}
+
+	a.logger.Debug("merging spilled groups", zap.Int("runs", len(a.runs)))
+
+	return a.merge(ctx, emit)
+}
+
+func (a *Aggregator) row(g *group) []string {
+	row := make([]string, 0, len(g.Key)+len(a.aggs))
+	row = append(row, g.Key...)
+	for i, agg := range a.aggs {
+		row = append(row, g.States[i].result(agg))
+	}
+
+	return row
+}
+
+// spill writes the in-memory groups to a new run file sorted by key.
+func (a *Aggregator) spill() error {
+	f, err := os.CreateTemp(a.spillDir, "centipede-agg-*")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	a.runs = append(a.runs, f.Name())
+
+	ids := make([]string, 0, len(a.groups))
+	for id := range a.groups {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, id := range ids {
+		if err = enc.Encode(a.groups[id]); err != nil {
+			return err
+		}
+	}
+
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	a.logger.Debug("spilled groups to disk", zap.Int("groups", len(a.groups)), zap.String("run", f.Name()))
+
+	a.groups = make(map[string]*group)
+
+	return nil
+}
+
+// merge k-way merges the sorted runs, combining partial states of the same
+// group.
+func (a *Aggregator) merge(ctx context.Context, emit func([][]string) error) error {
+	h := &runHeap{}
+
+	for _, name := range a.runs {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		r := &run{dec: json.NewDecoder(bufio.NewReader(f))}
+		if ok, err := r.advance(); err != nil {
+			return err
+		} else if ok {
+			heap.Push(h, r)
+		}
+	}
+
+	rows := make([][]string, 0, flushBatch)
+
+	var cur *group
+	var curID string
+	for h.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r := (*h)[0]
+		g, id := r.head, r.id
+
+		if ok, err := r.advance(); err != nil {
+			return err
+		} else if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+
+		if cur != nil && id == curID {
+			for i := range cur.States {
+				cur.States[i].merge(g.States[i])
+			}
+			continue
+		}
+
+		if cur != nil {
+			rows = append(rows, a.row(cur))
+			if len(rows) == flushBatch {
+				if err := emit(rows); err != nil {
+					return err
+				}
+				rows = make([][]string, 0, flushBatch)
+			}
+		}
+		cur, curID = g, id
+	}
+
+	if cur != nil {
+		rows = append(rows, a.row(cur))
+	}
+
+	if len(rows) > 0 {
+		return emit(rows)
+	}
+
+	return nil
+}
+
+func (a *Aggregator) cleanup() {
+	for _, name := range a.runs {
+		if err := os.Remove(name); err != nil {
+			a.logger.Warn("failed to remove spilled run", zap.String("run", name), zap.Error(err))
+		}
+	}
+
+	a.runs = nil
+	a.groups = make(map[string]*group)
+}
+
+// run is a cursor over one spilled run file.
+type run struct {
+	dec  *json.Decoder
+	head *group
+	id   string
+}
+
+func (r *run) advance() (bool, error) {
+	var g group
+	if err := r.dec.Decode(&g); err != nil {
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	r.head, r.id = &g, encodeKey(g.Key)
+
+	return true, nil
+}
+
+type runHeap []*run
+
+func (h runHeap) Len() int           { return len(h) }
+func (h runHeap) Less(i, j int) bool { return h[i].id < h[j].id }
+func (h runHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x any)        { *h = append(*h, x.(*run)) }
+func (h *runHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// encodeKey joins key values so that sorting the encodings sorts the keys.
+func encodeKey(key []string) string {
+	b, _ := json.Marshal(key)
+	return string(b)
+}
+
+// groupKeys returns the cartesian product of the group-by field values.
+func groupKeys(data map[string]interface{}, groupBy []string) [][]string {
+	keys := [][]string{{}}
+
+	for _, field := range groupBy {
+		vals := values(data[field])
+		if len(vals) == 0 {
+			vals = []string{""}
+		}
+
+		next := make([][]string, 0, len(keys)*len(vals))
+		for _, k := range keys {
+			for _, v := range vals {
+				key := make([]string, len(k), len(k)+1)
+				copy(key, k)
+				next = append(next, append(key, v))
+			}
+		}
+		keys = next
+	}
+
+	return keys
+}
+
+// values renders an extracted value as strings, one per list item.
+func values(v interface{}) []string {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{val}
+	case float64:
+		return []string{strconv.FormatFloat(val, 'f', -1, 64)}
+	case bool:
+		return []string{strconv.FormatBool(val)}
+	case []interface{}:
+		var vals []string
+		for _, item := range val {
+			vals = append(vals, values(item)...)
+		}
+		return vals
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return nil
+		}
+		return []string{string(b)}
+	}
+}
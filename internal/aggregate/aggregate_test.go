@@ -0,0 +1,102 @@
+//go:build unit
+
+package aggregate_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/ralucas/centipede/internal/aggregate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAggregator(t *testing.T) {
+	log, err := zap.NewDevelopment()
+	require.NoError(t, err)
+
+	aggs, err := aggregate.ParseAggregations([]string{
+		"count", "max(modified)", "min(modified)", "count_distinct(keyword)", "sum(size)", "avg(size)",
+	})
+	require.NoError(t, err)
+
+	groupBy := []string{"publisher.name", "accessLevel"}
+
+	records := []map[string]interface{}{
+		{"publisher.name": "GSA", "accessLevel": "public", "modified": "2019-06-12", "keyword": []interface{}{"a", "b"}, "size": "10"},
+		{"publisher.name": "GSA", "accessLevel": "public", "modified": "2021-03-30T15:14:53.668Z", "keyword": []interface{}{"b", "c"}, "size": "20"},
+		{"publisher.name": "GSA", "accessLevel": "non-public", "modified": "2017-05-15", "keyword": []interface{}{"a"}, "size": ""},
+		{"publisher.name": "NASA", "accessLevel": "public", "modified": "2020-01-01", "keyword": []interface{}{}, "size": "5"},
+	}
+
+	expect := [][]string{
+		{"GSA", "non-public", "1", "2017-05-15", "2017-05-15", "1", "0", ""},
+		{"GSA", "public", "2", "2021-03-30T15:14:53.668Z", "2019-06-12", "3", "30", "15"},
+		{"NASA", "public", "1", "2020-01-01", "2020-01-01", "0", "5", "5"},
+	}
+
+	run := func(t *testing.T, opts ...aggregate.AggregatorOption) [][]string {
+		a := aggregate.NewAggregator(groupBy, aggs, log, opts...)
+
+		for _, r := range records {
+			rows, err := a.Transform(context.TODO(), r, nil)
+			require.NoError(t, err)
+			assert.Empty(t, rows)
+		}
+
+		var result [][]string
+		err := a.Flush(context.TODO(), func(rows [][]string) error {
+			result = append(result, rows...)
+			return nil
+		})
+		require.NoError(t, err)
+
+		return result
+	}
+
+	t.Run("columns", func(t *testing.T) {
+		a := aggregate.NewAggregator(groupBy, aggs, log)
+
+		assert.Equal(t, []string{
+			"publisher.name", "accessLevel", "count", "max(modified)", "min(modified)",
+			"count_distinct(keyword)", "sum(size)", "avg(size)",
+		}, a.Columns(nil))
+	})
+
+	t.Run("in memory", func(t *testing.T) {
+		assert.Equal(t, expect, run(t))
+	})
+
+	t.Run("spills to disk", func(t *testing.T) {
+		dir := t.TempDir()
+
+		assert.Equal(t, expect, run(t, aggregate.WithMaxGroups(1), aggregate.WithSpillDir(dir)))
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("explodes list group-by values", func(t *testing.T) {
+		a := aggregate.NewAggregator([]string{"keyword"}, aggs[:1], log)
+
+		for _, r := range records {
+			_, err := a.Transform(context.TODO(), r, nil)
+			require.NoError(t, err)
+		}
+
+		var result []string
+		err := a.Flush(context.TODO(), func(rows [][]string) error {
+			for _, row := range rows {
+				result = append(result, fmt.Sprintf("%s=%s", row[0], row[1]))
+			}
+			return nil
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"=1", "a=2", "b=2", "c=1"}, result)
+	})
+}
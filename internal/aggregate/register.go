@@ -0,0 +1,42 @@
+package aggregate
+
+import (
+	"fmt"
+
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+)
+
+// Config configures the aggregate transformer.
+type Config struct {
+	GroupBy []string `json:"group_by"`
+	// Aggregations are specs such as count or max(modified), defaults to
+	// count.
+	Aggregations []string `json:"aggregations"`
+	MaxGroups    int      `json:"max_groups"`
+	Dir          string   `json:"dir"`
+}
+
+func init() {
+	etl.RegisterTransformer("aggregate", func(next etl.Transformer, _ []string, conf Config, log *zap.Logger) (etl.Transformer, error) {
+		if next != nil {
+			return nil, fmt.Errorf("%w: aggregate must start the chain", etl.ErrInvalidChain)
+		}
+
+		if len(conf.GroupBy) == 0 {
+			return nil, fmt.Errorf("%w: aggregate needs group_by", etl.ErrInvalidComponentConfig)
+		}
+
+		specs := conf.Aggregations
+		if len(specs) == 0 {
+			specs = []string{"count"}
+		}
+
+		aggs, err := ParseAggregations(specs)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewAggregator(conf.GroupBy, aggs, log, WithMaxGroups(conf.MaxGroups), WithSpillDir(conf.Dir)), nil
+	})
+}
@@ -0,0 +1,93 @@
+package aggregate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidAggregation = errors.New("invalid aggregation")
+
+// Func is an aggregate function name.
+type Func string
+
+const (
+	Count         Func = "count"
+	CountDistinct Func = "count_distinct"
+	Sum           Func = "sum"
+	Avg           Func = "avg"
+	Min           Func = "min"
+	Max           Func = "max"
+)
+
+// Aggregation is a parsed aggregate such as max(modified).
+type Aggregation struct {
+	Func  Func
+	Field string
+}
+
+// Name is the output column name, e.g. count or max(modified).
+func (a Aggregation) Name() string {
+	if a.Field == "" {
+		return string(a.Func)
+	}
+
+	return fmt.Sprintf("%s(%s)", a.Func, a.Field)
+}
+
+// ParseAggregations parses aggregate specs like count, max(modified) or
+// count_distinct(keyword). Only count may be used without a field.
+func ParseAggregations(specs []string) ([]Aggregation, error) {
+	aggs := make([]Aggregation, 0, len(specs))
+
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+
+		name, field := spec, ""
+		if open := strings.Index(spec, "("); open >= 0 {
+			if !strings.HasSuffix(spec, ")") {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidAggregation, spec)
+			}
+			name, field = spec[:open], strings.TrimSpace(spec[open+1:len(spec)-1])
+		}
+
+		agg := Aggregation{Func: Func(strings.ToLower(name)), Field: field}
+
+		switch agg.Func {
+		case Count:
+		case CountDistinct, Sum, Avg, Min, Max:
+			if agg.Field == "" {
+				return nil, fmt.Errorf("%w: %q requires a field", ErrInvalidAggregation, spec)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown function %q", ErrInvalidAggregation, name)
+		}
+
+		aggs = append(aggs, agg)
+	}
+
+	return aggs, nil
+}
+
+// Fields returns the fields that need to be extracted for the group-by
+// fields and aggregations, without duplicates.
+func Fields(groupBy []string, aggs []Aggregation) []string {
+	seen := make(map[string]bool)
+
+	var fields []string
+	for _, f := range groupBy {
+		if !seen[f] {
+			seen[f] = true
+			fields = append(fields, f)
+		}
+	}
+
+	for _, a := range aggs {
+		if a.Field != "" && !seen[a.Field] {
+			seen[a.Field] = true
+			fields = append(fields, a.Field)
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,55 @@
+//go:build unit
+
+package aggregate_test
+
+import (
+	"testing"
+
+	"github.com/ralucas/centipede/internal/aggregate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAggregations(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		aggs, err := aggregate.ParseAggregations([]string{"count", "max(modified)", "COUNT_DISTINCT( keyword )"})
+		require.NoError(t, err)
+
+		assert.Equal(t, []aggregate.Aggregation{
+			{Func: aggregate.Count},
+			{Func: aggregate.Max, Field: "modified"},
+			{Func: aggregate.CountDistinct, Field: "keyword"},
+		}, aggs)
+		assert.Equal(t, "count_distinct(keyword)", aggs[2].Name())
+	})
+
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{name: "fails on unknown function", spec: "median(modified)"},
+		{name: "fails on missing field", spec: "max"},
+		{name: "fails on unclosed paren", spec: "max(modified"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := aggregate.ParseAggregations([]string{test.spec})
+			assert.ErrorIs(t, err, aggregate.ErrInvalidAggregation)
+		})
+	}
+}
+
+func TestFields(t *testing.T) {
+	aggs := []aggregate.Aggregation{
+		{Func: aggregate.Count},
+		{Func: aggregate.Max, Field: "modified"},
+		{Func: aggregate.Min, Field: "modified"},
+		{Func: aggregate.CountDistinct, Field: "publisher.name"},
+	}
+
+	assert.Equal(t,
+		[]string{"publisher.name", "accessLevel", "modified"},
+		aggregate.Fields([]string{"publisher.name", "accessLevel"}, aggs),
+	)
+}
@@ -0,0 +1,106 @@
+package aggregate
+
+import (
+	"strconv"
+
+	"github.com/ralucas/centipede/internal/collate"
+)
+
+// state is the partial result of one aggregation for one group. It is
+// serialized when groups are spilled to disk and merged back afterwards.
+type state struct {
+	Records  int64           `json:"r,omitempty"`
+	Numbers  int64           `json:"n,omitempty"`
+	Sum      float64         `json:"s,omitempty"`
+	Min      *string         `json:"lo,omitempty"`
+	Max      *string         `json:"hi,omitempty"`
+	Distinct map[string]bool `json:"d,omitempty"`
+}
+
+func (s *state) add(agg Aggregation, values []string) {
+	s.Records++
+
+	switch agg.Func {
+	case CountDistinct:
+		if s.Distinct == nil {
+			s.Distinct = make(map[string]bool)
+		}
+		for _, v := range values {
+			if v != "" {
+				s.Distinct[v] = true
+			}
+		}
+	case Sum, Avg:
+		for _, v := range values {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				s.Numbers++
+				s.Sum += f
+			}
+		}
+	case Min, Max:
+		for _, v := range values {
+			if v != "" {
+				s.observe(v)
+			}
+		}
+	}
+}
+
+func (s *state) observe(v string) {
+	if s.Min == nil || collate.Compare(v, *s.Min) < 0 {
+		lo := v
+		s.Min = &lo
+	}
+	if s.Max == nil || collate.Compare(v, *s.Max) > 0 {
+		hi := v
+		s.Max = &hi
+	}
+}
+
+func (s *state) merge(o *state) {
+	s.Records += o.Records
+	s.Numbers += o.Numbers
+	s.Sum += o.Sum
+
+	if o.Min != nil {
+		s.observe(*o.Min)
+	}
+	if o.Max != nil {
+		s.observe(*o.Max)
+	}
+
+	if len(o.Distinct) > 0 && s.Distinct == nil {
+		s.Distinct = make(map[string]bool, len(o.Distinct))
+	}
+	for v := range o.Distinct {
+		s.Distinct[v] = true
+	}
+}
+
+func (s *state) result(agg Aggregation) string {
+	switch agg.Func {
+	case Count:
+		return strconv.FormatInt(s.Records, 10)
+	case CountDistinct:
+		return strconv.Itoa(len(s.Distinct))
+	case Sum:
+		return strconv.FormatFloat(s.Sum, 'f', -1, 64)
+	case Avg:
+		if s.Numbers == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(s.Sum/float64(s.Numbers), 'f', -1, 64)
+	case Min:
+		if s.Min == nil {
+			return ""
+		}
+		return *s.Min
+	case Max:
+		if s.Max == nil {
+			return ""
+		}
+		return *s.Max
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,67 @@
+package collate
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ralucas/centipede/internal/iso8601"
+)
+
+type kind int
+
+const (
+	text kind = iota
+	number
+	date
+)
+
+// Value is a string parsed once for repeated comparisons.
+type Value struct {
+	kind kind
+	num  float64
+	t    time.Time
+	s    string
+}
+
+// Parse detects whether s is a number or an ISO 8601 date.
+func Parse(s string) Value {
+	v := Value{s: s}
+
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		v.kind, v.num = number, f
+	} else if t, err := iso8601.ParseTime(s); err == nil {
+		v.kind, v.t = date, t
+	}
+
+	return v
+}
+
+// Compare orders values numerically when both are numbers, chronologically
+// when both are ISO 8601 dates, and lexically otherwise.
+func (v Value) Compare(o Value) int {
+	switch {
+	case v.kind == number && o.kind == number:
+		switch {
+		case v.num < o.num:
+			return -1
+		case v.num > o.num:
+			return 1
+		default:
+			return 0
+		}
+	case v.kind == date && o.kind == date:
+		return v.t.Compare(o.t)
+	default:
+		return strings.Compare(v.s, o.s)
+	}
+}
+
+func (v Value) String() string {
+	return v.s
+}
+
+// Compare parses and compares two strings, see Value.Compare.
+func Compare(a, b string) int {
+	return Parse(a).Compare(Parse(b))
+}
@@ -0,0 +1,32 @@
+//go:build unit
+
+package collate_test
+
+import (
+	"testing"
+
+	"github.com/ralucas/centipede/internal/collate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name   string
+		a      string
+		b      string
+		expect int
+	}{
+		{name: "numbers compare numerically", a: "9", b: "10", expect: -1},
+		{name: "dates compare chronologically", a: "2021-03-30T15:14:53.668Z", b: "2021-03-30", expect: 1},
+		{name: "dates across zones", a: "2021-03-30T01:00:00+02:00", b: "2021-03-30T00:00:00Z", expect: -1},
+		{name: "text compares lexically", a: "b", b: "a", expect: 1},
+		{name: "mixed kinds compare lexically", a: "10", b: "abc", expect: -1},
+		{name: "equal", a: "x", b: "x", expect: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expect, collate.Compare(test.a, test.b))
+		})
+	}
+}
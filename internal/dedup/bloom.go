@@ -0,0 +1,68 @@
+package dedup
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+const maxHashes = 16
+
+// BloomFilter is a fixed-size probabilistic set. Its memory use never grows,
+// at the cost of occasionally reporting an unseen digest as a duplicate.
+type BloomFilter struct {
+	bits []uint64
+	m    uint64
+	k    uint64
+}
+
+// NewBloomFilter allocates a filter of sizeBytes, choosing the number of
+// hash functions that minimizes false positives for the expected number of
+// distinct records.
+func NewBloomFilter(sizeBytes, expected int) *BloomFilter {
+	words := sizeBytes / 8
+	if words < 1 {
+		words = 1
+	}
+	m := uint64(words) * 64
+
+	k := uint64(1)
+	if expected > 0 {
+		k = uint64(math.Round(float64(m) / float64(expected) * math.Ln2))
+	}
+	k = min(max(k, 1), maxHashes)
+
+	return &BloomFilter{
+		bits: make([]uint64, words),
+		m:    m,
+		k:    k,
+	}
+}
+
+// Add sets the digest's bits and reports whether they were all set already,
+// i.e. whether the digest was probably seen before.
+func (b *BloomFilter) Add(d Digest) (bool, error) {
+	h1 := binary.LittleEndian.Uint64(d[:8])
+	h2 := binary.LittleEndian.Uint64(d[8:]) | 1
+
+	seen := true
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if b.bits[word]&mask == 0 {
+			seen = false
+			b.bits[word] |= mask
+		}
+	}
+
+	return seen, nil
+}
+
+// FalsePositiveRate estimates the probability that an unseen digest is
+// reported as a duplicate after n distinct additions.
+func (b *BloomFilter) FalsePositiveRate(n int) float64 {
+	return math.Pow(1-math.Exp(-float64(b.k)*float64(n)/float64(b.m)), float64(b.k))
+}
+
+func (b *BloomFilter) Close() error {
+	return nil
+}
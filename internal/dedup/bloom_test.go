@@ -0,0 +1,44 @@
+//go:build unit
+
+package dedup_test
+
+import (
+	"testing"
+
+	"github.com/ralucas/centipede/internal/dedup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBloomFilter(t *testing.T) {
+	const n = 10000
+
+	b := dedup.NewBloomFilter(64*1024, n)
+
+	for i := 0; i < n; i++ {
+		seen, err := b.Add(digest(i))
+		require.NoError(t, err)
+		if seen {
+			t.Logf("false positive at %d", i)
+		}
+	}
+
+	// no false negatives
+	for i := 0; i < n; i++ {
+		seen, err := b.Add(digest(i))
+		require.NoError(t, err)
+		require.True(t, seen)
+	}
+
+	falsePositives := 0
+	for i := n; i < 2*n; i++ {
+		seen, err := b.Add(digest(i))
+		require.NoError(t, err)
+		if seen {
+			falsePositives++
+		}
+	}
+
+	assert.Less(t, b.FalsePositiveRate(n), 0.01)
+	assert.Less(t, float64(falsePositives)/n, 0.05)
+}
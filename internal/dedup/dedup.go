@@ -0,0 +1,347 @@
+package dedup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ralucas/centipede/internal/iso8601"
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidMode = errors.New("invalid dedup mode")
+	// ErrIndex is a failure of the index of the latest records, which is
+	// never skipped.
+	ErrIndex = errors.New("failed to index latest record")
+	// ErrNoKey is a record missing a value for a key field, which is never
+	// a duplicate.
+	ErrNoKey = errors.New("record has no dedup key")
+)
+
+// Mode selects how seen keys are remembered.
+type Mode string
+
+const (
+	// ModeExact remembers every key in a DiskMap.
+	ModeExact Mode = "exact"
+	// ModeBloom remembers keys in a fixed-size BloomFilter.
+	ModeBloom Mode = "bloom"
+)
+
+// Keep selects which record of a duplicate group survives.
+type Keep string
+
+const (
+	// KeepFirst keeps the first record seen for a key.
+	KeepFirst Keep = "first"
+	// KeepLatest keeps the record with the greatest modified date.
+	KeepLatest Keep = "latest"
+)
+
+// ParseMode validates a mode name.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case ModeExact, ModeBloom:
+		return m, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidMode, s)
+	}
+}
+
+// ParseKeep validates a keep policy name.
+func ParseKeep(s string) (Keep, error) {
+	switch k := Keep(s); k {
+	case KeepFirst, KeepLatest:
+		return k, nil
+	default:
+		return "", fmt.Errorf("%w: keep %q", ErrInvalidMode, s)
+	}
+}
+
+// Digest identifies a record key. A digest is never all zeroes, which
+// DiskMap uses to mark empty slots.
+type Digest [16]byte
+
+// Set remembers digests, reporting whether a digest was added before.
+type Set interface {
+	Add(d Digest) (bool, error)
+	Close() error
+}
+
+// Keyer computes the dedup key of a raw record from a set of field paths,
+// or from the whole record when no fields are given. A record missing any
+// of the fields, or with an empty value for one, has no key.
+type Keyer struct {
+	fields [][]string
+}
+
+func NewKeyer(fields []string) *Keyer {
+	k := &Keyer{}
+	for _, f := range fields {
+		k.fields = append(k.fields, strings.Split(f, "."))
+	}
+
+	return k
+}
+
+func (k *Keyer) Key(record map[string]interface{}) (Digest, error) {
+	var b []byte
+	var err error
+
+	if len(k.fields) == 0 {
+		// map keys are marshaled in sorted order so this is stable
+		b, err = json.Marshal(record)
+	} else {
+		values := make([]interface{}, len(k.fields))
+		for i, keys := range k.fields {
+			if values[i] = lookup(record, keys); isBlank(values[i]) {
+				return Digest{}, fmt.Errorf("%w: %s is empty", ErrNoKey, strings.Join(keys, "."))
+			}
+		}
+		b, err = json.Marshal(values)
+	}
+	if err != nil {
+		return Digest{}, err
+	}
+
+	sum := sha256.Sum256(b)
+
+	var d Digest
+	copy(d[:], sum[:])
+	d[len(d)-1] |= 1
+
+	return d, nil
+}
+
+func lookup(record map[string]interface{}, keys []string) interface{} {
+	var cur interface{} = record
+	for _, key := range keys {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = m[key]
+	}
+
+	return cur
+}
+
+func isBlank(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case []interface{}:
+		return len(val) == 0
+	case map[string]interface{}:
+		return len(val) == 0
+	default:
+		return false
+	}
+}
+
+// Keeper decides whether the record at the given input position survives.
+type Keeper interface {
+	Keep(seq uint64, d Digest) (bool, error)
+}
+
+type firstKeeper struct {
+	set Set
+}
+
+// First keeps the first record of each key, remembering keys in set.
+func First(set Set) Keeper {
+	return &firstKeeper{set: set}
+}
+
+func (f *firstKeeper) Keep(_ uint64, d Digest) (bool, error) {
+	seen, err := f.set.Add(d)
+	return !seen, err
+}
+
+type latestKeeper struct {
+	index *DiskMap
+}
+
+// Latest keeps the record an IndexLatest pass chose for each key.
+func Latest(index *DiskMap) Keeper {
+	return &latestKeeper{index: index}
+}
+
+func (l *latestKeeper) Keep(seq uint64, d Digest) (bool, error) {
+	v, ok, err := l.index.Get(d)
+	if err != nil || !ok {
+		return ok, err
+	}
+
+	_, latest := decodeLatest(v)
+
+	return latest == seq, nil
+}
+
+// IndexLatest reads the whole stream and records, for every key, the
+// position of the record with the greatest modified date. Ties go to the
+// later record. Records whose modified date can't be parsed sort first.
+// Records without a key are left out of the index. A record failing to be
+// read or keyed fails the pass unless the policy skips failed records, in
+// which case the processing pass reports it.
+func IndexLatest(ctx context.Context, si etl.StreamIterator, keyer *Keyer, index *DiskMap, policy etl.ErrorPolicy) error {
+	var seq uint64
+	for obj, err := range etl.Records(ctx, si) {
+		// the position is taken before any error so it matches the
+		// positions the Iterator counts
+		cur := seq
+		seq++
+
+		if err == nil {
+			err = indexLatest(obj, cur, keyer, index)
+		}
+
+		if err == nil || errors.Is(err, ErrNoKey) {
+			continue
+		}
+
+		if ctx.Err() != nil || errors.Is(err, ErrIndex) || !policy.Skips() {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func indexLatest(obj map[string]interface{}, seq uint64, keyer *Keyer, index *DiskMap) error {
+	d, err := keyer.Key(obj)
+	if err != nil {
+		return err
+	}
+
+	modified := modifiedNanos(obj)
+
+	v, ok, err := index.Get(d)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrIndex, err)
+	}
+
+	if cur, _ := decodeLatest(v); !ok || modified >= cur {
+		if err = index.Put(d, encodeLatest(modified, seq)); err != nil {
+			return fmt.Errorf("%w: %w", ErrIndex, err)
+		}
+	}
+
+	return nil
+}
+
+func modifiedNanos(record map[string]interface{}) int64 {
+	s, _ := record["modified"].(string)
+
+	t, err := iso8601.ParseTime(s)
+	if err != nil {
+		return math.MinInt64
+	}
+
+	return t.UnixNano()
+}
+
+func encodeLatest(modified int64, seq uint64) Value {
+	var v Value
+	binary.LittleEndian.PutUint64(v[:8], uint64(modified))
+	binary.LittleEndian.PutUint64(v[8:], seq)
+
+	return v
+}
+
+func decodeLatest(v Value) (int64, uint64) {
+	return int64(binary.LittleEndian.Uint64(v[:8])), binary.LittleEndian.Uint64(v[8:])
+}
+
+// Iterator is a StreamIterator that drops the records its Keeper rejects.
+type Iterator struct {
+	si       etl.StreamIterator
+	keyer    *Keyer
+	keeper   Keeper
+	seq      uint64
+	dropped  atomic.Int64
+	logDrops bool
+	logger   *zap.Logger
+}
+
+type IteratorOption func(*Iterator)
+
+// WithDropLogging logs every dropped duplicate with its input position.
+func WithDropLogging() IteratorOption {
+	return func(it *Iterator) {
+		it.logDrops = true
+	}
+}
+
+func NewIterator(si etl.StreamIterator, keyer *Keyer, keeper Keeper, log *zap.Logger, opts ...IteratorOption) *Iterator {
+	it := &Iterator{
+		si:     si,
+		keyer:  keyer,
+		keeper: keeper,
+		logger: log,
+	}
+
+	for _, opt := range opts {
+		opt(it)
+	}
+
+	return it
+}
+
+func (it *Iterator) Next() (map[string]interface{}, error) {
+	for {
+		// every read counts, failed ones too, so positions stay those of
+		// IndexLatest when errors are skipped
+		seq := it.seq
+		it.seq++
+
+		obj, err := it.si.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		d, err := it.keyer.Key(obj)
+		if errors.Is(err, ErrNoKey) {
+			return obj, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		keep, err := it.keeper.Keep(seq, d)
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			return obj, nil
+		}
+
+		it.dropped.Add(1)
+		if it.logDrops {
+			it.logger.Info("dropped duplicate record", zap.Uint64("index", seq))
+		}
+
+		if !it.si.HasNext() {
+			return nil, etl.Done
+		}
+	}
+}
+
+func (it *Iterator) HasNext() bool {
+	return it.si.HasNext()
+}
+
+// Dropped returns the number of duplicates dropped so far.
+func (it *Iterator) Dropped() int64 {
+	return it.dropped.Load()
+}
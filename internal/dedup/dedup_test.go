@@ -0,0 +1,220 @@
+//go:build unit
+
+package dedup_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ralucas/centipede/internal/dedup"
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var errRead = errors.New("unreadable record")
+
+// sliceIterator mirrors JSONStreamIterator, reporting HasNext until a Next
+// call returns etl.Done. The records at the positions in errs fail to read.
+type sliceIterator struct {
+	records []map[string]interface{}
+	errs    map[int]error
+	pos     int
+	done    bool
+}
+
+func (s *sliceIterator) Next() (map[string]interface{}, error) {
+	if len(s.records) == 0 {
+		s.done = true
+		return nil, etl.Done
+	}
+
+	obj := s.records[0]
+	s.records = s.records[1:]
+	s.pos++
+
+	if err := s.errs[s.pos-1]; err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+func (s *sliceIterator) HasNext() bool {
+	return !s.done
+}
+
+func testRecords() []map[string]interface{} {
+	return []map[string]interface{}{
+		{"identifier": "a", "modified": "2019-01-01", "title": "first a"},
+		{"identifier": "b", "modified": "2019-01-01", "title": "first b"},
+		{"identifier": "a", "modified": "2021-01-01", "title": "latest a"},
+		{"identifier": "a", "modified": "2020-01-01", "title": "middle a"},
+		{"identifier": "b", "modified": "2019-01-01", "title": "first b"},
+	}
+}
+
+func collect(t *testing.T, si etl.StreamIterator) []string {
+	var titles []string
+	for si.HasNext() {
+		obj, err := si.Next()
+		if errors.Is(err, etl.Done) {
+			break
+		}
+		if errors.Is(err, errRead) {
+			continue
+		}
+		require.NoError(t, err)
+		titles = append(titles, obj["title"].(string))
+	}
+
+	return titles
+}
+
+func TestKeyer(t *testing.T) {
+	records := testRecords()
+
+	t.Run("keys on fields", func(t *testing.T) {
+		k := dedup.NewKeyer([]string{"identifier"})
+
+		a1, err := k.Key(records[0])
+		require.NoError(t, err)
+		a2, err := k.Key(records[2])
+		require.NoError(t, err)
+		b, err := k.Key(records[1])
+		require.NoError(t, err)
+
+		assert.Equal(t, a1, a2)
+		assert.NotEqual(t, a1, b)
+	})
+
+	t.Run("keys on nested fields", func(t *testing.T) {
+		k := dedup.NewKeyer([]string{"publisher.name"})
+
+		a, err := k.Key(map[string]interface{}{"publisher": map[string]interface{}{"name": "x"}, "title": "1"})
+		require.NoError(t, err)
+		b, err := k.Key(map[string]interface{}{"publisher": map[string]interface{}{"name": "x"}, "title": "2"})
+		require.NoError(t, err)
+
+		assert.Equal(t, a, b)
+	})
+
+	t.Run("no key without the fields", func(t *testing.T) {
+		k := dedup.NewKeyer([]string{"identifier", "publisher.name"})
+
+		for _, record := range []map[string]interface{}{
+			{"title": "no fields"},
+			{"identifier": "a", "title": "no publisher"},
+			{"identifier": "", "publisher": map[string]interface{}{"name": "x"}},
+			{"identifier": "a", "publisher": "x"},
+		} {
+			_, err := k.Key(record)
+			assert.ErrorIs(t, err, dedup.ErrNoKey, record["title"])
+		}
+	})
+
+	t.Run("keys on whole record", func(t *testing.T) {
+		k := dedup.NewKeyer(nil)
+
+		b1, err := k.Key(records[1])
+		require.NoError(t, err)
+		b2, err := k.Key(records[4])
+		require.NoError(t, err)
+		a, err := k.Key(records[0])
+		require.NoError(t, err)
+
+		assert.Equal(t, b1, b2)
+		assert.NotEqual(t, a, b1)
+	})
+}
+
+func TestIterator(t *testing.T) {
+	log, err := zap.NewDevelopment()
+	require.NoError(t, err)
+
+	keyer := dedup.NewKeyer([]string{"identifier"})
+
+	t.Run("keeps first with exact set", func(t *testing.T) {
+		set, err := dedup.NewDiskMap(t.TempDir())
+		require.NoError(t, err)
+		defer set.Close()
+
+		it := dedup.NewIterator(&sliceIterator{records: testRecords()}, keyer, dedup.First(set), log)
+
+		assert.Equal(t, []string{"first a", "first b"}, collect(t, it))
+		assert.Equal(t, int64(3), it.Dropped())
+	})
+
+	t.Run("keeps first with bloom filter", func(t *testing.T) {
+		it := dedup.NewIterator(
+			&sliceIterator{records: testRecords()},
+			keyer,
+			dedup.First(dedup.NewBloomFilter(1024, 10)),
+			log,
+			dedup.WithDropLogging(),
+		)
+
+		assert.Equal(t, []string{"first a", "first b"}, collect(t, it))
+		assert.Equal(t, int64(3), it.Dropped())
+	})
+
+	t.Run("keeps latest by modified", func(t *testing.T) {
+		index, err := dedup.NewDiskMap(t.TempDir())
+		require.NoError(t, err)
+		defer index.Close()
+
+		require.NoError(t, dedup.IndexLatest(context.TODO(), &sliceIterator{records: testRecords()}, keyer, index, etl.ErrorPolicy{}))
+
+		it := dedup.NewIterator(&sliceIterator{records: testRecords()}, keyer, dedup.Latest(index), log)
+
+		// ties go to the later record
+		assert.Equal(t, []string{"latest a", "first b"}, collect(t, it))
+		assert.Equal(t, int64(3), it.Dropped())
+	})
+	t.Run("keeps records without a key", func(t *testing.T) {
+		set, err := dedup.NewDiskMap(t.TempDir())
+		require.NoError(t, err)
+		defer set.Close()
+
+		records := []map[string]interface{}{
+			{"title": "untitled 1"},
+			{"identifier": "", "title": "untitled 2"},
+			{"title": "untitled 3"},
+		}
+
+		it := dedup.NewIterator(&sliceIterator{records: records}, keyer, dedup.First(set), log)
+
+		assert.Equal(t, []string{"untitled 1", "untitled 2", "untitled 3"}, collect(t, it))
+		assert.Zero(t, it.Dropped())
+	})
+
+	t.Run("keeps latest past failed records", func(t *testing.T) {
+		records := func() *sliceIterator {
+			return &sliceIterator{
+				records: []map[string]interface{}{
+					{"identifier": "a", "modified": "2019-01-01", "title": "first a"},
+					{"identifier": "a", "modified": "2022-01-01", "title": "unreadable a"},
+					{"identifier": "a", "modified": "2021-01-01", "title": "latest a"},
+				},
+				errs: map[int]error{1: errRead},
+			}
+		}
+
+		index, err := dedup.NewDiskMap(t.TempDir())
+		require.NoError(t, err)
+		defer index.Close()
+
+		err = dedup.IndexLatest(context.TODO(), records(), keyer, index, etl.ErrorPolicy{Mode: etl.ErrorModeFail})
+		require.ErrorIs(t, err, errRead)
+
+		err = dedup.IndexLatest(context.TODO(), records(), keyer, index, etl.ErrorPolicy{Mode: etl.ErrorModeSkip})
+		require.NoError(t, err)
+
+		it := dedup.NewIterator(records(), keyer, dedup.Latest(index), log)
+
+		assert.Equal(t, []string{"latest a"}, collect(t, it))
+		assert.Equal(t, int64(1), it.Dropped())
+	})
+}
@@ -0,0 +1,207 @@
+package dedup
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+const (
+	slotSize     = len(Digest{}) + len(Value{})
+	initialSlots = 1 << 16
+	// growChunk is the number of slots read at a time while rehashing.
+	growChunk = 4096
+)
+
+// Value is the fixed-size payload stored alongside a digest.
+type Value [16]byte
+
+// DiskMap is an open-addressing hash table from Digest to Value kept in a
+// temporary file, so exact deduplication of huge inputs is bounded by disk
+// rather than memory. It relies on the page cache for speed and is not safe
+// for concurrent use.
+type DiskMap struct {
+	file  *os.File
+	dir   string
+	slots uint64
+	count uint64
+}
+
+// NewDiskMap creates the backing file in dir, or the default temporary
+// directory when dir is empty.
+func NewDiskMap(dir string) (*DiskMap, error) {
+	f, err := newTable(dir, initialSlots)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DiskMap{
+		file:  f,
+		dir:   dir,
+		slots: initialSlots,
+	}, nil
+}
+
+func newTable(dir string, slots uint64) (*os.File, error) {
+	f, err := os.CreateTemp(dir, "centipede-dedup-*")
+	if err != nil {
+		return nil, err
+	}
+
+	if err = f.Truncate(int64(slots) * int64(slotSize)); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Len returns the number of stored digests.
+func (m *DiskMap) Len() int {
+	return int(m.count)
+}
+
+// Get returns the value stored for the digest.
+func (m *DiskMap) Get(d Digest) (Value, bool, error) {
+	_, v, found, err := m.find(m.file, m.slots, d)
+	return v, found, err
+}
+
+// Put stores the value for the digest, replacing any previous value.
+func (m *DiskMap) Put(d Digest, v Value) error {
+	slot, _, found, err := m.find(m.file, m.slots, d)
+	if err != nil {
+		return err
+	}
+
+	if err = writeSlot(m.file, slot, d, v); err != nil {
+		return err
+	}
+
+	if !found {
+		m.count++
+		if m.count*2 > m.slots {
+			return m.grow()
+		}
+	}
+
+	return nil
+}
+
+// Add stores the digest and reports whether it was already present.
+func (m *DiskMap) Add(d Digest) (bool, error) {
+	_, found, err := m.Get(d)
+	if err != nil || found {
+		return found, err
+	}
+
+	return false, m.Put(d, Value{})
+}
+
+// Close removes the backing file.
+func (m *DiskMap) Close() error {
+	name := m.file.Name()
+	if err := m.file.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(name)
+}
+
+// find linearly probes for the digest, returning its slot or the first
+// empty slot when it is absent.
+func (m *DiskMap) find(f *os.File, slots uint64, d Digest) (uint64, Value, bool, error) {
+	var buf [slotSize]byte
+
+	slot := binary.LittleEndian.Uint64(d[:8]) & (slots - 1)
+	for {
+		if _, err := f.ReadAt(buf[:], int64(slot)*int64(slotSize)); err != nil {
+			return 0, Value{}, false, err
+		}
+
+		key := buf[:len(Digest{})]
+		if bytes.Equal(key, d[:]) {
+			var v Value
+			copy(v[:], buf[len(Digest{}):])
+			return slot, v, true, nil
+		}
+
+		if isEmpty(key) {
+			return slot, Value{}, false, nil
+		}
+
+		slot = (slot + 1) & (slots - 1)
+	}
+}
+
+// grow doubles the table, rehashing every slot into a new file.
+func (m *DiskMap) grow() error {
+	slots := m.slots * 2
+
+	f, err := newTable(m.dir, slots)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, growChunk*slotSize)
+	for off := int64(0); off < int64(m.slots)*int64(slotSize); off += int64(len(buf)) {
+		n, err := m.file.ReadAt(buf, off)
+		if err != nil && err != io.EOF {
+			f.Close()
+			os.Remove(f.Name())
+			return err
+		}
+
+		for i := 0; i+slotSize <= n; i += slotSize {
+			if isEmpty(buf[i : i+len(Digest{})]) {
+				continue
+			}
+
+			var d Digest
+			var v Value
+			copy(d[:], buf[i:])
+			copy(v[:], buf[i+len(Digest{}):i+slotSize])
+
+			slot, _, _, err := m.find(f, slots, d)
+			if err == nil {
+				err = writeSlot(f, slot, d, v)
+			}
+			if err != nil {
+				f.Close()
+				os.Remove(f.Name())
+				return err
+			}
+		}
+	}
+
+	if err = m.Close(); err != nil {
+		return err
+	}
+
+	m.file = f
+	m.slots = slots
+
+	return nil
+}
+
+func writeSlot(f *os.File, slot uint64, d Digest, v Value) error {
+	var buf [slotSize]byte
+
+	copy(buf[:], d[:])
+	copy(buf[len(Digest{}):], v[:])
+
+	_, err := f.WriteAt(buf[:], int64(slot)*int64(slotSize))
+	return err
+}
+
+func isEmpty(key []byte) bool {
+	for _, b := range key {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
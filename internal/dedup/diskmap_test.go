@@ -0,0 +1,67 @@
+//go:build unit
+
+package dedup_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/ralucas/centipede/internal/dedup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func digest(i int) dedup.Digest {
+	sum := sha256.Sum256([]byte(fmt.Sprint(i)))
+
+	var d dedup.Digest
+	copy(d[:], sum[:])
+	d[len(d)-1] |= 1
+
+	return d
+}
+
+func TestDiskMap(t *testing.T) {
+	dir := t.TempDir()
+
+	m, err := dedup.NewDiskMap(dir)
+	require.NoError(t, err)
+
+	// enough entries to force the table to grow a few times
+	const n = 200000
+
+	for i := 0; i < n; i++ {
+		var v dedup.Value
+		v[0] = byte(i)
+		require.NoError(t, m.Put(digest(i), v))
+	}
+
+	assert.Equal(t, n, m.Len())
+
+	for _, i := range []int{0, 1, n / 2, n - 1} {
+		v, ok, err := m.Get(digest(i))
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, byte(i), v[0])
+	}
+
+	_, ok, err := m.Get(digest(n))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	seen, err := m.Add(digest(1))
+	require.NoError(t, err)
+	assert.True(t, seen)
+
+	seen, err = m.Add(digest(n))
+	require.NoError(t, err)
+	assert.False(t, seen)
+
+	require.NoError(t, m.Close())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
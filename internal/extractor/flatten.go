@@ -0,0 +1,109 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ralucas/centipede/internal/flatten"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrUnknownColumn = errors.New("record has a column missing from the header")
+	ErrInvalidPolicy = errors.New("invalid late column policy")
+)
+
+// LateColumnPolicy decides what happens to leaf paths that were not
+// discovered before the header was written.
+type LateColumnPolicy string
+
+const (
+	// LateColumnDrop drops the values and warns once per path.
+	LateColumnDrop LateColumnPolicy = "drop"
+	// LateColumnFail fails the record with ErrUnknownColumn.
+	LateColumnFail LateColumnPolicy = "fail"
+)
+
+// ParseLateColumnPolicy validates a policy name.
+func ParseLateColumnPolicy(s string) (LateColumnPolicy, error) {
+	switch p := LateColumnPolicy(s); p {
+	case LateColumnDrop, LateColumnFail:
+		return p, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidPolicy, s)
+	}
+}
+
+// FlattenExtractor flattens each record to its leaf paths and extracts the
+// requested ones.
+type FlattenExtractor struct {
+	filter *flatten.Filter
+	policy LateColumnPolicy
+	late   sync.Map
+	logger *zap.Logger
+}
+
+type FlattenExtractorOption func(*FlattenExtractor)
+
+// WithFilter limits late column checks to the paths selected by the
+// discovery filter.
+func WithFilter(filter *flatten.Filter) FlattenExtractorOption {
+	return func(e *FlattenExtractor) {
+		e.filter = filter
+	}
+}
+
+// WithLateColumnPolicy sets the policy for leaf paths missing from the
+// fields, defaults to LateColumnDrop.
+func WithLateColumnPolicy(policy LateColumnPolicy) FlattenExtractorOption {
+	return func(e *FlattenExtractor) {
+		e.policy = policy
+	}
+}
+
+func NewFlattenExtractor(log *zap.Logger, opts ...FlattenExtractorOption) *FlattenExtractor {
+	e := &FlattenExtractor{
+		filter: flatten.NewFilter(nil),
+		policy: LateColumnDrop,
+		logger: log,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+func (e *FlattenExtractor) Extract(ctx context.Context, dataset map[string]interface{}, fields []string) (map[string]interface{}, error) {
+	flat := flatten.Flatten(dataset)
+
+	extract := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if v, ok := flat[field]; ok {
+			extract[field] = v
+			delete(flat, field)
+		} else {
+			extract[field] = ""
+		}
+	}
+
+	// whatever is left was not part of the discovered header
+	for leaf := range flat {
+		if !e.filter.Match(leaf) {
+			continue
+		}
+
+		if e.policy == LateColumnFail {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownColumn, leaf)
+		}
+
+		if _, seen := e.late.LoadOrStore(leaf, true); !seen {
+			e.logger.Warn("dropping column discovered after the header was written", zap.String("field", leaf))
+		}
+	}
+
+	return extract, nil
+}
@@ -0,0 +1,68 @@
+//go:build unit
+
+package extractor_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ralucas/centipede/internal/extractor"
+	"github.com/ralucas/centipede/internal/flatten"
+	"github.com/ralucas/centipede/test/fixtures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFlattenExtract(t *testing.T) {
+	log, err := zap.NewDevelopment()
+	require.NoError(t, err)
+
+	f := fixtures.NewTestFixture()
+	testData, err := f.DatasetMaps()
+	require.NoError(t, err)
+
+	testFields := []string{"contactPoint.fn", "keyword", "distribution.format", "doesnotexist"}
+
+	t.Run("success", func(t *testing.T) {
+		e := extractor.NewFlattenExtractor(log)
+
+		extract, err := e.Extract(context.TODO(), testData[0], testFields)
+		assert.NoError(t, err)
+
+		assert.Equal(t, 4, len(extract))
+		assert.Equal(t, "Toni L. Holloway", extract["contactPoint.fn"])
+		assert.Equal(t, []interface{}{"xlsx"}, extract["distribution.format"])
+		assert.Empty(t, extract["doesnotexist"])
+	})
+
+	t.Run("drops late columns", func(t *testing.T) {
+		e := extractor.NewFlattenExtractor(log, extractor.WithFilter(flatten.NewFilter([]string{"*"})))
+
+		extract, err := e.Extract(context.TODO(), testData[0], testFields)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, len(extract))
+	})
+
+	t.Run("fails on late columns", func(t *testing.T) {
+		e := extractor.NewFlattenExtractor(
+			log,
+			extractor.WithFilter(flatten.NewFilter([]string{"*"})),
+			extractor.WithLateColumnPolicy(extractor.LateColumnFail),
+		)
+
+		_, err := e.Extract(context.TODO(), testData[0], testFields)
+		assert.ErrorIs(t, err, extractor.ErrUnknownColumn)
+	})
+
+	t.Run("ignores columns outside the filter", func(t *testing.T) {
+		e := extractor.NewFlattenExtractor(
+			log,
+			extractor.WithFilter(flatten.NewFilter([]string{"contactPoint.**"})),
+			extractor.WithLateColumnPolicy(extractor.LateColumnFail),
+		)
+
+		_, err := e.Extract(context.TODO(), testData[0], []string{"contactPoint.@type", "contactPoint.fn", "contactPoint.hasEmail"})
+		assert.NoError(t, err)
+	})
+}
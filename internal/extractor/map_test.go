@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/ralucas/centipede/internal/extractor"
+	"github.com/ralucas/centipede/internal/transformer"
 	"github.com/ralucas/centipede/test/fixtures"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -52,4 +53,45 @@ func TestExtract(t *testing.T) {
 		assert.Empty(t, extract["doesnotexist"])
 		assert.Empty(t, extract["contactPoint.doesnotexist"])
 	})
+
+	t.Run("path crossing an array fails, below a scalar is empty", func(t *testing.T) {
+		record := map[string]interface{}{
+			"distribution": []interface{}{map[string]interface{}{"format": "csv"}},
+			"title":        "air",
+		}
+
+		_, err := e.Extract(context.TODO(), record, []string{"distribution.format"})
+		assert.ErrorIs(t, err, extractor.ErrArrayPath)
+		assert.ErrorContains(t, err, "distribution is an array")
+
+		extract, err := e.Extract(context.TODO(), record, []string{"title.value"})
+		assert.NoError(t, err)
+		assert.Empty(t, extract["title.value"])
+	})
+
+	t.Run("renders bools, numbers and nulls as strings", func(t *testing.T) {
+		record := map[string]interface{}{
+			"dataQuality": true,
+			"version":     2.5,
+			"flags":       []interface{}{false, 3.0},
+			"spatial":     nil,
+			"publisher":   map[string]interface{}{"verified": true},
+		}
+		fields := []string{"dataQuality", "version", "flags", "spatial", "publisher.verified"}
+
+		extract, err := e.Extract(context.TODO(), record, fields)
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]interface{}{
+			"dataQuality":        "true",
+			"version":            "2.5",
+			"flags":              []interface{}{"false", "3"},
+			"spatial":            "",
+			"publisher.verified": "true",
+		}, extract)
+
+		rows, err := transformer.NewRowTransformer(log).Transform(context.TODO(), extract, fields)
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"true", "2.5", "false", "", "true"}, {"true", "2.5", "3", "", "true"}}, rows)
+	})
 }
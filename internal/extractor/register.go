@@ -0,0 +1,35 @@
+package extractor
+
+import (
+	"github.com/ralucas/centipede/internal/flatten"
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+)
+
+// FlattenConfig configures the flatten extractor.
+type FlattenConfig struct {
+	// Patterns are the field patterns the fields were discovered from.
+	Patterns []string `json:"patterns"`
+	// LateColumns is the late column policy, drop or fail.
+	LateColumns string `json:"late_columns"`
+}
+
+func init() {
+	etl.RegisterExtractor("map", func(_ struct{}, log *zap.Logger) (etl.Extractor, error) {
+		return NewMapExtractor(log), nil
+	})
+
+	etl.RegisterExtractor("flatten", func(conf FlattenConfig, log *zap.Logger) (etl.Extractor, error) {
+		opts := []FlattenExtractorOption{WithFilter(flatten.NewFilter(conf.Patterns))}
+
+		if conf.LateColumns != "" {
+			policy, err := ParseLateColumnPolicy(conf.LateColumns)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, WithLateColumnPolicy(policy))
+		}
+
+		return NewFlattenExtractor(log, opts...), nil
+	})
+}
@@ -0,0 +1,349 @@
+package extsort
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ralucas/centipede/internal/collate"
+	"go.uber.org/zap"
+)
+
+var ErrUnknownColumn = errors.New("unknown sort column")
+
+const (
+	defaultMemoryBudget = 256 << 20
+	// rowOverhead approximates the per-row cost beyond the string bytes.
+	rowOverhead = 64
+	// emitBatch is the number of sorted rows handed to emit at a time.
+	emitBatch = 1000
+)
+
+// Key is a column to sort rows by.
+type Key struct {
+	Column int
+	Desc   bool
+}
+
+// ParseKeys resolves specs like modified:desc or identifier (ascending) to
+// positions within columns.
+func ParseKeys(specs []string, columns []string) ([]Key, error) {
+	keys := make([]Key, 0, len(specs))
+
+	for _, spec := range specs {
+		name, dir, _ := strings.Cut(strings.TrimSpace(spec), ":")
+
+		var key Key
+		switch strings.ToLower(dir) {
+		case "", "asc":
+		case "desc":
+			key.Desc = true
+		default:
+			return nil, fmt.Errorf("invalid sort direction %q in %q", dir, spec)
+		}
+
+		key.Column = slices.Index(columns, name)
+		if key.Column < 0 {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownColumn, name)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// entry is a row with its sort key values parsed once.
+type entry struct {
+	row  []string
+	keys []collate.Value
+}
+
+// Sorter is an external merge sort for rows. Rows are buffered until the
+// memory budget is exceeded, then sorted and spilled to a run file. Emit
+// merges the runs with whatever is still buffered.
+type Sorter struct {
+	keys   []Key
+	budget int64
+	size   int64
+	buf    []entry
+	dir    string
+	runs   []string
+	logger *zap.Logger
+}
+
+type SorterOption func(*Sorter)
+
+// WithMemoryBudget sets the approximate number of bytes of rows buffered
+// before spilling to disk.
+func WithMemoryBudget(bytes int64) SorterOption {
+	return func(s *Sorter) {
+		if bytes > 0 {
+			s.budget = bytes
+		}
+	}
+}
+
+// WithSpillDir sets the directory for run files, defaults to the system
+// temporary directory.
+func WithSpillDir(dir string) SorterOption {
+	return func(s *Sorter) {
+		s.dir = dir
+	}
+}
+
+func NewSorter(keys []Key, log *zap.Logger, opts ...SorterOption) *Sorter {
+	s := &Sorter{
+		keys:   keys,
+		budget: defaultMemoryBudget,
+		logger: log,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Add buffers a row, spilling to disk once over budget. It is not safe for
+// concurrent use.
+func (s *Sorter) Add(row []string) error {
+	s.buf = append(s.buf, s.entry(row))
+
+	s.size += rowOverhead
+	for _, v := range row {
+		s.size += int64(len(v)) + 16
+	}
+
+	if s.size > s.budget {
+		return s.spill()
+	}
+
+	return nil
+}
+
+// Emit hands every row to emit in sorted order and removes the run files.
+func (s *Sorter) Emit(ctx context.Context, emit func([][]string) error) error {
+	defer s.Close()
+
+	s.sortBuffer()
+
+	if len(s.runs) == 0 {
+		for start := 0; start < len(s.buf); start += emitBatch {
+			end := min(start+emitBatch, len(s.buf))
+
+			rows := make([][]string, 0, end-start)
+			for _, e := range s.buf[start:end] {
+				rows = append(rows, e.row)
+			}
+
+			if err := emit(rows); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	s.logger.Debug("merging sorted runs", zap.Int("runs", len(s.runs)))
+
+	h := &cursorHeap{sorter: s}
+
+	for _, name := range s.runs {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		r := csv.NewReader(bufio.NewReader(f))
+		r.FieldsPerRecord = -1
+
+		c := &cursor{next: func() ([]string, error) { return r.Read() }}
+		if err = h.push(c); err != nil {
+			return err
+		}
+	}
+
+	// the in-memory buffer is merged as one more run
+	i := 0
+	buffered := &cursor{next: func() ([]string, error) {
+		if i == len(s.buf) {
+			return nil, io.EOF
+		}
+		i++
+		return s.buf[i-1].row, nil
+	}}
+	if err := h.push(buffered); err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, emitBatch)
+	for h.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		c := h.cursors[0]
+		rows = append(rows, c.head.row)
+
+		if ok, err := c.advance(s); err != nil {
+			return err
+		} else if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+
+		if len(rows) == emitBatch {
+			if err := emit(rows); err != nil {
+				return err
+			}
+			rows = make([][]string, 0, emitBatch)
+		}
+	}
+
+	if len(rows) > 0 {
+		return emit(rows)
+	}
+
+	return nil
+}
+
+// Close removes any run files and drops the buffer.
+func (s *Sorter) Close() error {
+	var errs []error
+	for _, name := range s.runs {
+		if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	s.runs = nil
+	s.buf = nil
+	s.size = 0
+
+	return errors.Join(errs...)
+}
+
+func (s *Sorter) entry(row []string) entry {
+	e := entry{row: row, keys: make([]collate.Value, len(s.keys))}
+	for i, k := range s.keys {
+		if k.Column < len(row) {
+			e.keys[i] = collate.Parse(row[k.Column])
+		}
+	}
+
+	return e
+}
+
+// compare orders by the sort keys, then by the whole row so output is
+// deterministic even when keys tie.
+func (s *Sorter) compare(a, b entry) int {
+	for i, k := range s.keys {
+		c := a.keys[i].Compare(b.keys[i])
+		if k.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	return slices.Compare(a.row, b.row)
+}
+
+func (s *Sorter) sortBuffer() {
+	slices.SortFunc(s.buf, s.compare)
+}
+
+func (s *Sorter) spill() error {
+	s.sortBuffer()
+
+	f, err := os.CreateTemp(s.dir, "centipede-sort-*")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s.runs = append(s.runs, f.Name())
+
+	bw := bufio.NewWriter(f)
+	w := csv.NewWriter(bw)
+	for _, e := range s.buf {
+		if err = w.Write(e.row); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	if err = w.Error(); err != nil {
+		return err
+	}
+
+	if err = bw.Flush(); err != nil {
+		return err
+	}
+
+	s.logger.Debug("spilled sorted run", zap.Int("rows", len(s.buf)), zap.String("run", f.Name()))
+
+	s.buf = nil
+	s.size = 0
+
+	return nil
+}
+
+// cursor walks one sorted run.
+type cursor struct {
+	next func() ([]string, error)
+	head entry
+}
+
+func (c *cursor) advance(s *Sorter) (bool, error) {
+	row, err := c.next()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	c.head = s.entry(row)
+
+	return true, nil
+}
+
+type cursorHeap struct {
+	sorter  *Sorter
+	cursors []*cursor
+}
+
+func (h *cursorHeap) push(c *cursor) error {
+	ok, err := c.advance(h.sorter)
+	if ok {
+		heap.Push(h, c)
+	}
+
+	return err
+}
+
+func (h *cursorHeap) Len() int { return len(h.cursors) }
+func (h *cursorHeap) Less(i, j int) bool {
+	return h.sorter.compare(h.cursors[i].head, h.cursors[j].head) < 0
+}
+func (h *cursorHeap) Swap(i, j int) { h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i] }
+func (h *cursorHeap) Push(x any)    { h.cursors = append(h.cursors, x.(*cursor)) }
+func (h *cursorHeap) Pop() any {
+	n := len(h.cursors)
+	x := h.cursors[n-1]
+	h.cursors = h.cursors[:n-1]
+	return x
+}
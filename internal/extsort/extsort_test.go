@@ -0,0 +1,92 @@
+//go:build unit
+
+package extsort_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/ralucas/centipede/internal/extsort"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParseKeys(t *testing.T) {
+	columns := []string{"modified", "identifier", "keyword"}
+
+	keys, err := extsort.ParseKeys([]string{"modified:desc", "identifier", "keyword:ASC"}, columns)
+	require.NoError(t, err)
+	assert.Equal(t, []extsort.Key{{Column: 0, Desc: true}, {Column: 1}, {Column: 2}}, keys)
+
+	_, err = extsort.ParseKeys([]string{"title"}, columns)
+	assert.ErrorIs(t, err, extsort.ErrUnknownColumn)
+
+	_, err = extsort.ParseKeys([]string{"modified:sideways"}, columns)
+	assert.Error(t, err)
+}
+
+func TestSorter(t *testing.T) {
+	log, err := zap.NewDevelopment()
+	require.NoError(t, err)
+
+	keys := []extsort.Key{{Column: 0, Desc: true}, {Column: 1}}
+
+	var rows [][]string
+	for i := 0; i < 500; i++ {
+		rows = append(rows, []string{
+			fmt.Sprintf("2020-01-%02d", i%28+1),
+			fmt.Sprint(i % 7),
+			fmt.Sprintf("multi\nline, %d", i),
+		})
+	}
+	rand.New(rand.NewSource(1)).Shuffle(len(rows), func(i, j int) { rows[i], rows[j] = rows[j], rows[i] })
+
+	sortAll := func(t *testing.T, opts ...extsort.SorterOption) [][]string {
+		s := extsort.NewSorter(keys, log, opts...)
+		for _, row := range rows {
+			require.NoError(t, s.Add(row))
+		}
+
+		var result [][]string
+		err := s.Emit(context.TODO(), func(batch [][]string) error {
+			result = append(result, batch...)
+			return nil
+		})
+		require.NoError(t, err)
+
+		return result
+	}
+
+	assertSorted := func(t *testing.T, result [][]string) {
+		require.Equal(t, len(rows), len(result))
+		for i := 1; i < len(result); i++ {
+			prev, cur := result[i-1], result[i]
+			require.GreaterOrEqual(t, prev[0], cur[0])
+			if prev[0] == cur[0] {
+				require.LessOrEqual(t, prev[1], cur[1])
+			}
+		}
+	}
+
+	t.Run("in memory", func(t *testing.T) {
+		assertSorted(t, sortAll(t))
+	})
+
+	t.Run("spills to disk", func(t *testing.T) {
+		dir := t.TempDir()
+
+		inMemory := sortAll(t)
+		spilled := sortAll(t, extsort.WithMemoryBudget(1024), extsort.WithSpillDir(dir))
+
+		assertSorted(t, spilled)
+		assert.Equal(t, inMemory, spilled)
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
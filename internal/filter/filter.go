@@ -0,0 +1,224 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// Predicate reports whether a raw record is kept.
+type Predicate interface {
+	Match(record map[string]interface{}) bool
+}
+
+type op int
+
+const (
+	opExists op = iota
+	opMissing
+	opEqual
+	opNotEqual
+	opMatch
+)
+
+// condition tests the values found at a dotted field path. Arrays along the
+// path are searched element by element, so a condition on
+// distribution.format holds when any distribution has a matching format.
+type condition struct {
+	keys  []string
+	op    op
+	value string
+	re    *regexp.Regexp
+}
+
+// Parse parses a filter expression, one of:
+//
+//	field         the field has a non-empty value
+//	!field        the field is missing or empty
+//	field=value   the field equals value
+//	field!=value  the field doesn't equal value
+//	field~regexp  the field matches the regular expression
+//
+// Values are compared as text, so numbers and booleans match their JSON
+// form.
+func Parse(expr string) (Predicate, error) {
+	expr = strings.TrimSpace(expr)
+
+	c := &condition{op: opExists}
+	field := expr
+
+	if i := strings.IndexAny(expr, "=~"); i >= 0 {
+		field, c.value = expr[:i], expr[i+1:]
+
+		switch {
+		case expr[i] == '~':
+			re, err := regexp.Compile(c.value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q: %w", ErrInvalidFilter, expr, err)
+			}
+			c.op, c.re = opMatch, re
+		case strings.HasSuffix(field, "!"):
+			c.op, field = opNotEqual, strings.TrimSuffix(field, "!")
+		default:
+			c.op = opEqual
+		}
+	} else if strings.HasPrefix(field, "!") {
+		c.op, field = opMissing, strings.TrimPrefix(field, "!")
+	}
+
+	field = strings.TrimSpace(field)
+	if field == "" {
+		return nil, fmt.Errorf("%w: %q has no field", ErrInvalidFilter, expr)
+	}
+
+	c.keys = strings.Split(field, ".")
+
+	return c, nil
+}
+
+// ParseAll parses each expression, the record is kept when all hold.
+func ParseAll(exprs []string) (Predicate, error) {
+	all := make(all, 0, len(exprs))
+	for _, expr := range exprs {
+		p, err := Parse(expr)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, p)
+	}
+
+	return all, nil
+}
+
+type all []Predicate
+
+func (a all) Match(record map[string]interface{}) bool {
+	for _, p := range a {
+		if !p.Match(record) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *condition) Match(record map[string]interface{}) bool {
+	values := lookup(record, c.keys, nil)
+
+	switch c.op {
+	case opExists:
+		return anyValue(values, func(s string) bool { return s != "" })
+	case opMissing:
+		return !anyValue(values, func(s string) bool { return s != "" })
+	case opEqual:
+		return anyValue(values, func(s string) bool { return s == c.value })
+	case opNotEqual:
+		return !anyValue(values, func(s string) bool { return s == c.value })
+	default:
+		return anyValue(values, c.re.MatchString)
+	}
+}
+
+// lookup appends the scalar values at keys to values, descending into
+// arrays along the way.
+func lookup(cur interface{}, keys []string, values []interface{}) []interface{} {
+	if arr, ok := cur.([]interface{}); ok {
+		for _, v := range arr {
+			values = lookup(v, keys, values)
+		}
+		return values
+	}
+
+	if len(keys) == 0 {
+		if cur == nil {
+			return values
+		}
+		return append(values, cur)
+	}
+
+	m, ok := cur.(map[string]interface{})
+	if !ok {
+		return values
+	}
+
+	return lookup(m[keys[0]], keys[1:], values)
+}
+
+func anyValue(values []interface{}, f func(string) bool) bool {
+	for _, v := range values {
+		if f(text(v)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func text(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Iterator wraps a StreamIterator, passing on only the records matched by
+// the predicate.
+type Iterator struct {
+	si        etl.StreamIterator
+	predicate Predicate
+	seq       uint64
+	dropped   atomic.Int64
+	logger    *zap.Logger
+}
+
+func NewIterator(si etl.StreamIterator, predicate Predicate, log *zap.Logger) *Iterator {
+	return &Iterator{
+		si:        si,
+		predicate: predicate,
+		logger:    log,
+	}
+}
+
+func (it *Iterator) Next() (map[string]interface{}, error) {
+	for {
+		obj, err := it.si.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		seq := it.seq
+		it.seq++
+
+		if it.predicate.Match(obj) {
+			return obj, nil
+		}
+
+		it.dropped.Add(1)
+		it.logger.Debug("filtered out record", zap.Uint64("index", seq))
+
+		if !it.si.HasNext() {
+			return nil, etl.Done
+		}
+	}
+}
+
+func (it *Iterator) HasNext() bool {
+	return it.si.HasNext()
+}
+
+// Dropped returns the number of records filtered out so far.
+func (it *Iterator) Dropped() int64 {
+	return it.dropped.Load()
+}
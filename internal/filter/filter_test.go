@@ -0,0 +1,130 @@
+//go:build unit
+
+package filter_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ralucas/centipede/internal/filter"
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// sliceIterator mirrors JSONStreamIterator, reporting HasNext until a Next
+// call returns etl.Done.
+type sliceIterator struct {
+	records []map[string]interface{}
+	done    bool
+}
+
+func (s *sliceIterator) Next() (map[string]interface{}, error) {
+	if len(s.records) == 0 {
+		s.done = true
+		return nil, etl.Done
+	}
+
+	obj := s.records[0]
+	s.records = s.records[1:]
+
+	return obj, nil
+}
+
+func (s *sliceIterator) HasNext() bool {
+	return !s.done
+}
+
+func testRecord() map[string]interface{} {
+	return map[string]interface{}{
+		"accessLevel": "public",
+		"title":       "Air Quality 2019",
+		"version":     float64(2),
+		"publisher":   map[string]interface{}{"name": "EPA"},
+		"keyword":     []interface{}{"air", "quality"},
+		"distribution": []interface{}{
+			map[string]interface{}{"format": "CSV"},
+			map[string]interface{}{"format": "JSON"},
+		},
+		"license": "",
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		{"accessLevel=public", true},
+		{"accessLevel=non-public", false},
+		{"accessLevel!=non-public", true},
+		{"accessLevel!=public", false},
+		{"publisher.name=EPA", true},
+		{"keyword=quality", true},
+		{"keyword!=air", false},
+		{"distribution.format=JSON", true},
+		{"distribution.format=XML", false},
+		{"title~^Air .* 20\\d\\d$", true},
+		{"title~^Water", false},
+		{"version=2", true},
+		{"publisher.name", true},
+		{"license", false},
+		{"!license", true},
+		{"!spatial", true},
+		{"!publisher", false},
+		{" accessLevel = public", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := filter.Parse(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.match, p.Match(testRecord()))
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{"", "=public", "!", "title~("} {
+		_, err := filter.Parse(expr)
+		assert.ErrorIs(t, err, filter.ErrInvalidFilter, expr)
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	p, err := filter.ParseAll([]string{"accessLevel=public", "publisher.name=EPA"})
+	require.NoError(t, err)
+	assert.True(t, p.Match(testRecord()))
+
+	p, err = filter.ParseAll([]string{"accessLevel=public", "publisher.name=NASA"})
+	require.NoError(t, err)
+	assert.False(t, p.Match(testRecord()))
+}
+
+func TestIterator(t *testing.T) {
+	si := &sliceIterator{records: []map[string]interface{}{
+		{"title": "a", "accessLevel": "public"},
+		{"title": "b", "accessLevel": "restricted public"},
+		{"title": "c", "accessLevel": "public"},
+		{"title": "d", "accessLevel": "non-public"},
+	}}
+
+	p, err := filter.Parse("accessLevel=public")
+	require.NoError(t, err)
+
+	it := filter.NewIterator(si, p, zap.NewNop())
+
+	var titles []string
+	for it.HasNext() {
+		obj, err := it.Next()
+		if errors.Is(err, etl.Done) {
+			break
+		}
+		require.NoError(t, err)
+		titles = append(titles, obj["title"].(string))
+	}
+
+	assert.Equal(t, []string{"a", "c"}, titles)
+	assert.Equal(t, int64(2), it.Dropped())
+}
@@ -0,0 +1,89 @@
+package flatten
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrArrayPath = errors.New("nested field path crosses an array")
+
+// Lookup returns the value of field in obj, following a dotted path into
+// nested objects. Scalars are rendered as text and arrays as a list of
+// texts, a missing field is empty. A path crossing an array fails with
+// ErrArrayPath, as its values are only reached by flattening.
+func Lookup(obj map[string]interface{}, field string) (interface{}, error) {
+	keys := strings.Split(field, Separator)
+
+	cur := obj
+	for i := 0; i < len(keys)-1; i++ {
+		val, ok := cur[keys[i]]
+		if !ok || val == nil {
+			return "", nil
+		}
+
+		switch v := val.(type) {
+		case map[string]interface{}:
+			cur = v
+		case []interface{}:
+			return "", fmt.Errorf("%w: %s, %s is an array, its values are extracted by the flatten extractor",
+				ErrArrayPath, field, strings.Join(keys[:i+1], Separator))
+		default:
+			// a scalar has no fields, as if they were missing
+			return "", nil
+		}
+	}
+
+	val, ok := cur[keys[len(keys)-1]]
+	if !ok {
+		return "", nil
+	}
+
+	return render(val), nil
+}
+
+// render turns a value into the strings rows are built from: scalars as
+// text and arrays as a list of texts.
+func render(v interface{}) interface{} {
+	list, ok := v.([]interface{})
+	if !ok {
+		return ToString(v)
+	}
+
+	items := make([]interface{}, len(list))
+	for i, item := range list {
+		items[i] = ToString(item)
+	}
+
+	return items
+}
+
+// Rows builds the rows of a record holding rendered values, one column per
+// field. A list spreads over as many rows as it has items, the other
+// columns repeating on each row and shorter lists padded with empty
+// strings.
+func Rows(m map[string]interface{}, fields []string) [][]string {
+	count := 1
+	for _, v := range m {
+		if list, ok := v.([]interface{}); ok && len(list) > count {
+			count = len(list)
+		}
+	}
+
+	rows := make([][]string, count)
+	for row := range rows {
+		rows[row] = make([]string, len(fields))
+		for col, field := range fields {
+			switch v := m[field].(type) {
+			case []interface{}:
+				if row < len(v) {
+					rows[row][col] = v[row].(string)
+				}
+			case string:
+				rows[row][col] = v
+			}
+		}
+	}
+
+	return rows
+}
@@ -0,0 +1,245 @@
+package flatten
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Wildcard selects every leaf path.
+	Wildcard = "*"
+	// Separator joins the keys of a nested leaf path.
+	Separator = "."
+)
+
+// Flatten walks a decoded json object and returns every leaf path mapped to
+// its value rendered as a string. Arrays of scalars become []interface{} of
+// strings so they are exploded into rows downstream. Arrays of objects are
+// flattened element-wise, e.g. distribution.format, and padded with empty
+// strings so values stay aligned across the keys of the same array.
+func Flatten(obj map[string]interface{}) map[string]interface{} {
+	flat := make(map[string]interface{})
+	flattenInto(flat, "", obj)
+
+	return flat
+}
+
+func flattenInto(flat map[string]interface{}, prefix string, v interface{}) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			flattenInto(flat, join(prefix, k), child)
+		}
+	case []interface{}:
+		if !hasObjects(val) {
+			list := make([]interface{}, 0, len(val))
+			for _, item := range val {
+				list = append(list, scalarList(item)...)
+			}
+			flat[prefix] = list
+			return
+		}
+
+		elements := make([]map[string]interface{}, len(val))
+		keys := make(map[string]bool)
+		for i, item := range val {
+			elements[i] = make(map[string]interface{})
+			flattenInto(elements[i], "", item)
+			for k := range elements[i] {
+				keys[k] = true
+			}
+		}
+
+		for k := range keys {
+			list := make([]interface{}, 0, len(val))
+			for _, el := range elements {
+				if item, ok := el[k]; ok {
+					list = append(list, scalarList(item)...)
+				} else {
+					list = append(list, "")
+				}
+			}
+			flat[join(prefix, k)] = list
+		}
+	default:
+		flat[prefix] = ToString(val)
+	}
+}
+
+func hasObjects(list []interface{}) bool {
+	for _, item := range list {
+		if _, ok := item.(map[string]interface{}); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scalarList renders a value as a list of strings, concatenating any
+// nested lists.
+func scalarList(v interface{}) []interface{} {
+	switch val := v.(type) {
+	case []interface{}:
+		list := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			list = append(list, scalarList(item)...)
+		}
+		return list
+	default:
+		return []interface{}{ToString(val)}
+	}
+}
+
+// ToString renders a json value as a column value: null as empty, scalars
+// as text and objects and arrays as json.
+func ToString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + Separator + key
+}
+
+// IsPattern reports whether the fields request discovery, i.e. any of them
+// is a glob pattern or an exclusion.
+func IsPattern(fields []string) bool {
+	for _, f := range fields {
+		if strings.Contains(f, "*") || strings.HasPrefix(f, "!") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Filter selects leaf paths by glob patterns. Within a pattern * matches a
+// single key and ** matches any number of keys, so publisher.** selects
+// publisher.name as well as publisher.subOrganizationOf.name.
+type Filter struct {
+	include [][]string
+	exclude [][]string
+}
+
+// NewFilter builds a filter from field patterns. A bare * selects every
+// leaf path, patterns prefixed with ! exclude paths, and when there are only
+// exclusions every other path is included.
+func NewFilter(patterns []string) *Filter {
+	f := &Filter{}
+
+	for _, p := range patterns {
+		switch {
+		case p == Wildcard:
+			f.include = append(f.include, []string{"**"})
+		case strings.HasPrefix(p, "!"):
+			f.exclude = append(f.exclude, strings.Split(p[1:], Separator))
+		default:
+			f.include = append(f.include, strings.Split(p, Separator))
+		}
+	}
+
+	return f
+}
+
+// Match reports whether the leaf path is selected by the filter.
+func (f *Filter) Match(leaf string) bool {
+	keys := strings.Split(leaf, Separator)
+
+	for _, p := range f.exclude {
+		if matchKeys(p, keys) {
+			return false
+		}
+	}
+
+	if len(f.include) == 0 {
+		return true
+	}
+
+	for _, p := range f.include {
+		if matchKeys(p, keys) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchKeys(pattern, keys []string) bool {
+	if len(pattern) == 0 {
+		return len(keys) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(keys); i++ {
+			if matchKeys(pattern[1:], keys[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(keys) == 0 {
+		return false
+	}
+
+	if ok, err := path.Match(pattern[0], keys[0]); err != nil || !ok {
+		return false
+	}
+
+	return matchKeys(pattern[1:], keys[1:])
+}
+
+// Discoverer accumulates the leaf paths seen across records.
+type Discoverer struct {
+	filter *Filter
+	paths  map[string]bool
+}
+
+func NewDiscoverer(filter *Filter) *Discoverer {
+	return &Discoverer{
+		filter: filter,
+		paths:  make(map[string]bool),
+	}
+}
+
+// Add records the leaf paths of a raw record that pass the filter.
+func (d *Discoverer) Add(record map[string]interface{}) {
+	for leaf := range Flatten(record) {
+		if d.filter.Match(leaf) {
+			d.paths[leaf] = true
+		}
+	}
+}
+
+// Fields returns the discovered leaf paths, sorted for a stable header.
+func (d *Discoverer) Fields() []string {
+	fields := make([]string, 0, len(d.paths))
+	for leaf := range d.paths {
+		fields = append(fields, leaf)
+	}
+	sort.Strings(fields)
+
+	return fields
+}
@@ -0,0 +1,94 @@
+//go:build unit
+
+package flatten_test
+
+import (
+	"testing"
+
+	"github.com/ralucas/centipede/internal/flatten"
+	"github.com/ralucas/centipede/test/fixtures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlatten(t *testing.T) {
+	obj := map[string]interface{}{
+		"title":       "test",
+		"dataQuality": true,
+		"count":       float64(12),
+		"keyword":     []interface{}{"a", "b"},
+		"publisher": map[string]interface{}{
+			"name": "pub",
+			"subOrganizationOf": map[string]interface{}{
+				"name": "parent",
+			},
+		},
+		"distribution": []interface{}{
+			map[string]interface{}{"format": "csv", "title": "one"},
+			map[string]interface{}{"format": "xlsx"},
+		},
+	}
+
+	flat := flatten.Flatten(obj)
+
+	assert.Equal(t, map[string]interface{}{
+		"title":                            "test",
+		"dataQuality":                      "true",
+		"count":                            "12",
+		"keyword":                          []interface{}{"a", "b"},
+		"publisher.name":                   "pub",
+		"publisher.subOrganizationOf.name": "parent",
+		"distribution.format":              []interface{}{"csv", "xlsx"},
+		"distribution.title":               []interface{}{"one", ""},
+	}, flat)
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		leaf     string
+		expect   bool
+	}{
+		{name: "wildcard matches nested", patterns: []string{"*"}, leaf: "publisher.subOrganizationOf.name", expect: true},
+		{name: "double star matches descendants", patterns: []string{"publisher.**"}, leaf: "publisher.subOrganizationOf.name", expect: true},
+		{name: "double star skips siblings", patterns: []string{"publisher.**"}, leaf: "contactPoint.fn", expect: false},
+		{name: "single star matches one key", patterns: []string{"publisher.*"}, leaf: "publisher.name", expect: true},
+		{name: "single star does not recurse", patterns: []string{"publisher.*"}, leaf: "publisher.subOrganizationOf.name", expect: false},
+		{name: "exclusion wins", patterns: []string{"*", "!distribution.**"}, leaf: "distribution.format", expect: false},
+		{name: "only exclusions include the rest", patterns: []string{"!distribution.**"}, leaf: "title", expect: true},
+		{name: "glob within a key", patterns: []string{"contact*.fn"}, leaf: "contactPoint.fn", expect: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expect, flatten.NewFilter(test.patterns).Match(test.leaf))
+		})
+	}
+}
+
+func TestIsPattern(t *testing.T) {
+	assert.True(t, flatten.IsPattern([]string{"*"}))
+	assert.True(t, flatten.IsPattern([]string{"modified", "publisher.**"}))
+	assert.True(t, flatten.IsPattern([]string{"!distribution.format"}))
+	assert.False(t, flatten.IsPattern([]string{"modified", "publisher.name"}))
+}
+
+func TestDiscoverer(t *testing.T) {
+	f := fixtures.NewTestFixture()
+	testData, err := f.DatasetMaps()
+	require.NoError(t, err)
+
+	d := flatten.NewDiscoverer(flatten.NewFilter([]string{"publisher.**", "keyword"}))
+	for _, obj := range testData {
+		d.Add(obj)
+	}
+
+	assert.Equal(t, []string{
+		"keyword",
+		"publisher.@type",
+		"publisher.name",
+		"publisher.subOrganizationOf.@type",
+		"publisher.subOrganizationOf.name",
+	}, d.Fields())
+}
@@ -0,0 +1,375 @@
+package iso8601
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidTime     = errors.New("invalid iso 8601 time")
+	ErrInvalidDuration = errors.New("invalid iso 8601 duration")
+	ErrInvalidInterval = errors.New("invalid iso 8601 interval")
+)
+
+// layouts are the date and datetime forms seen in DCAT-US catalogs, most
+// specific first. The last entries cover values written by time.Time.String.
+var layouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+	"20060102T150405Z0700",
+	"20060102",
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02 15:04:05",
+}
+
+// ParseTime parses an ISO 8601 date or datetime. Values without a zone are
+// interpreted as UTC.
+func ParseTime(s string) (time.Time, error) {
+	return ParseTimeInLocation(s, time.UTC)
+}
+
+// ParseTimeInLocation parses an ISO 8601 date or datetime, interpreting
+// values without a zone in the given location.
+func ParseTimeInLocation(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidTime, s)
+}
+
+// Duration is an ISO 8601 duration such as P1Y2M10DT2H30M or P2W.
+// Components may be fractional, e.g. R/P3.5D.
+type Duration struct {
+	Years   float64
+	Months  float64
+	Weeks   float64
+	Days    float64
+	Hours   float64
+	Minutes float64
+	Seconds float64
+}
+
+// ParseDuration parses an ISO 8601 duration.
+func ParseDuration(s string) (Duration, error) {
+	var d Duration
+
+	s = strings.TrimSpace(s)
+	if len(s) < 3 || s[0] != 'P' {
+		return d, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+	}
+
+	inTime := false
+	components := 0
+	num := ""
+	for _, c := range s[1:] {
+		switch {
+		case c == 'T':
+			if inTime || num != "" {
+				return d, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+			}
+			inTime = true
+		case (c >= '0' && c <= '9') || c == '.' || c == ',':
+			if c == ',' {
+				c = '.'
+			}
+			num += string(c)
+		default:
+			if num == "" {
+				return d, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+			}
+			v, err := strconv.ParseFloat(num, 64)
+			if err != nil {
+				return d, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+			}
+			num = ""
+			components++
+
+			switch {
+			case c == 'Y' && !inTime:
+				d.Years = v
+			case c == 'M' && !inTime:
+				d.Months = v
+			case c == 'W' && !inTime:
+				d.Weeks = v
+			case c == 'D' && !inTime:
+				d.Days = v
+			case c == 'H' && inTime:
+				d.Hours = v
+			case c == 'M' && inTime:
+				d.Minutes = v
+			case c == 'S' && inTime:
+				d.Seconds = v
+			default:
+				return d, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+			}
+		}
+	}
+
+	if num != "" || components == 0 {
+		return d, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+	}
+
+	return d, nil
+}
+
+// IsZero reports whether every component of the duration is zero.
+func (d Duration) IsZero() bool {
+	return d == Duration{}
+}
+
+// AddTo returns t shifted by the duration. Whole years, months and days are
+// applied on the calendar; fractional parts are approximated.
+func (d Duration) AddTo(t time.Time) time.Time {
+	return d.shift(t, 1)
+}
+
+// SubFrom returns t shifted back by the duration.
+func (d Duration) SubFrom(t time.Time) time.Time {
+	return d.shift(t, -1)
+}
+
+func (d Duration) shift(t time.Time, sign int) time.Time {
+	const day = 24 * time.Hour
+
+	years, yearFrac := splitFloat(d.Years)
+	months, monthFrac := splitFloat(d.Months)
+	days, dayFrac := splitFloat(d.Days + d.Weeks*7)
+
+	t = t.AddDate(sign*years, sign*months, sign*days)
+
+	frac := yearFrac*365.2425*float64(day) +
+		monthFrac*30.436875*float64(day) +
+		dayFrac*float64(day) +
+		d.Hours*float64(time.Hour) +
+		d.Minutes*float64(time.Minute) +
+		d.Seconds*float64(time.Second)
+
+	return t.Add(time.Duration(float64(sign) * frac))
+}
+
+func splitFloat(f float64) (int, float64) {
+	i := int(f)
+	return i, f - float64(i)
+}
+
+// String returns the duration in ISO 8601 form.
+func (d Duration) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("P")
+	writeComponent(&sb, d.Years, 'Y')
+	writeComponent(&sb, d.Months, 'M')
+	writeComponent(&sb, d.Weeks, 'W')
+	writeComponent(&sb, d.Days, 'D')
+
+	if d.Hours != 0 || d.Minutes != 0 || d.Seconds != 0 {
+		sb.WriteString("T")
+		writeComponent(&sb, d.Hours, 'H')
+		writeComponent(&sb, d.Minutes, 'M')
+		writeComponent(&sb, d.Seconds, 'S')
+	}
+
+	if sb.Len() == 1 {
+		sb.WriteString("T0S")
+	}
+
+	return sb.String()
+}
+
+func writeComponent(sb *strings.Builder, v float64, unit byte) {
+	if v == 0 {
+		return
+	}
+	sb.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	sb.WriteByte(unit)
+}
+
+// Label returns a human readable description of the duration, e.g.
+// "every 2 weeks".
+func (d Duration) Label() string {
+	components := []struct {
+		v    float64
+		unit string
+	}{
+		{d.Years, "year"},
+		{d.Months, "month"},
+		{d.Weeks, "week"},
+		{d.Days, "day"},
+		{d.Hours, "hour"},
+		{d.Minutes, "minute"},
+		{d.Seconds, "second"},
+	}
+
+	var parts []string
+	for _, c := range components {
+		switch {
+		case c.v == 0:
+			continue
+		case c.v == 1:
+			parts = append(parts, c.unit)
+		default:
+			parts = append(parts, fmt.Sprintf("%s %ss", strconv.FormatFloat(c.v, 'f', -1, 64), c.unit))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "continuously"
+	}
+
+	return "every " + strings.Join(parts, ", ")
+}
+
+// Interval is an ISO 8601 time interval, optionally repeating. Start and End
+// are zero when the interval is expressed only as a duration, e.g. R/P1D.
+type Interval struct {
+	Start       time.Time
+	End         time.Time
+	Duration    Duration
+	HasDuration bool
+	Repeating   bool
+	// Repetitions is -1 for unbounded repeating intervals.
+	Repetitions int
+}
+
+// ParseInterval parses an interval in any of the forms start/end,
+// start/duration, duration/end or a repeating R[n]/ prefixed variant.
+// Values without a zone are interpreted as UTC.
+func ParseInterval(s string) (Interval, error) {
+	return ParseIntervalInLocation(s, time.UTC)
+}
+
+// ParseIntervalInLocation parses an interval, interpreting values without a
+// zone in the given location.
+func ParseIntervalInLocation(s string, loc *time.Location) (Interval, error) {
+	var iv Interval
+
+	parts := strings.Split(strings.TrimSpace(s), "/")
+	if strings.HasPrefix(parts[0], "R") {
+		iv.Repeating = true
+		iv.Repetitions = -1
+		if n := parts[0][1:]; n != "" {
+			reps, err := strconv.Atoi(n)
+			if err != nil {
+				return iv, fmt.Errorf("%w: %q", ErrInvalidInterval, s)
+			}
+			iv.Repetitions = reps
+		}
+		parts = parts[1:]
+	}
+
+	switch len(parts) {
+	case 1:
+		// a lone duration is only meaningful as a repeating interval
+		if !iv.Repeating {
+			return iv, fmt.Errorf("%w: %q", ErrInvalidInterval, s)
+		}
+		d, err := ParseDuration(parts[0])
+		if err != nil {
+			return iv, fmt.Errorf("%w: %q", ErrInvalidInterval, s)
+		}
+		iv.Duration, iv.HasDuration = d, true
+	case 2:
+		start, end := parts[0], parts[1]
+		switch {
+		case strings.HasPrefix(start, "P"):
+			d, err := ParseDuration(start)
+			if err != nil {
+				return iv, fmt.Errorf("%w: %q", ErrInvalidInterval, s)
+			}
+			t, err := ParseTimeInLocation(end, loc)
+			if err != nil {
+				return iv, fmt.Errorf("%w: %q", ErrInvalidInterval, s)
+			}
+			iv.Duration, iv.HasDuration = d, true
+			iv.End = t
+			iv.Start = d.SubFrom(t)
+		case strings.HasPrefix(end, "P"):
+			d, err := ParseDuration(end)
+			if err != nil {
+				return iv, fmt.Errorf("%w: %q", ErrInvalidInterval, s)
+			}
+			t, err := ParseTimeInLocation(start, loc)
+			if err != nil {
+				return iv, fmt.Errorf("%w: %q", ErrInvalidInterval, s)
+			}
+			iv.Duration, iv.HasDuration = d, true
+			iv.Start = t
+			iv.End = d.AddTo(t)
+		default:
+			st, err := ParseTimeInLocation(start, loc)
+			if err != nil {
+				return iv, fmt.Errorf("%w: %q", ErrInvalidInterval, s)
+			}
+			et, err := ParseTimeInLocation(end, loc)
+			if err != nil {
+				return iv, fmt.Errorf("%w: %q", ErrInvalidInterval, s)
+			}
+			iv.Start, iv.End = st, et
+		}
+	default:
+		return iv, fmt.Errorf("%w: %q", ErrInvalidInterval, s)
+	}
+
+	return iv, nil
+}
+
+// periodicityLabels maps the accrualPeriodicity values enumerated by the
+// DCAT-US schema to their published labels.
+var periodicityLabels = map[string]string{
+	"R/P10Y":   "decennial",
+	"R/P4Y":    "quadrennial",
+	"R/P3Y":    "triennial",
+	"R/P2Y":    "biennial",
+	"R/P1Y":    "annual",
+	"R/P6M":    "semiannual",
+	"R/P4M":    "three times a year",
+	"R/P3M":    "quarterly",
+	"R/P2M":    "bimonthly",
+	"R/P1M":    "monthly",
+	"R/P0.5M":  "semimonthly",
+	"R/P0.33M": "three times a month",
+	"R/P2W":    "biweekly",
+	"R/P1W":    "weekly",
+	"R/P3.5D":  "semiweekly",
+	"R/P0.33W": "three times a week",
+	"R/P1D":    "daily",
+	"R/PT1H":   "hourly",
+	"R/PT1S":   "continuously updated",
+}
+
+// PeriodicityLabel decodes an accrualPeriodicity value such as R/P1M into a
+// human readable label. Values outside the DCAT-US vocabulary fall back to
+// Duration.Label.
+func PeriodicityLabel(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "irregular" {
+		return s, nil
+	}
+
+	if label, ok := periodicityLabels[s]; ok {
+		return label, nil
+	}
+
+	iv, err := ParseInterval(s)
+	if err != nil {
+		return "", err
+	}
+	if !iv.HasDuration {
+		return "", fmt.Errorf("%w: %q has no duration", ErrInvalidInterval, s)
+	}
+
+	return iv.Duration.Label(), nil
+}
@@ -0,0 +1,148 @@
+//go:build unit
+
+package iso8601_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ralucas/centipede/internal/iso8601"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		expect time.Time
+		err    bool
+	}{
+		{name: "date", input: "2019-06-12", expect: time.Date(2019, 6, 12, 0, 0, 0, 0, time.UTC)},
+		{name: "datetime with zone", input: "2019-06-12T10:30:00-05:00", expect: time.Date(2019, 6, 12, 15, 30, 0, 0, time.UTC)},
+		{name: "datetime without zone", input: "2019-06-12T10:30:00", expect: time.Date(2019, 6, 12, 10, 30, 0, 0, time.UTC)},
+		{name: "year and month", input: "2019-06", expect: time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "year", input: "2019", expect: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "go time string", input: "2019-06-12 10:30:00 +0000 UTC", expect: time.Date(2019, 6, 12, 10, 30, 0, 0, time.UTC)},
+		{name: "repeating interval", input: "R/P1D", err: true},
+		{name: "garbage", input: "yesterday", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ts, err := iso8601.ParseTime(test.input)
+			if test.err {
+				assert.ErrorIs(t, err, iso8601.ErrInvalidTime)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, test.expect.Equal(ts), "expected %s, got %s", test.expect, ts)
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect iso8601.Duration
+		err    bool
+	}{
+		{input: "P1Y", expect: iso8601.Duration{Years: 1}},
+		{input: "P3.5D", expect: iso8601.Duration{Days: 3.5}},
+		{input: "P2W", expect: iso8601.Duration{Weeks: 2}},
+		{input: "P1Y2M10DT2H30M", expect: iso8601.Duration{Years: 1, Months: 2, Days: 10, Hours: 2, Minutes: 30}},
+		{input: "PT1S", expect: iso8601.Duration{Seconds: 1}},
+		{input: "P1H", err: true},
+		{input: "PT", err: true},
+		{input: "1D", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			d, err := iso8601.ParseDuration(test.input)
+			if test.err {
+				assert.ErrorIs(t, err, iso8601.ErrInvalidDuration)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expect, d)
+			assert.Equal(t, test.input, d.String())
+		})
+	}
+}
+
+func TestParseInterval(t *testing.T) {
+	t.Run("start and end", func(t *testing.T) {
+		iv, err := iso8601.ParseInterval("2000-01-01/2010-12-31")
+		require.NoError(t, err)
+
+		assert.Equal(t, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), iv.Start)
+		assert.Equal(t, time.Date(2010, 12, 31, 0, 0, 0, 0, time.UTC), iv.End)
+		assert.False(t, iv.Repeating)
+	})
+
+	t.Run("start and duration", func(t *testing.T) {
+		iv, err := iso8601.ParseInterval("2000-01-01/P1M")
+		require.NoError(t, err)
+
+		assert.Equal(t, time.Date(2000, 2, 1, 0, 0, 0, 0, time.UTC), iv.End)
+	})
+
+	t.Run("duration and end", func(t *testing.T) {
+		iv, err := iso8601.ParseInterval("P1Y/2010-12-31")
+		require.NoError(t, err)
+
+		assert.Equal(t, time.Date(2009, 12, 31, 0, 0, 0, 0, time.UTC), iv.Start)
+	})
+
+	t.Run("repeating duration", func(t *testing.T) {
+		iv, err := iso8601.ParseInterval("R/P1D")
+		require.NoError(t, err)
+
+		assert.True(t, iv.Repeating)
+		assert.Equal(t, -1, iv.Repetitions)
+		assert.Equal(t, iso8601.Duration{Days: 1}, iv.Duration)
+		assert.True(t, iv.Start.IsZero())
+	})
+
+	t.Run("bounded repeating interval", func(t *testing.T) {
+		iv, err := iso8601.ParseInterval("R5/2008-03-01T13:00:00Z/P1Y")
+		require.NoError(t, err)
+
+		assert.Equal(t, 5, iv.Repetitions)
+		assert.Equal(t, time.Date(2009, 3, 1, 13, 0, 0, 0, time.UTC), iv.End)
+	})
+
+	t.Run("fails on lone duration", func(t *testing.T) {
+		_, err := iso8601.ParseInterval("P1D")
+		assert.ErrorIs(t, err, iso8601.ErrInvalidInterval)
+	})
+}
+
+func TestPeriodicityLabel(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect string
+	}{
+		{input: "R/P1D", expect: "daily"},
+		{input: "R/P3M", expect: "quarterly"},
+		{input: "R/P3.5D", expect: "semiweekly"},
+		{input: "R/PT1S", expect: "continuously updated"},
+		{input: "irregular", expect: "irregular"},
+		{input: "R/P5D", expect: "every 5 days"},
+		{input: "R/P1Y6M", expect: "every year, 6 months"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			label, err := iso8601.PeriodicityLabel(test.input)
+			require.NoError(t, err)
+			assert.Equal(t, test.expect, label)
+		})
+	}
+
+	t.Run("fails on non-interval", func(t *testing.T) {
+		_, err := iso8601.PeriodicityLabel("sometimes")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,40 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// JSONLinesLoader writes each row as a line of NDJSON, so rows can be any
+// type encoding/json handles, such as structs.
+type JSONLinesLoader[T any] struct {
+	mu     *sync.Mutex
+	logger *zap.Logger
+}
+
+func NewJSONLinesLoader[T any](log *zap.Logger) *JSONLinesLoader[T] {
+	return &JSONLinesLoader[T]{
+		mu:     &sync.Mutex{},
+		logger: log,
+	}
+}
+
+func (l *JSONLinesLoader[T]) Load(ctx context.Context, rows []T, w io.Writer) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	enc := json.NewEncoder(w)
+
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			l.logger.Error("failed writing row as json", zap.Any("row", row), zap.Error(err))
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+//go:build unit
+
+package loader_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ralucas/centipede/internal/loader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestJSONLinesLoader(t *testing.T) {
+	type summary struct {
+		Title    string `json:"title"`
+		Keywords int    `json:"keywords"`
+	}
+
+	var out bytes.Buffer
+	l := loader.NewJSONLinesLoader[summary](zap.NewNop())
+
+	err := l.Load(context.TODO(), []summary{{"Air", 2}, {"Water", 0}}, &out)
+	require.NoError(t, err)
+
+	err = l.Load(context.TODO(), []summary{{"Soil", 1}}, &out)
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"title":"Air","keywords":2}
+{"title":"Water","keywords":0}
+{"title":"Soil","keywords":1}
+`, out.String())
+}
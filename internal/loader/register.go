@@ -0,0 +1,12 @@
+package loader
+
+import (
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+)
+
+func init() {
+	etl.RegisterLoader("csv", func(_ struct{}, log *zap.Logger) (etl.Loader, error) {
+		return NewCSVLoader(log), nil
+	})
+}
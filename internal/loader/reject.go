@@ -0,0 +1,75 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/ralucas/centipede/internal/streamreader"
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+)
+
+// RejectWriter is a dead-letter sink writing each failed record as a line
+// of NDJSON, which RejectIterator can replay once the records are fixed.
+type RejectWriter struct {
+	mu       sync.Mutex
+	enc      *json.Encoder
+	rejected int64
+	logger   *zap.Logger
+}
+
+func NewRejectWriter(w io.Writer, log *zap.Logger) *RejectWriter {
+	return &RejectWriter{
+		enc:    json.NewEncoder(w),
+		logger: log,
+	}
+}
+
+// Reject writes the record with its position, stage, branch and error, and
+// those of its failures in other branches.
+// Each entry is written straight through, so rejects survive a crashed run.
+func (w *RejectWriter) Reject(_ context.Context, err *etl.RecordError, record map[string]interface{}) error {
+	reject := streamreader.Reject{
+		Index:  err.Index,
+		Stage:  err.Stage,
+		Branch: err.Branch,
+		Error:  err.Err.Error(),
+		Record: record,
+	}
+
+	var invalid *streamreader.InvalidDatasetError
+	if errors.As(err, &invalid) {
+		reject.Field = invalid.Field
+	}
+
+	for _, other := range err.Others {
+		reject.Others = append(reject.Others, streamreader.RejectCause{
+			Stage:  other.Stage,
+			Branch: other.Branch,
+			Error:  other.Err.Error(),
+		})
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.enc.Encode(reject); err != nil {
+		w.logger.Error("failed to write reject", zap.Int64("index", reject.Index), zap.Error(err))
+		return err
+	}
+
+	w.rejected++
+
+	return nil
+}
+
+// Rejected returns the number of records written.
+func (w *RejectWriter) Rejected() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.rejected
+}
@@ -0,0 +1,87 @@
+//go:build unit
+
+package loader_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ralucas/centipede/internal/loader"
+	"github.com/ralucas/centipede/internal/streamreader"
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRejectWriter(t *testing.T) {
+	log, err := zap.NewDevelopment()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w := loader.NewRejectWriter(&buf, log)
+
+	invalid := map[string]interface{}{"title": "no access level"}
+	failed := map[string]interface{}{"identifier": "abc"}
+
+	errs := []struct {
+		err    *etl.RecordError
+		record map[string]interface{}
+	}{
+		{
+			err: &etl.RecordError{
+				Index: 3,
+				Stage: etl.StageRead,
+				Err:   streamreader.NewInvalidDatasetError(invalid, errors.New("field accessLevel in DatasetJson: required")),
+			},
+			record: invalid,
+		},
+		{
+			err:    &etl.RecordError{Index: 7, Stage: etl.StageExtract, Err: errors.New("test")},
+			record: failed,
+		},
+		{
+			err: &etl.RecordError{
+				Index:  9,
+				Stage:  etl.StageTransform,
+				Branch: "keywords",
+				Err:    errors.New("test"),
+				Others: []*etl.RecordError{{Index: 9, Stage: etl.StageExtract, Branch: "titles", Err: errors.New("other")}},
+			},
+			record: failed,
+		},
+	}
+
+	for _, e := range errs {
+		require.NoError(t, w.Reject(context.TODO(), e.err, e.record))
+	}
+	assert.Equal(t, int64(3), w.Rejected())
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 3)
+	assert.Contains(t, string(lines[0]), `"index":3,"stage":"read"`)
+	assert.Contains(t, string(lines[0]), `"field":"accessLevel"`)
+	assert.Contains(t, string(lines[1]), `"index":7,"stage":"extract","error":"test"`)
+	assert.NotContains(t, string(lines[1]), `"field"`)
+	assert.NotContains(t, string(lines[1]), `"branch"`)
+	assert.Contains(t, string(lines[2]), `"index":9,"stage":"transform","branch":"keywords","error":"test"`)
+	assert.Contains(t, string(lines[2]), `"others":[{"stage":"extract","branch":"titles","error":"other"}]`)
+	assert.NotContains(t, string(lines[1]), `"others"`)
+
+	// the rejects replay as input
+	ri := streamreader.NewRejectIterator(&buf, log)
+
+	var replayed []map[string]interface{}
+	for ri.HasNext() {
+		obj, err := ri.Next()
+		if errors.Is(err, etl.Done) {
+			break
+		}
+		require.NoError(t, err)
+		replayed = append(replayed, obj)
+	}
+
+	assert.Equal(t, []map[string]interface{}{invalid, failed, failed}, replayed)
+}
@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+)
+
+const namespace = "centipede"
+
+// shutdownTimeout bounds how long Serve waits for in-flight scrapes.
+const shutdownTimeout = 5 * time.Second
+
+// Prometheus is etl.Instrumentation backed by Prometheus collectors.
+type Prometheus struct {
+	recordsRead    prometheus.Counter
+	recordsWritten prometheus.Counter
+	rowsWritten    prometheus.Counter
+	errors         *prometheus.CounterVec
+	stageDuration  *prometheus.HistogramVec
+	workersBusy    prometheus.Gauge
+}
+
+type PrometheusOption func(*Prometheus, prometheus.Registerer)
+
+// WithBytesRead exposes the bytes read from c as a gauge.
+func WithBytesRead(c etl.ByteCounter) PrometheusOption {
+	return func(_ *Prometheus, reg prometheus.Registerer) {
+		reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "bytes_read",
+			Help:      "Bytes read from the input.",
+		}, func() float64 {
+			return float64(c.BytesRead())
+		}))
+	}
+}
+
+// NewPrometheus registers the pipeline metrics with reg.
+func NewPrometheus(reg prometheus.Registerer, opts ...PrometheusOption) *Prometheus {
+	p := &Prometheus{
+		recordsRead: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "records_read_total",
+			Help:      "Records read from the input.",
+		}),
+		recordsWritten: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "records_written_total",
+			Help:      "Records whose rows were written to the output.",
+		}),
+		rowsWritten: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rows_written_total",
+			Help:      "Rows written to the output.",
+		}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_total",
+			Help:      "Failures by pipeline stage.",
+		}, []string{"stage"}),
+		stageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "stage_duration_seconds",
+			Help:      "Time spent per record in each stage, per batch for load.",
+			Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+		}, []string{"stage"}),
+		workersBusy: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "workers_in_flight",
+			Help:      "Workers currently extracting and transforming a batch.",
+		}),
+	}
+
+	reg.MustRegister(p.recordsRead, p.recordsWritten, p.rowsWritten, p.errors, p.stageDuration, p.workersBusy)
+
+	for _, opt := range opts {
+		opt(p, reg)
+	}
+
+	return p
+}
+
+func (p *Prometheus) RecordsRead(n int) {
+	p.recordsRead.Add(float64(n))
+}
+
+func (p *Prometheus) RecordsWritten(n int) {
+	p.recordsWritten.Add(float64(n))
+}
+
+func (p *Prometheus) RowsWritten(n int) {
+	p.rowsWritten.Add(float64(n))
+}
+
+func (p *Prometheus) StageDuration(stage etl.Stage, d time.Duration) {
+	p.stageDuration.WithLabelValues(string(stage)).Observe(d.Seconds())
+}
+
+func (p *Prometheus) StageError(stage etl.Stage) {
+	p.errors.WithLabelValues(string(stage)).Inc()
+}
+
+func (p *Prometheus) WorkersBusy(delta int) {
+	p.workersBusy.Add(float64(delta))
+}
+
+// Serve exposes the metrics gathered by g on addr at /metrics until ctx is
+// done.
+func Serve(ctx context.Context, addr string, g prometheus.Gatherer, log *zap.Logger) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(g, promhttp.HandlerOpts{}))
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: shutdownTimeout,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		log.Info("serving metrics", zap.String("addr", addr))
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
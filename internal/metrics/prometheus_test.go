@@ -0,0 +1,109 @@
+//go:build unit
+
+package metrics_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/ralucas/centipede/internal/metrics"
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fixedBytes int64
+
+func (b fixedBytes) BytesRead() int64 {
+	return int64(b)
+}
+
+func TestPrometheus(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	p := metrics.NewPrometheus(reg, metrics.WithBytesRead(fixedBytes(2048)))
+
+	p.RecordsRead(3)
+	p.RecordsRead(2)
+	p.RecordsWritten(4)
+	p.RowsWritten(7)
+	p.StageError(etl.StageTransform)
+	p.StageError(etl.StageTransform)
+	p.StageError(etl.StageLoad)
+	p.StageDuration(etl.StageExtract, time.Millisecond)
+	p.WorkersBusy(2)
+	p.WorkersBusy(-1)
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+	series := make(map[string]int)
+	for _, f := range families {
+		m := f.GetMetric()
+		series[f.GetName()] = len(m)
+		if len(m[0].GetLabel()) > 0 {
+			continue
+		}
+		switch {
+		case m[0].GetCounter() != nil:
+			values[f.GetName()] = m[0].GetCounter().GetValue()
+		case m[0].GetGauge() != nil:
+			values[f.GetName()] = m[0].GetGauge().GetValue()
+		}
+	}
+
+	assert.Equal(t, map[string]float64{
+		"centipede_bytes_read":            2048,
+		"centipede_records_read_total":    5,
+		"centipede_records_written_total": 4,
+		"centipede_rows_written_total":    7,
+		"centipede_workers_in_flight":     1,
+	}, values)
+
+	assert.Equal(t, 2, series["centipede_errors_total"])
+	assert.Equal(t, 1, series["centipede_stage_duration_seconds"])
+}
+
+func TestServe(t *testing.T) {
+	log, err := zap.NewDevelopment()
+	require.NoError(t, err)
+
+	reg := prometheus.NewRegistry()
+	p := metrics.NewPrometheus(reg)
+	p.RecordsRead(1)
+
+	// reserve a free port for the server
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- metrics.Serve(ctx, addr, reg, log)
+	}()
+
+	var body []byte
+	require.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + addr + "/metrics")
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+
+		body, err = io.ReadAll(resp.Body)
+		return err == nil && resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Contains(t, string(body), "centipede_records_read_total 1")
+
+	cancel()
+	assert.NoError(t, <-errc)
+}
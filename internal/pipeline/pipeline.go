@@ -0,0 +1,544 @@
+package pipeline
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/ralucas/centipede/internal/filter"
+	"github.com/ralucas/centipede/internal/flatten"
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	// stock components, registered with etl
+	_ "github.com/ralucas/centipede/internal/aggregate"
+	_ "github.com/ralucas/centipede/internal/extractor"
+	_ "github.com/ralucas/centipede/internal/loader"
+	_ "github.com/ralucas/centipede/internal/streamreader"
+	_ "github.com/ralucas/centipede/internal/streamreader/custom"
+	_ "github.com/ralucas/centipede/internal/transformer"
+)
+
+var ErrInvalidPipeline = errors.New("invalid pipeline")
+
+// Schema is the published JSON schema pipeline files are validated against.
+//
+//go:embed pipeline.schema.json
+var Schema string
+
+var schema = jsonschema.MustCompileString("pipeline.schema.json", Schema)
+
+// DefaultFields are extracted when a pipeline doesn't list its fields, the
+// same as the --fields default.
+var DefaultFields = []string{"modified", "publisher.name", "publisher.subOrganizationOf.name", "contactPoint.fn", "keyword"}
+
+// Pipeline is a run described by a pipeline file.
+type Pipeline struct {
+	Version     int         `yaml:"version"`
+	Source      Source      `yaml:"source"`
+	Fields      []string    `yaml:"fields"`
+	Extractor   *Component  `yaml:"extractor"`
+	Discover    Discover    `yaml:"discover"`
+	Filters     []string    `yaml:"filters"`
+	Dedup       *Dedup      `yaml:"dedup"`
+	Transforms  Transforms  `yaml:"transforms"`
+	Sinks       []Sink      `yaml:"sinks"`
+	Errors      Errors      `yaml:"errors"`
+	Concurrency Concurrency `yaml:"concurrency"`
+	Report      string      `yaml:"report"`
+	MetricsAddr string      `yaml:"metrics_addr"`
+	Progress    bool        `yaml:"progress"`
+	Trace       Trace       `yaml:"trace"`
+}
+
+// Source reads the input with the source registered as Format.
+type Source struct {
+	Path     string                 `yaml:"path"`
+	Format   string                 `yaml:"format"`
+	Validate bool                   `yaml:"validate"`
+	Options  map[string]interface{} `yaml:"options"`
+}
+
+// stock source formats
+const (
+	FormatJSON    = "json"
+	FormatCustom  = "custom"
+	FormatRejects = "rejects"
+)
+
+// Component names a registered component and holds its settings.
+type Component struct {
+	Name    string                 `yaml:"name"`
+	Options map[string]interface{} `yaml:"options"`
+}
+
+type Discover struct {
+	Sample      int    `yaml:"sample"`
+	LateColumns string `yaml:"late_columns"`
+}
+
+type Dedup struct {
+	Mode      string   `yaml:"mode"`
+	Key       []string `yaml:"key"`
+	Keep      string   `yaml:"keep"`
+	Dir       string   `yaml:"dir"`
+	MemoryMiB int      `yaml:"memory_mib"`
+	Expected  int      `yaml:"expected"`
+	Log       bool     `yaml:"log"`
+}
+
+type Transforms struct {
+	NormalizeDates *NormalizeDates `yaml:"normalize_dates"`
+	Aggregate      *Aggregate      `yaml:"aggregate"`
+	Sort           *Sort           `yaml:"sort"`
+	Custom         []Component     `yaml:"custom"`
+}
+
+type NormalizeDates struct {
+	Layout   string `yaml:"layout"`
+	Timezone string `yaml:"timezone"`
+}
+
+type Aggregate struct {
+	GroupBy      []string `yaml:"group_by"`
+	Aggregations []string `yaml:"aggregations"`
+	MaxGroups    int      `yaml:"max_groups"`
+	Dir          string   `yaml:"dir"`
+}
+
+type Sort struct {
+	By        []string `yaml:"by"`
+	MemoryMiB int      `yaml:"memory_mib"`
+	Dir       string   `yaml:"dir"`
+}
+
+// Sink is an output written by the loader registered as Format. Sinks
+// after the first are named after their file when they have no name of
+// their own.
+type Sink struct {
+	Name    string                 `yaml:"name"`
+	Path    string                 `yaml:"path"`
+	Fields  []string               `yaml:"fields"`
+	Format  string                 `yaml:"format"`
+	Options map[string]interface{} `yaml:"options"`
+}
+
+type Errors struct {
+	OnError      string `yaml:"on_error"`
+	MaxErrors    int    `yaml:"max_errors"`
+	MaxErrorRate Rate   `yaml:"max_error_rate"`
+	RejectFile   string `yaml:"reject_file"`
+}
+
+// Rate is an error rate written either as a number or a percentage.
+type Rate string
+
+func (r *Rate) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case float64:
+		*r = Rate(strconv.FormatFloat(v, 'f', -1, 64))
+	case nil:
+		*r = ""
+	default:
+		*r = Rate(fmt.Sprint(v))
+	}
+
+	return nil
+}
+
+type Concurrency struct {
+	Workers       int           `yaml:"workers"`
+	BatchSize     int           `yaml:"batch_size"`
+	BatchTimeout  time.Duration `yaml:"batch_timeout"`
+	Ordered       bool          `yaml:"ordered"`
+	ReorderBuffer int           `yaml:"reorder_buffer"`
+}
+
+// Trace exports spans of the run when Exporter is set.
+type Trace struct {
+	Exporter   string  `yaml:"exporter"`
+	File       string  `yaml:"file"`
+	RecordRate float64 `yaml:"record_rate"`
+}
+
+// Error is a problem with a pipeline file, located at the line of the value
+// at fault. It matches ErrInvalidPipeline.
+type Error struct {
+	File    string
+	Line    int
+	Column  int
+	Path    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s: %s", e.File, e.Line, e.Column, e.Path, e.Message)
+}
+
+func (e *Error) Is(target error) bool {
+	return target == ErrInvalidPipeline
+}
+
+// Load reads, validates and decodes the pipeline file at path.
+func Load(path string) (*Pipeline, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(path, src)
+}
+
+// Parse decodes a pipeline file named name. ${VAR} and ${VAR:-default} are
+// replaced by environment variables before the file is parsed, $$ escapes a
+// dollar sign. The document is validated against Schema, every violation
+// is reported as an Error and joined.
+func Parse(name string, src []byte) (*Pipeline, error) {
+	src, err := interpolate(name, src, os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := parser.ParseBytes(src, 0)
+	if err != nil {
+		return nil, syntaxError(name, err)
+	}
+
+	doc, err := yaml.YAMLToJSON(src)
+	if err != nil {
+		return nil, syntaxError(name, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidPipeline, name, err)
+	}
+
+	var verr *jsonschema.ValidationError
+	if err := schema.Validate(v); errors.As(err, &verr) {
+		return nil, schemaErrors(name, file, verr)
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidPipeline, name, err)
+	}
+
+	p := defaults()
+	if err := yaml.Unmarshal(src, p); err != nil {
+		return nil, syntaxError(name, err)
+	}
+
+	p.setDefaults()
+
+	if err := p.check(name, file); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func defaults() *Pipeline {
+	return &Pipeline{
+		Source:   Source{Format: FormatJSON},
+		Fields:   DefaultFields,
+		Discover: Discover{Sample: 1000, LateColumns: "drop"},
+		Errors:   Errors{OnError: "fail"},
+		Trace:    Trace{File: "traces.json", RecordRate: 1},
+		Concurrency: Concurrency{
+			BatchSize:     100,
+			BatchTimeout:  time.Second,
+			ReorderBuffer: 1000,
+		},
+	}
+}
+
+// setDefaults fills in the optional sections that are present.
+func (p *Pipeline) setDefaults() {
+	if d := p.Dedup; d != nil {
+		d.Keep = orDefault(d.Keep, "first")
+		d.MemoryMiB = orDefault(d.MemoryMiB, 64)
+		d.Expected = orDefault(d.Expected, 1000000)
+	}
+
+	if nd := p.Transforms.NormalizeDates; nd != nil {
+		nd.Layout = orDefault(nd.Layout, time.RFC3339)
+		nd.Timezone = orDefault(nd.Timezone, "UTC")
+	}
+
+	if agg := p.Transforms.Aggregate; agg != nil {
+		if len(agg.Aggregations) == 0 {
+			agg.Aggregations = []string{"count"}
+		}
+		agg.MaxGroups = orDefault(agg.MaxGroups, 100000)
+	}
+
+	if s := p.Transforms.Sort; s != nil {
+		s.MemoryMiB = orDefault(s.MemoryMiB, 256)
+	}
+
+	for i := range p.Sinks {
+		s := &p.Sinks[i]
+		s.Format = orDefault(s.Format, "csv")
+		if i > 0 {
+			s.Name = orDefault(s.Name, strings.TrimSuffix(filepath.Base(s.Path), filepath.Ext(s.Path)))
+		}
+	}
+}
+
+func orDefault[T comparable](v, def T) T {
+	var zero T
+	if v == zero {
+		return def
+	}
+
+	return v
+}
+
+// check validates what the schema can't express, including that every
+// component is registered and takes its options.
+func (p *Pipeline) check(name string, file *ast.File) error {
+	var errs []error
+
+	// unknown names are reported at the name, bad config at the options
+	checkComponent := func(namePath, optionsPath string, kind etl.Kind, component string, config interface{}) {
+		err := etl.CheckComponent(kind, component, config)
+		switch {
+		case errors.Is(err, etl.ErrUnknownComponent):
+			errs = append(errs, errorAt(name, file, namePath, err.Error()))
+		case err != nil:
+			errs = append(errs, errorAt(name, file, optionsPath, err.Error()))
+		}
+	}
+
+	checkComponent("$.source.format", "$.source.options", etl.KindSource, p.Source.Format, p.Source.Config())
+
+	if p.Extractor != nil {
+		checkComponent("$.extractor.name", "$.extractor.options", etl.KindExtractor, p.Extractor.Name, p.Extractor.Options)
+	}
+
+	for i, c := range p.Transforms.Custom {
+		path := fmt.Sprintf("$.transforms.custom[%d]", i)
+		checkComponent(path+".name", path+".options", etl.KindTransformer, c.Name, c.Options)
+	}
+
+	for i, s := range p.Sinks {
+		path := fmt.Sprintf("$.sinks[%d]", i)
+		checkComponent(path+".format", path+".options", etl.KindLoader, s.Format, s.Options)
+	}
+
+	for i, expr := range p.Filters {
+		if _, err := filter.Parse(expr); err != nil {
+			errs = append(errs, errorAt(name, file, fmt.Sprintf("$.filters[%d]", i), err.Error()))
+		}
+	}
+
+	if len(p.Sinks) > 0 && len(p.Sinks[0].Fields) > 0 {
+		errs = append(errs, errorAt(name, file, "$.sinks[0].fields", "the first sink writes the top-level fields, set them there"))
+	}
+
+	names := make(map[string]int)
+
+	for i, s := range p.Sinks[1:] {
+		i++
+		path := fmt.Sprintf("$.sinks[%d]", i)
+
+		if j, ok := names[s.Name]; ok {
+			errs = append(errs, errorAt(name, file, path, fmt.Sprintf("name %q is also used by sinks[%d]", s.Name, j)))
+		}
+		names[s.Name] = i
+
+		switch {
+		case p.Transforms.Aggregate != nil && len(s.Fields) > 0:
+			errs = append(errs, errorAt(name, file, path+".fields", "fields can't be set with transforms.aggregate, sinks get the aggregated columns"))
+		case p.Transforms.Aggregate == nil && len(s.Fields) == 0:
+			errs = append(errs, errorAt(name, file, path, "fields are required for every sink after the first"))
+		case flatten.IsPattern(s.Fields):
+			errs = append(errs, errorAt(name, file, path+".fields", "only the first sink can discover fields from patterns"))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Config is the source's config, its options with validate set when the
+// source validates.
+func (s Source) Config() map[string]interface{} {
+	config := make(map[string]interface{}, len(s.Options)+1)
+	for k, v := range s.Options {
+		config[k] = v
+	}
+
+	if s.Validate {
+		config["validate"] = true
+	}
+
+	return config
+}
+
+var envPattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// interpolate replaces environment variables line by line, so an unset
+// variable can be reported at its line.
+func interpolate(name string, src []byte, lookupEnv func(string) (string, bool)) ([]byte, error) {
+	lines := bytes.SplitAfter(src, []byte("\n"))
+
+	var errs []error
+
+	for i, line := range lines {
+		lines[i] = envPattern.ReplaceAllFunc(line, func(m []byte) []byte {
+			if string(m) == "$$" {
+				return []byte("$")
+			}
+
+			sm := envPattern.FindSubmatch(m)
+			if v, ok := lookupEnv(string(sm[1])); ok && (v != "" || sm[2] == nil) {
+				return []byte(v)
+			}
+			if sm[2] != nil {
+				return sm[3]
+			}
+
+			errs = append(errs, &Error{
+				File:    name,
+				Line:    i + 1,
+				Column:  bytes.Index(line, m) + 1,
+				Message: fmt.Sprintf("environment variable %s is not set", sm[1]),
+			})
+
+			return m
+		})
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return bytes.Join(lines, nil), nil
+}
+
+var positionPattern = regexp.MustCompile(`^\[(\d+):(\d+)\] (.*)`)
+
+// syntaxError reports a YAML error at the position the parser gives.
+func syntaxError(name string, err error) error {
+	msg := yaml.FormatError(err, false, false)
+
+	m := positionPattern.FindStringSubmatch(msg)
+	if m == nil {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidPipeline, name, msg)
+	}
+
+	line, _ := strconv.Atoi(m[1])
+	column, _ := strconv.Atoi(m[2])
+
+	return &Error{File: name, Line: line, Column: column, Message: m[3]}
+}
+
+var additionalPattern = regexp.MustCompile(`^additionalProperties '([^']+)' not allowed$`)
+
+// schemaErrors reports the innermost causes of a validation error, each at
+// the line of the value it is about.
+func schemaErrors(name string, file *ast.File, verr *jsonschema.ValidationError) error {
+	var errs []error
+	seen := make(map[string]bool)
+
+	var walk func(*jsonschema.ValidationError)
+	walk = func(ve *jsonschema.ValidationError) {
+		if len(ve.Causes) > 0 {
+			for _, c := range ve.Causes {
+				walk(c)
+			}
+			return
+		}
+
+		path := yamlPath(ve.InstanceLocation)
+		msg := ve.Message
+
+		// point at the unknown key rather than the object holding it
+		if m := additionalPattern.FindStringSubmatch(msg); m != nil {
+			path += "." + m[1]
+			msg = fmt.Sprintf("unknown field %q", m[1])
+		}
+
+		err := errorAt(name, file, path, msg)
+		if !seen[err.Error()] {
+			seen[err.Error()] = true
+			errs = append(errs, err)
+		}
+	}
+	walk(verr)
+
+	return errors.Join(errs...)
+}
+
+// yamlPath turns a JSON pointer such as /sinks/0/path into a YAML path such
+// as $.sinks[0].path.
+func yamlPath(pointer string) string {
+	path := "$"
+	for _, tok := range strings.Split(pointer, "/")[1:] {
+		tok = strings.NewReplacer("~1", "/", "~0", "~").Replace(tok)
+		if _, err := strconv.Atoi(tok); err == nil {
+			path += "[" + tok + "]"
+		} else {
+			path += "." + tok
+		}
+	}
+
+	return path
+}
+
+// errorAt locates the value at path in the file, falling back to its
+// closest parent that is found.
+func errorAt(name string, file *ast.File, path, msg string) *Error {
+	e := &Error{File: name, Line: 1, Column: 1, Path: strings.TrimPrefix(path, "$."), Message: msg}
+	if path == "$" {
+		e.Path = ""
+	}
+
+	for p := path; p != "$" && p != ""; p = parent(p) {
+		yp, err := yaml.PathString(p)
+		if err != nil {
+			continue
+		}
+
+		node, err := yp.FilterFile(file)
+		if err != nil || node == nil {
+			continue
+		}
+
+		pos := node.GetToken().Position
+		e.Line, e.Column = pos.Line, pos.Column
+
+		break
+	}
+
+	return e
+}
+
+func parent(path string) string {
+	if i := strings.LastIndexAny(path, ".["); i > 0 {
+		return path[:i]
+	}
+
+	return "$"
+}
@@ -0,0 +1,202 @@
+//go:build unit
+
+package pipeline_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ralucas/centipede/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fullPipeline = `version: 1
+source:
+  path: ${CENTIPEDE_TEST_INPUT}
+  format: custom
+  validate: true
+fields: [identifier, publisher.name]
+filters:
+  - accessLevel=public
+dedup:
+  mode: exact
+  key: [identifier]
+transforms:
+  normalize_dates:
+    timezone: ${CENTIPEDE_TEST_TZ:-America/Denver}
+  sort:
+    by: [identifier]
+sinks:
+  - path: out.csv
+  - name: titles
+    path: titles.csv
+    fields: [identifier, title]
+  - path: ${CENTIPEDE_TEST_DIR:-.}/keywords.csv
+    fields: [identifier, keyword]
+errors:
+  on_error: threshold
+  max_errors: 10
+  max_error_rate: 0.5%
+concurrency:
+  workers: 4
+  batch_timeout: 250ms
+  ordered: true
+trace:
+  exporter: file
+`
+
+func TestParse(t *testing.T) {
+	t.Setenv("CENTIPEDE_TEST_INPUT", "data.json")
+
+	p, err := pipeline.Parse("pipeline.yaml", []byte(fullPipeline))
+	require.NoError(t, err)
+
+	assert.Equal(t, pipeline.Source{Path: "data.json", Format: pipeline.FormatCustom, Validate: true}, p.Source)
+	assert.Equal(t, []string{"identifier", "publisher.name"}, p.Fields)
+	assert.Equal(t, []string{"accessLevel=public"}, p.Filters)
+
+	require.NotNil(t, p.Dedup)
+	assert.Equal(t, "first", p.Dedup.Keep)
+	assert.Equal(t, 64, p.Dedup.MemoryMiB)
+
+	require.NotNil(t, p.Transforms.NormalizeDates)
+	assert.Equal(t, pipeline.NormalizeDates{Layout: time.RFC3339, Timezone: "America/Denver"}, *p.Transforms.NormalizeDates)
+	assert.Nil(t, p.Transforms.Aggregate)
+	require.NotNil(t, p.Transforms.Sort)
+	assert.Equal(t, 256, p.Transforms.Sort.MemoryMiB)
+
+	assert.Equal(t, []pipeline.Sink{
+		{Path: "out.csv", Format: "csv"},
+		{Name: "titles", Path: "titles.csv", Fields: []string{"identifier", "title"}, Format: "csv"},
+		{Name: "keywords", Path: "./keywords.csv", Fields: []string{"identifier", "keyword"}, Format: "csv"},
+	}, p.Sinks)
+
+	assert.Equal(t, pipeline.Errors{OnError: "threshold", MaxErrors: 10, MaxErrorRate: "0.5%"}, p.Errors)
+	assert.Equal(t, pipeline.Concurrency{
+		Workers:       4,
+		BatchSize:     100,
+		BatchTimeout:  250 * time.Millisecond,
+		Ordered:       true,
+		ReorderBuffer: 1000,
+	}, p.Concurrency)
+	assert.Equal(t, pipeline.Trace{Exporter: "file", File: "traces.json", RecordRate: 1}, p.Trace)
+}
+
+func TestParseDefaults(t *testing.T) {
+	p, err := pipeline.Parse("pipeline.yaml", []byte("version: 1\nsource: {path: data.json}\nsinks: [{path: out.csv}]\nerrors: {max_error_rate: 0.005}\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, pipeline.FormatJSON, p.Source.Format)
+	assert.Equal(t, pipeline.DefaultFields, p.Fields)
+	assert.Equal(t, pipeline.Discover{Sample: 1000, LateColumns: "drop"}, p.Discover)
+	assert.Nil(t, p.Dedup)
+	assert.Equal(t, pipeline.Rate("0.005"), p.Errors.MaxErrorRate)
+	assert.Equal(t, "fail", p.Errors.OnError)
+	assert.Empty(t, p.Trace.Exporter)
+}
+
+func TestParseInterpolation(t *testing.T) {
+	t.Setenv("CENTIPEDE_TEST_EMPTY", "")
+
+	src := "version: 1\nsource:\n  path: $${HOME}/${CENTIPEDE_TEST_EMPTY:-data.json}\nsinks:\n  - path: out${CENTIPEDE_TEST_EMPTY}.csv\n"
+
+	p, err := pipeline.Parse("pipeline.yaml", []byte(src))
+	require.NoError(t, err)
+	assert.Equal(t, "${HOME}/data.json", p.Source.Path)
+	assert.Equal(t, "out.csv", p.Sinks[0].Path)
+
+	_, err = pipeline.Parse("pipeline.yaml", []byte("version: 1\nsource:\n  path: ${CENTIPEDE_TEST_UNSET}\n"))
+	assert.ErrorIs(t, err, pipeline.ErrInvalidPipeline)
+	assert.EqualError(t, err, "pipeline.yaml:3:9: environment variable CENTIPEDE_TEST_UNSET is not set")
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		errs []string
+	}{
+		{
+			name: "unknown field",
+			src:  "version: 1\nsource:\n  path: data.json\n  formatt: json\nsinks: [{path: out.csv}]\n",
+			errs: []string{`pipeline.yaml:4:12: source.formatt: unknown field "formatt"`},
+		},
+		{
+			name: "bad enum",
+			src:  "version: 1\nsource:\n  path: data.json\nsinks:\n  - path: out.csv\nerrors:\n  on_error: ignore\n",
+			errs: []string{`pipeline.yaml:7:13: errors.on_error: value must be one of "fail", "skip", "threshold"`},
+		},
+		{
+			name: "missing and mistyped",
+			src:  "version: 1\nsinks:\n  - path: out.csv\n    fields: identifier\n",
+			errs: []string{
+				`pipeline.yaml:1:1: missing properties: 'source'`,
+				`pipeline.yaml:4:13: sinks[0].fields: expected array, but got string`,
+			},
+		},
+		{
+			name: "bad duration",
+			src:  "version: 1\nsource: {path: data.json}\nsinks: [{path: out.csv}]\nconcurrency:\n  batch_timeout: 5\n",
+			errs: []string{`pipeline.yaml:5:18: concurrency.batch_timeout: expected string, but got number`},
+		},
+		{
+			name: "extra sinks",
+			src:  "version: 1\nsource: {path: data.json}\nfields: ['*']\nfilters: ['title~(']\nsinks:\n  - path: out.csv\n  - path: a.csv\n  - path: b.csv\n    fields: ['publisher.*']\n  - name: b\n    path: c.csv\n    fields: [title]\n",
+			errs: []string{
+				"pipeline.yaml:4:11: filters[0]: invalid filter: \"title~(\": error parsing regexp: missing closing ): `(`",
+				`pipeline.yaml:7:9: sinks[1]: fields are required for every sink after the first`,
+				`pipeline.yaml:9:13: sinks[2].fields: only the first sink can discover fields from patterns`,
+				`pipeline.yaml:10:9: sinks[3]: name "b" is also used by sinks[2]`,
+			},
+		},
+		{
+			name: "first sink fields",
+			src:  "version: 1\nsource: {path: data.json}\nsinks:\n  - path: out.csv\n    fields: [title]\n  - path: a.csv\n    fields: [title]\n",
+			errs: []string{`pipeline.yaml:5:13: sinks[0].fields: the first sink writes the top-level fields, set them there`},
+		},
+		{
+			name: "components",
+			src:  "version: 1\nsource:\n  path: data.json\n  format: xml\nextractor:\n  name: flatten\n  options: {late_columns: [drop]}\ntransforms:\n  custom:\n    - name: normalize_dates\n      options: {timezone: UTC, zone: UTC}\nsinks:\n  - path: out.csv\n    format: parquet\n",
+			errs: []string{
+				`pipeline.yaml:4:11: source.format: unknown component: no source named "xml", expected one of custom, json, rejects`,
+				`pipeline.yaml:7:12: extractor.options: invalid component config: json: cannot unmarshal array into Go struct field FlattenConfig.late_columns of type string`,
+				`pipeline.yaml:11:16: transforms.custom[0].options: invalid component config: json: unknown field "zone"`,
+				`pipeline.yaml:14:13: sinks[0].format: unknown component: no loader named "parquet", expected one of csv`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pipeline.Parse("pipeline.yaml", []byte(tt.src))
+			require.ErrorIs(t, err, pipeline.ErrInvalidPipeline)
+
+			var msgs []string
+			for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+				var perr *pipeline.Error
+				require.True(t, errors.As(e, &perr))
+				msgs = append(msgs, perr.Error())
+			}
+			assert.ElementsMatch(t, tt.errs, msgs)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "pipeline.yaml")
+	require.NoError(t, os.WriteFile(name, []byte("version: 2\nsource: {path: data.json}\nsinks: [{path: out.csv}]\n"), 0o644))
+
+	_, err := pipeline.Load(name)
+	assert.ErrorIs(t, err, pipeline.ErrInvalidPipeline)
+	assert.ErrorContains(t, err, name+":1:10: version: value must be \"1\"")
+}
+
+func TestParseSyntaxError(t *testing.T) {
+	_, err := pipeline.Parse("pipeline.yaml", []byte("version: 1\nsource:\n  path: a\n   format: b: c\n"))
+	assert.ErrorIs(t, err, pipeline.ErrInvalidPipeline)
+	assert.EqualError(t, err, "pipeline.yaml:3:9: unexpected key name")
+}
@@ -0,0 +1,193 @@
+package preview
+
+import (
+	"context"
+	"io"
+	"iter"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/ralucas/centipede/pkg/etl"
+)
+
+const (
+	// MaxCellWidth is the number of characters a cell is cut to.
+	MaxCellWidth = 40
+	columnGap    = "  "
+	ellipsis     = "…"
+)
+
+// Limit reads at most n records of si, counting those that fail to read.
+func Limit(ctx context.Context, si etl.StreamIterator, n int) etl.StreamIterator {
+	return etl.FromSeq(iter.Seq2[map[string]interface{}, error](func(yield func(map[string]interface{}, error) bool) {
+		if n <= 0 {
+			return
+		}
+
+		read := 0
+		for obj, err := range etl.Records(ctx, si) {
+			if !yield(obj, err) {
+				return
+			}
+
+			if read++; read >= n {
+				return
+			}
+		}
+	}))
+}
+
+// Table is a loader holding the rows it is handed, the first being the
+// header, to render them as an aligned table.
+type Table struct {
+	mu     sync.Mutex
+	header []string
+	rows   [][]string
+	// uncut are the columns whose cells are never cut
+	uncut map[string]bool
+}
+
+type TableOption func(*Table)
+
+// WithUncutColumns keeps the cells of the named columns whole, however
+// long they are.
+func WithUncutColumns(columns ...string) TableOption {
+	return func(t *Table) {
+		for _, col := range columns {
+			t.uncut[col] = true
+		}
+	}
+}
+
+func NewTable(opts ...TableOption) *Table {
+	t := &Table{
+		uncut: make(map[string]bool),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+func (t *Table) Load(_ context.Context, rows [][]string, _ io.Writer) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.header == nil && len(rows) > 0 {
+		t.header, rows = rows[0], rows[1:]
+	}
+
+	for _, row := range rows {
+		t.rows = append(t.rows, append([]string(nil), row...))
+	}
+
+	return nil
+}
+
+// Rows returns the number of rows loaded, without the header.
+func (t *Table) Rows() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.rows)
+}
+
+// EmptyColumns returns the columns that are empty in every row, which is
+// none when there are no rows.
+func (t *Table) EmptyColumns() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.rows) == 0 {
+		return nil
+	}
+
+	var empty []string
+	for i, col := range t.header {
+		filled := false
+		for _, row := range t.rows {
+			if i < len(row) && strings.TrimSpace(row[i]) != "" {
+				filled = true
+				break
+			}
+		}
+
+		if !filled {
+			empty = append(empty, col)
+		}
+	}
+
+	return empty
+}
+
+// Render writes the header, a rule and the rows to w with the columns
+// aligned. Line breaks and tabs in cells become spaces and cells longer
+// than MaxCellWidth are cut, unless their column is uncut.
+func (t *Table) Render(w io.Writer) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := make([][]string, 0, len(t.rows)+2)
+	lines = append(lines, t.cells(t.header))
+
+	rule := make([]string, len(t.header))
+	lines = append(lines, rule)
+
+	for _, row := range t.rows {
+		lines = append(lines, t.cells(row))
+	}
+
+	widths := make([]int, len(t.header))
+	for _, line := range lines {
+		for i, cell := range line {
+			if i < len(widths) {
+				widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+			}
+		}
+	}
+
+	for i, width := range widths {
+		rule[i] = strings.Repeat("-", width)
+	}
+
+	var b strings.Builder
+	for _, line := range lines {
+		for i, width := range widths {
+			var cell string
+			if i < len(line) {
+				cell = line[i]
+			}
+
+			if i == len(widths)-1 {
+				b.WriteString(cell)
+				break
+			}
+
+			b.WriteString(cell)
+			b.WriteString(strings.Repeat(" ", width-utf8.RuneCountInString(cell)))
+			b.WriteString(columnGap)
+		}
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// cells makes the cells of a row fit on one line of the table.
+func (t *Table) cells(row []string) []string {
+	out := make([]string, len(row))
+	for i, cell := range row {
+		cell = strings.Join(strings.Fields(cell), " ")
+		if utf8.RuneCountInString(cell) > MaxCellWidth && !(i < len(t.header) && t.uncut[t.header[i]]) {
+			cell = string([]rune(cell)[:MaxCellWidth-1]) + ellipsis
+		}
+		out[i] = cell
+	}
+
+	return out
+}
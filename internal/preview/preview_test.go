@@ -0,0 +1,117 @@
+//go:build unit
+
+package preview_test
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"strings"
+	"testing"
+
+	"github.com/ralucas/centipede/internal/preview"
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTable(t *testing.T) {
+	table := preview.NewTable()
+
+	err := table.Load(context.TODO(), [][]string{{"title", "contactPoint.fn", "keyword"}, {"Air", "", "a\nb"}}, nil)
+	require.NoError(t, err)
+
+	err = table.Load(context.TODO(), [][]string{{"Water quality " + strings.Repeat("x", 40), " ", "water"}}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, table.Rows())
+	assert.Equal(t, []string{"contactPoint.fn"}, table.EmptyColumns())
+
+	var out strings.Builder
+	require.NoError(t, table.Render(&out))
+
+	assert.Equal(t, ""+
+		"title                                     contactPoint.fn  keyword\n"+
+		"----------------------------------------  ---------------  -------\n"+
+		"Air                                                        a b\n"+
+		"Water quality xxxxxxxxxxxxxxxxxxxxxxxxx…                   water\n",
+		out.String())
+}
+
+func TestTableUncutColumns(t *testing.T) {
+	long := strings.Repeat("x", 50)
+
+	table := preview.NewTable(preview.WithUncutColumns("path"))
+
+	err := table.Load(context.TODO(), [][]string{{"path", "examples"}, {long, long}}, nil)
+	require.NoError(t, err)
+
+	var out strings.Builder
+	require.NoError(t, table.Render(&out))
+
+	lines := strings.Split(out.String(), "\n")
+	assert.Equal(t, long+"  "+strings.Repeat("x", 39)+"…", lines[2])
+}
+
+func TestTableEmpty(t *testing.T) {
+	table := preview.NewTable()
+
+	err := table.Load(context.TODO(), [][]string{{"title"}}, nil)
+	require.NoError(t, err)
+
+	assert.Nil(t, table.EmptyColumns())
+
+	var out strings.Builder
+	require.NoError(t, table.Render(&out))
+	assert.Equal(t, "title\n-----\n", out.String())
+}
+
+func TestLimit(t *testing.T) {
+	errRead := errors.New("unreadable record")
+
+	records := func(yield func(map[string]interface{}, error) bool) {
+		for i := 0; i < 5; i++ {
+			var err error
+			if i == 1 {
+				err = errRead
+			}
+
+			if !yield(map[string]interface{}{"n": i}, err) {
+				return
+			}
+		}
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []interface{}
+		errs  int
+	}{
+		{name: "first records", limit: 3, want: []interface{}{0, 2}, errs: 1},
+		{name: "beyond the input", limit: 10, want: []interface{}{0, 2, 3, 4}, errs: 1},
+		{name: "none", limit: 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			si := etl.FromSeq(iter.Seq2[map[string]interface{}, error](records))
+
+			var (
+				got  []interface{}
+				errs int
+			)
+			for obj, err := range etl.Records(context.TODO(), preview.Limit(context.TODO(), si, tc.limit)) {
+				if err != nil {
+					assert.ErrorIs(t, err, errRead)
+					errs++
+					continue
+				}
+				got = append(got, obj["n"])
+			}
+
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.errs, errs)
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package profile
+
+import (
+	"encoding/json"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/ralucas/centipede/internal/flatten"
+)
+
+// MaxExamples is the number of distinct example values kept per path.
+const MaxExamples = 3
+
+// JSON types of the values seen at a path.
+const (
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeNull    = "null"
+)
+
+// Profile describes the leaf paths of the records profiled.
+type Profile struct {
+	Records int     `json:"records"`
+	Fields  []Field `json:"fields"`
+}
+
+// Field describes one leaf path, named as --fields selects it.
+type Field struct {
+	Path string `json:"path"`
+	// Types are the json types of the values seen, sorted.
+	Types []string `json:"types"`
+	// Present is the number of records having the path and Filled the
+	// number of those with a non-empty value.
+	Present  int     `json:"present"`
+	Filled   int     `json:"filled"`
+	FillRate float64 `json:"fillRate"`
+	// Cardinality is the number of distinct values, an estimate once there
+	// are too many to count exactly.
+	Cardinality      int  `json:"cardinality"`
+	CardinalityExact bool `json:"cardinalityExact"`
+	// Array tells whether the path's values are in arrays, which explode
+	// into one row per value.
+	Array bool `json:"array"`
+	// FlattenOnly tells whether the path crosses an array of objects, like
+	// distribution.format, which only the flatten extractor reads.
+	FlattenOnly bool     `json:"flattenOnly"`
+	Examples    []string `json:"examples"`
+}
+
+// Profiler accumulates the leaf paths of records. Paths follow the
+// flattening of field patterns, so an array of objects such as distribution
+// has paths like distribution.format.
+type Profiler struct {
+	records int
+	fields  map[string]*stats
+}
+
+type stats struct {
+	types       map[string]bool
+	present     int
+	filled      int
+	distinct    *Sketch
+	array       bool
+	flattenOnly bool
+	examples    []string
+}
+
+func NewProfiler() *Profiler {
+	return &Profiler{
+		fields: make(map[string]*stats),
+	}
+}
+
+// Observe adds a decoded record to the profile.
+func (p *Profiler) Observe(record map[string]interface{}) {
+	p.records++
+
+	// filled holds whether each path of the record has a non-empty value
+	filled := make(map[string]bool)
+	for k, v := range record {
+		p.walk(filled, k, v, false, false)
+	}
+
+	for path, ok := range filled {
+		s := p.fields[path]
+		s.present++
+		if ok {
+			s.filled++
+		}
+	}
+}
+
+// walk adds the leaf paths under v, inArray telling whether v is in an
+// array and underObjects whether an object in an array was crossed to reach
+// it.
+func (p *Profiler) walk(filled map[string]bool, path string, v interface{}, inArray, underObjects bool) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			p.walk(filled, path+flatten.Separator+k, child, inArray, underObjects || inArray)
+		}
+	case []interface{}:
+		// arrays of objects are only the prefix of their keys' paths
+		if !hasObjects(val) {
+			p.stats(path).array = true
+			mark(filled, path, false)
+		}
+
+		for _, item := range val {
+			p.walk(filled, path, item, true, underObjects)
+		}
+	default:
+		s := p.stats(path)
+		s.array = s.array || inArray
+		s.flattenOnly = s.flattenOnly || underObjects
+
+		typ, rendered := describe(val)
+		s.types[typ] = true
+
+		mark(filled, path, rendered != "")
+		if rendered == "" {
+			return
+		}
+
+		s.distinct.Add(rendered)
+		if len(s.examples) < MaxExamples && !slices.Contains(s.examples, rendered) {
+			s.examples = append(s.examples, rendered)
+		}
+	}
+}
+
+func (p *Profiler) stats(path string) *stats {
+	s, ok := p.fields[path]
+	if !ok {
+		s = &stats{
+			types:    make(map[string]bool),
+			distinct: NewSketch(),
+		}
+		p.fields[path] = s
+	}
+
+	return s
+}
+
+// Profile returns the profile of the records observed, with the paths
+// sorted.
+func (p *Profiler) Profile() Profile {
+	prof := Profile{
+		Records: p.records,
+		Fields:  make([]Field, 0, len(p.fields)),
+	}
+
+	for path, s := range p.fields {
+		types := make([]string, 0, len(s.types))
+		for typ := range s.types {
+			types = append(types, typ)
+		}
+		sort.Strings(types)
+
+		cardinality, exact := s.distinct.Estimate()
+
+		f := Field{
+			Path:             path,
+			Types:            types,
+			Present:          s.present,
+			Filled:           s.filled,
+			Cardinality:      cardinality,
+			CardinalityExact: exact,
+			Array:            s.array,
+			FlattenOnly:      s.flattenOnly,
+			Examples:         append([]string{}, s.examples...),
+		}
+		if p.records > 0 {
+			f.FillRate = float64(s.filled) / float64(p.records)
+		}
+
+		prof.Fields = append(prof.Fields, f)
+	}
+
+	sort.Slice(prof.Fields, func(i, j int) bool {
+		return prof.Fields[i].Path < prof.Fields[j].Path
+	})
+
+	return prof
+}
+
+// describe returns the json type of a scalar and its value as a string,
+// empty for null.
+func describe(v interface{}) (string, string) {
+	switch val := v.(type) {
+	case nil:
+		return TypeNull, ""
+	case string:
+		return TypeString, val
+	case float64:
+		return TypeNumber, strconv.FormatFloat(val, 'f', -1, 64)
+	case json.Number:
+		return TypeNumber, val.String()
+	case bool:
+		return TypeBoolean, strconv.FormatBool(val)
+	default:
+		b, _ := json.Marshal(val)
+		return TypeString, string(b)
+	}
+}
+
+func hasObjects(list []interface{}) bool {
+	for _, item := range list {
+		if _, ok := item.(map[string]interface{}); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mark records that a record has path, filled once any of its values is.
+func mark(filled map[string]bool, path string, nonEmpty bool) {
+	filled[path] = filled[path] || nonEmpty
+}
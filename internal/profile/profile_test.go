@@ -0,0 +1,89 @@
+//go:build unit
+
+package profile_test
+
+import (
+	"testing"
+
+	"github.com/ralucas/centipede/internal/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfiler(t *testing.T) {
+	p := profile.NewProfiler()
+
+	p.Observe(map[string]interface{}{
+		"title":       "Air",
+		"dataQuality": true,
+		"keyword":     []interface{}{"air", "quality"},
+		"publisher":   map[string]interface{}{"name": "EPA"},
+		"distribution": []interface{}{
+			map[string]interface{}{"format": "csv"},
+			map[string]interface{}{"format": "xlsx"},
+		},
+	})
+	p.Observe(map[string]interface{}{
+		"title":       "Water",
+		"dataQuality": nil,
+		"keyword":     []interface{}{},
+		"publisher":   map[string]interface{}{"name": "EPA"},
+	})
+	p.Observe(map[string]interface{}{
+		"title":       "",
+		"dataQuality": 1.5,
+		"keyword":     []interface{}{"water", "air", "quality", "soil"},
+	})
+
+	prof := p.Profile()
+	require.Equal(t, 3, prof.Records)
+
+	fields := make(map[string]profile.Field)
+	paths := make([]string, 0, len(prof.Fields))
+	for _, f := range prof.Fields {
+		fields[f.Path] = f
+		paths = append(paths, f.Path)
+	}
+
+	assert.Equal(t, []string{"dataQuality", "distribution.format", "keyword", "publisher.name", "title"}, paths)
+
+	assert.Equal(t, profile.Field{
+		Path:             "title",
+		Types:            []string{profile.TypeString},
+		Present:          3,
+		Filled:           2,
+		FillRate:         2.0 / 3,
+		Cardinality:      2,
+		CardinalityExact: true,
+		Examples:         []string{"Air", "Water"},
+	}, fields["title"])
+
+	assert.Equal(t, []string{profile.TypeBoolean, profile.TypeNull, profile.TypeNumber}, fields["dataQuality"].Types)
+	assert.Equal(t, 2, fields["dataQuality"].Filled)
+
+	keyword := fields["keyword"]
+	assert.True(t, keyword.Array)
+	assert.False(t, keyword.FlattenOnly)
+	assert.Equal(t, 3, keyword.Present)
+	assert.Equal(t, 2, keyword.Filled)
+	assert.Equal(t, 4, keyword.Cardinality)
+	assert.Equal(t, []string{"air", "quality", "water"}, keyword.Examples)
+
+	format := fields["distribution.format"]
+	assert.True(t, format.Array)
+	assert.True(t, format.FlattenOnly)
+	assert.Equal(t, 1, format.Present)
+	assert.InDelta(t, 1.0/3, format.FillRate, 1e-9)
+	assert.Equal(t, []string{"csv", "xlsx"}, format.Examples)
+
+	assert.False(t, fields["publisher.name"].Array)
+	assert.False(t, fields["publisher.name"].FlattenOnly)
+	assert.Equal(t, 1, fields["publisher.name"].Cardinality)
+}
+
+func TestProfilerEmpty(t *testing.T) {
+	prof := profile.NewProfiler().Profile()
+
+	assert.Zero(t, prof.Records)
+	assert.Empty(t, prof.Fields)
+}
@@ -0,0 +1,99 @@
+package profile
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+const (
+	// sketchPrecision is the number of hash bits picking a register, which
+	// gives 4096 registers and a standard error of about 1.6%.
+	sketchPrecision = 12
+	// exactLimit is the number of distinct values counted exactly before
+	// the sketch switches to estimating.
+	exactLimit = 1024
+)
+
+// Sketch counts distinct values. Up to exactLimit values it holds their
+// hashes, beyond which it becomes a HyperLogLog of fixed size.
+type Sketch struct {
+	exact     map[uint64]struct{}
+	registers []uint8
+}
+
+func NewSketch() *Sketch {
+	return &Sketch{
+		exact: make(map[uint64]struct{}),
+	}
+}
+
+// Add counts v.
+func (s *Sketch) Add(v string) {
+	h := hash(v)
+
+	if s.registers != nil {
+		s.insert(h)
+		return
+	}
+
+	s.exact[h] = struct{}{}
+	if len(s.exact) > exactLimit {
+		s.registers = make([]uint8, 1<<sketchPrecision)
+		for h := range s.exact {
+			s.insert(h)
+		}
+		s.exact = nil
+	}
+}
+
+// Estimate returns the number of distinct values added and whether it is
+// exact.
+func (s *Sketch) Estimate() (int, bool) {
+	if s.registers == nil {
+		return len(s.exact), true
+	}
+
+	m := float64(len(s.registers))
+
+	var (
+		sum   float64
+		zeros int
+	)
+	for _, r := range s.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	// small cardinalities are better counted by the empty registers
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return int(math.Round(estimate)), false
+}
+
+func (s *Sketch) insert(h uint64) {
+	idx := h >> (64 - sketchPrecision)
+	rank := uint8(bits.LeadingZeros64(h<<sketchPrecision|1<<(sketchPrecision-1))) + 1
+	s.registers[idx] = max(s.registers[idx], rank)
+}
+
+// hash is FNV-1a, finalized so every bit of the result depends on every
+// bit of the input, as the registers need.
+func hash(v string) uint64 {
+	f := fnv.New64a()
+	f.Write([]byte(v))
+
+	h := f.Sum64()
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+
+	return h
+}
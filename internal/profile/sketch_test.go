@@ -0,0 +1,46 @@
+//go:build unit
+
+package profile_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/ralucas/centipede/internal/profile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSketch(t *testing.T) {
+	tests := []struct {
+		name      string
+		distinct  int
+		exact     bool
+		tolerance float64
+	}{
+		{name: "empty", distinct: 0, exact: true},
+		{name: "few values", distinct: 300, exact: true},
+		{name: "many values", distinct: 100000, tolerance: 0.05},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := profile.NewSketch()
+
+			// each value is added twice, duplicates must not be counted
+			for n := 0; n < 2; n++ {
+				for i := 0; i < tc.distinct; i++ {
+					s.Add("value-" + strconv.Itoa(i))
+				}
+			}
+
+			got, exact := s.Estimate()
+
+			assert.Equal(t, tc.exact, exact)
+			if tc.exact {
+				assert.Equal(t, tc.distinct, got)
+			} else {
+				assert.InEpsilon(t, tc.distinct, got, tc.tolerance)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package progress
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultTerminalInterval = 250 * time.Millisecond
+	defaultLogInterval      = 10 * time.Second
+)
+
+// Reporter shows the progress of a run, drawing a single updating line on
+// a terminal or logging it periodically otherwise. It counts records, rows
+// and errors as etl.Instrumentation and takes the bytes consumed from a
+// counting reader around the input.
+type Reporter struct {
+	bytes    etl.ByteCounter
+	total    int64
+	out      io.Writer
+	terminal bool
+	interval time.Duration
+	logger   *zap.Logger
+	started  time.Time
+
+	read   atomic.Int64
+	rows   atomic.Int64
+	errors atomic.Int64
+}
+
+type ReporterOption func(*Reporter)
+
+// WithTerminal draws the progress on out as a line redrawn in place rather
+// than logging it.
+func WithTerminal(out io.Writer) ReporterOption {
+	return func(r *Reporter) {
+		r.out = out
+		r.terminal = true
+	}
+}
+
+// WithInterval sets how often progress is shown, defaults to 250ms on a
+// terminal and 10s when logging.
+func WithInterval(d time.Duration) ReporterOption {
+	return func(r *Reporter) {
+		if d > 0 {
+			r.interval = d
+		}
+	}
+}
+
+// NewReporter reports the progress through an input of total bytes, or of
+// unknown size when total is 0.
+func NewReporter(bytes etl.ByteCounter, total int64, log *zap.Logger, opts ...ReporterOption) *Reporter {
+	r := &Reporter{
+		bytes:  bytes,
+		total:  total,
+		logger: log,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.interval == 0 {
+		r.interval = defaultLogInterval
+		if r.terminal {
+			r.interval = defaultTerminalInterval
+		}
+	}
+
+	return r
+}
+
+// IsTerminal reports whether f is a terminal.
+func IsTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// Run shows the progress every interval until ctx is done, then shows it
+// one last time.
+func (r *Reporter) Run(ctx context.Context) error {
+	r.started = time.Now()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.show(true)
+			return nil
+		case <-ticker.C:
+			r.show(false)
+		}
+	}
+}
+
+func (r *Reporter) RecordsRead(n int) {
+	r.read.Add(int64(n))
+}
+
+func (r *Reporter) RecordsWritten(int) {}
+
+func (r *Reporter) RowsWritten(n int) {
+	r.rows.Add(int64(n))
+}
+
+func (r *Reporter) StageDuration(etl.Stage, time.Duration) {}
+
+func (r *Reporter) StageError(etl.Stage) {
+	r.errors.Add(1)
+}
+
+func (r *Reporter) WorkersBusy(int) {}
+
+// snapshot is the progress at a point in the run.
+type snapshot struct {
+	bytes, total       int64
+	read, rows, errors int64
+	elapsed            time.Duration
+}
+
+func (r *Reporter) snapshot() snapshot {
+	return snapshot{
+		bytes:   r.bytes.BytesRead(),
+		total:   r.total,
+		read:    r.read.Load(),
+		rows:    r.rows.Load(),
+		errors:  r.errors.Load(),
+		elapsed: time.Since(r.started),
+	}
+}
+
+// rate is the records read per second.
+func (s snapshot) rate() float64 {
+	if s.elapsed <= 0 {
+		return 0
+	}
+
+	return float64(s.read) / s.elapsed.Seconds()
+}
+
+// eta extrapolates the time left from the bytes consumed so far, it is
+// false while that is unknown.
+func (s snapshot) eta() (time.Duration, bool) {
+	if s.total <= 0 || s.bytes <= 0 {
+		return 0, false
+	}
+
+	left := max(s.total-s.bytes, 0)
+
+	return time.Duration(float64(s.elapsed) * float64(left) / float64(s.bytes)).Round(time.Second), true
+}
+
+func (r *Reporter) show(final bool) {
+	s := r.snapshot()
+
+	if !r.terminal {
+		fields := []zap.Field{
+			zap.Int64("bytes", s.bytes),
+			zap.Int64("records", s.read),
+			zap.Float64("recordsPerSecond", s.rate()),
+			zap.Int64("rows", s.rows),
+			zap.Int64("errors", s.errors),
+		}
+		if s.total > 0 {
+			fields = append(fields, zap.Int64("totalBytes", s.total), zap.Float64("percent", percent(s.bytes, s.total)))
+		}
+		if eta, ok := s.eta(); ok && !final {
+			fields = append(fields, zap.Duration("eta", eta))
+		}
+
+		r.logger.Info("progress", fields...)
+		return
+	}
+
+	// carriage return and erase the line so each draw replaces the last
+	line := "\r\x1b[K" + format(s, final)
+	if final {
+		line += "\n"
+	}
+
+	fmt.Fprint(r.out, line)
+}
+
+// format renders a snapshot as a single line, such as
+// "42.1% 1.2 GiB / 2.9 GiB | 18204 rec/s | 1203442 rows | 3 errors | ETA 1m32s".
+func format(s snapshot, final bool) string {
+	parts := make([]string, 0, 5)
+
+	if s.total > 0 {
+		parts = append(parts, fmt.Sprintf("%5.1f%% %s / %s", percent(s.bytes, s.total), humanBytes(s.bytes), humanBytes(s.total)))
+	} else {
+		parts = append(parts, humanBytes(s.bytes))
+	}
+
+	parts = append(parts,
+		fmt.Sprintf("%.0f rec/s", s.rate()),
+		fmt.Sprintf("%d rows", s.rows),
+		fmt.Sprintf("%d errors", s.errors),
+	)
+
+	switch eta, ok := s.eta(); {
+	case final:
+		parts = append(parts, fmt.Sprintf("done in %s", s.elapsed.Round(time.Millisecond)))
+	case ok:
+		parts = append(parts, fmt.Sprintf("ETA %s", eta))
+	default:
+		parts = append(parts, "ETA unknown")
+	}
+
+	return strings.Join(parts, " | ")
+}
+
+func percent(n, total int64) float64 {
+	return min(float64(n)/float64(total)*100, 100)
+}
+
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
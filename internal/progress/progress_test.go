@@ -0,0 +1,118 @@
+//go:build unit
+
+package progress_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ralucas/centipede/internal/progress"
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type byteCounter struct {
+	n atomic.Int64
+}
+
+func (b *byteCounter) BytesRead() int64 {
+	return b.n.Load()
+}
+
+// syncBuffer is a bytes.Buffer safe to read while the reporter writes.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
+
+func TestReporterTerminal(t *testing.T) {
+	counter := &byteCounter{}
+	counter.n.Store(3 << 20)
+
+	var out syncBuffer
+	r := progress.NewReporter(counter, 6<<20, zap.NewNop(), progress.WithTerminal(&out), progress.WithInterval(5*time.Millisecond))
+
+	r.RecordsRead(10)
+	r.RowsWritten(25)
+	r.StageError(etl.StageTransform)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- r.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "ETA")
+	}, time.Second, 5*time.Millisecond)
+
+	counter.n.Store(6 << 20)
+	cancel()
+	require.NoError(t, <-done)
+
+	lines := strings.Split(out.String(), "\r\x1b[K")
+	assert.Contains(t, lines[1], " 50.0% 3.0 MiB / 6.0 MiB | ")
+	assert.Contains(t, lines[1], " | 25 rows | 1 errors | ETA ")
+
+	final := lines[len(lines)-1]
+	assert.Contains(t, final, "100.0% 6.0 MiB / 6.0 MiB")
+	assert.Contains(t, final, "done in")
+	assert.True(t, strings.HasSuffix(final, "\n"))
+}
+
+func TestReporterLog(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	counter := &byteCounter{}
+	counter.n.Store(512)
+
+	r := progress.NewReporter(counter, 0, zap.New(core))
+	r.RecordsRead(4)
+	r.RowsWritten(8)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, r.Run(ctx))
+
+	entries := logs.FilterMessage("progress").All()
+	require.Len(t, entries, 1)
+
+	fields := entries[0].ContextMap()
+	assert.Equal(t, int64(512), fields["bytes"])
+	assert.Equal(t, int64(4), fields["records"])
+	assert.Equal(t, int64(8), fields["rows"])
+	assert.Equal(t, int64(0), fields["errors"])
+	// the size of the input is unknown
+	assert.NotContains(t, fields, "percent")
+	assert.NotContains(t, fields, "eta")
+}
+
+func TestIsTerminal(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	assert.False(t, progress.IsTerminal(f))
+}
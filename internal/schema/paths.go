@@ -0,0 +1,232 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	ErrUnknownPath = errors.New("unknown field path")
+	ErrInvalidPath = errors.New("invalid field path")
+)
+
+// pathSeparator joins the keys of a nested field path.
+const pathSeparator = "."
+
+// UnknownPathError is a field path that names no field of a dataset. It
+// matches ErrUnknownPath.
+type UnknownPathError struct {
+	Path string
+	// Suggestion is the closest valid path, empty when none is close.
+	Suggestion string
+	// Reason tells where the path leaves the schema.
+	Reason string
+}
+
+func (e *UnknownPathError) Error() string {
+	msg := fmt.Sprintf("%s %q: %s", ErrUnknownPath, e.Path, e.Reason)
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf(", did you mean %q?", e.Suggestion)
+	}
+
+	return msg
+}
+
+func (e *UnknownPathError) Is(target error) bool {
+	return target == ErrUnknownPath
+}
+
+// InvalidPathError is a field path of the schema that doesn't lead to a
+// single value per record: it crosses an array of objects or ends at an
+// object. It matches ErrInvalidPath.
+type InvalidPathError struct {
+	Path string
+	// Array is the array of objects the path crosses or ends at, empty
+	// when it ends at an object.
+	Array  string
+	Reason string
+}
+
+func (e *InvalidPathError) Error() string {
+	return fmt.Sprintf("%s %q: %s", ErrInvalidPath, e.Path, e.Reason)
+}
+
+func (e *InvalidPathError) Is(target error) bool {
+	return target == ErrInvalidPath
+}
+
+// distributionJson is a distribution of a dataset, which DatasetJson leaves
+// untyped.
+type distributionJson struct {
+	Type            string `json:"@type"`
+	AccessURL       string `json:"accessURL"`
+	ConformsTo      string `json:"conformsTo"`
+	DescribedBy     string `json:"describedBy"`
+	DescribedByType string `json:"describedByType"`
+	Description     string `json:"description"`
+	DownloadURL     string `json:"downloadURL"`
+	Format          string `json:"format"`
+	MediaType       string `json:"mediaType"`
+	Title           string `json:"title"`
+}
+
+// untypedFields are the DCAT-US types of the fields DatasetJson leaves
+// untyped and that hold more than a single value. The others are strings,
+// booleans or null.
+var untypedFields = map[string]reflect.Type{
+	"distribution": reflect.TypeFor[[]distributionJson](),
+	"language":     reflect.TypeFor[[]string](),
+	"references":   reflect.TypeFor[[]string](),
+	"theme":        reflect.TypeFor[[]string](),
+}
+
+// CheckPath checks a dotted field path, e.g. contactPoint.fn, against the
+// fields of DatasetJson and the objects it holds, as the map extractor
+// reads it. An unknown path gives an *UnknownPathError suggesting the
+// closest valid path. A path crossing an array of objects, such as
+// distribution.format, or ending at an object gives an *InvalidPathError.
+func CheckPath(path string) error {
+	keys := strings.Split(path, pathSeparator)
+
+	corrected := make([]string, 0, len(keys))
+	reason := ""
+	array := ""
+	t := reflect.TypeFor[DatasetJson]()
+
+	for i, key := range keys {
+		if isObjects(t) {
+			if array == "" {
+				array = strings.Join(corrected, pathSeparator)
+			}
+			t = t.Elem()
+		}
+
+		if t.Kind() != reflect.Struct {
+			if reason == "" {
+				reason = fmt.Sprintf("%s has no fields", strings.Join(keys[:i], pathSeparator))
+			}
+			return &UnknownPathError{Path: path, Reason: reason}
+		}
+
+		names := fieldNames(t)
+
+		name := key
+		if _, ok := names[key]; !ok {
+			if reason == "" {
+				reason = fmt.Sprintf("no field %q", key)
+				if i > 0 {
+					reason += " in " + strings.Join(keys[:i], pathSeparator)
+				}
+			}
+
+			if name = closest(key, names); name == "" {
+				return &UnknownPathError{Path: path, Reason: reason}
+			}
+		}
+
+		corrected = append(corrected, name)
+		t = names[name]
+	}
+
+	if reason != "" {
+		return &UnknownPathError{
+			Path:       path,
+			Suggestion: strings.Join(corrected, pathSeparator),
+			Reason:     reason,
+		}
+	}
+
+	switch {
+	case array != "":
+		return &InvalidPathError{Path: path, Array: array, Reason: fmt.Sprintf("%s is an array of objects", array)}
+	case isObjects(t):
+		return &InvalidPathError{Path: path, Array: path, Reason: fmt.Sprintf("%s is an array of objects", path)}
+	case t.Kind() == reflect.Struct:
+		return &InvalidPathError{Path: path, Reason: fmt.Sprintf("%s is an object, select one of its fields", path)}
+	}
+
+	return nil
+}
+
+// isObjects tells whether t is an array of objects.
+func isObjects(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct
+}
+
+// fieldNames maps the json names of a struct's fields to their types,
+// untyped fields taking their DCAT-US type.
+func fieldNames(t reflect.Type) map[string]reflect.Type {
+	names := make(map[string]reflect.Type, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Interface {
+			var ok bool
+			if ft, ok = untypedFields[name]; !ok {
+				ft = reflect.TypeFor[string]()
+			}
+		}
+
+		names[name] = ft
+	}
+
+	return names
+}
+
+// closest returns the name nearest to key, ignoring case, or empty when
+// none is within a third of key's length.
+func closest(key string, names map[string]reflect.Type) string {
+	best, bestDistance := "", max(1, len(key)/3)+1
+
+	for name := range names {
+		d := distance(strings.ToLower(key), strings.ToLower(name))
+		if d < bestDistance || (d == bestDistance && name < best) {
+			best, bestDistance = name, d
+		}
+	}
+
+	return best
+}
+
+// distance is the number of insertions, deletions, substitutions and
+// transpositions of adjacent characters turning a into b.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
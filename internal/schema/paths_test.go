@@ -0,0 +1,86 @@
+//go:build unit
+
+package schema_test
+
+import (
+	"testing"
+
+	"github.com/ralucas/centipede/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckPath(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		valid      bool
+		suggestion string
+	}{
+		{name: "dataset field", path: "title", valid: true},
+		{name: "json-ld type", path: "@type", valid: true},
+		{name: "contact point", path: "contactPoint.fn", valid: true},
+		{name: "nested organization", path: "publisher.subOrganizationOf.subOrganizationOf.name", valid: true},
+		{name: "array of strings", path: "keyword", valid: true},
+		{name: "untyped scalar", path: "dataQuality", valid: true},
+		{name: "untyped array of strings", path: "theme", valid: true},
+		{name: "wrong case", path: "contactpoint.fn", suggestion: "contactPoint.fn"},
+		{name: "typo", path: "modifed", suggestion: "modified"},
+		{name: "transposition", path: "titel", suggestion: "title"},
+		{name: "typos at every level", path: "publsher.subOrganisationOf.nme", suggestion: "publisher.subOrganizationOf.name"},
+		{name: "typo below an array of objects", path: "distribution.fromat", suggestion: "distribution.format"},
+		{name: "unknown nested field", path: "contactPoint.email"},
+		{name: "below a scalar", path: "keyword.name"},
+		{name: "below an untyped scalar", path: "dataQuality.value"},
+		{name: "unrelated", path: "foo"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := schema.CheckPath(tc.path)
+			if tc.valid {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, schema.ErrUnknownPath)
+
+			var unknown *schema.UnknownPathError
+			require.ErrorAs(t, err, &unknown)
+			assert.Equal(t, tc.path, unknown.Path)
+			assert.Equal(t, tc.suggestion, unknown.Suggestion)
+			assert.NotEmpty(t, unknown.Reason)
+
+			if tc.suggestion != "" {
+				assert.Contains(t, err.Error(), `did you mean "`+tc.suggestion+`"?`)
+			}
+		})
+	}
+}
+
+func TestCheckPathInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		array string
+	}{
+		{name: "crosses an array of objects", path: "distribution.format", array: "distribution"},
+		{name: "ends at an array of objects", path: "distribution", array: "distribution"},
+		{name: "ends at an object", path: "publisher"},
+		{name: "ends at a nested object", path: "publisher.subOrganizationOf"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := schema.CheckPath(tc.path)
+			require.ErrorIs(t, err, schema.ErrInvalidPath)
+			assert.NotErrorIs(t, err, schema.ErrUnknownPath)
+
+			var invalid *schema.InvalidPathError
+			require.ErrorAs(t, err, &invalid)
+			assert.Equal(t, tc.path, invalid.Path)
+			assert.Equal(t, tc.array, invalid.Array)
+			assert.NotEmpty(t, invalid.Reason)
+		})
+	}
+}
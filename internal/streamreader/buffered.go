@@ -0,0 +1,64 @@
+package streamreader
+
+import (
+	"errors"
+
+	"github.com/ralucas/centipede/pkg/etl"
+)
+
+// BufferedIterator wraps a StreamIterator so the first records can be
+// inspected ahead of time and are then replayed in order.
+type BufferedIterator struct {
+	si  etl.StreamIterator
+	buf []buffered
+}
+
+// buffered is a record read ahead of time, or the error reading it.
+type buffered struct {
+	obj map[string]interface{}
+	err error
+}
+
+func NewBufferedIterator(si etl.StreamIterator) *BufferedIterator {
+	return &BufferedIterator{
+		si: si,
+	}
+}
+
+// Fill reads up to n records into the buffer and returns those read
+// successfully. A record failing to be read counts towards n and its error
+// is held back in its place, to be returned from Next, so whether it fails
+// the run is up to the reader. Reading stops early at the end of the
+// stream.
+func (b *BufferedIterator) Fill(n int) []map[string]interface{} {
+	for len(b.buf) < n && b.si.HasNext() {
+		obj, err := b.si.Next()
+		if errors.Is(err, etl.Done) {
+			break
+		}
+		b.buf = append(b.buf, buffered{obj: obj, err: err})
+	}
+
+	records := make([]map[string]interface{}, 0, len(b.buf))
+	for _, e := range b.buf {
+		if e.err == nil {
+			records = append(records, e.obj)
+		}
+	}
+
+	return records
+}
+
+func (b *BufferedIterator) Next() (map[string]interface{}, error) {
+	if len(b.buf) > 0 {
+		e := b.buf[0]
+		b.buf = b.buf[1:]
+		return e.obj, e.err
+	}
+
+	return b.si.Next()
+}
+
+func (b *BufferedIterator) HasNext() bool {
+	return len(b.buf) > 0 || b.si.HasNext()
+}
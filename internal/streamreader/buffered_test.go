@@ -0,0 +1,107 @@
+//go:build unit
+
+package streamreader_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ralucas/centipede/internal/streamreader"
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/ralucas/centipede/test/fixtures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestBufferedIterator(t *testing.T) {
+	log, err := zap.NewDevelopment()
+	require.NoError(t, err)
+
+	f := fixtures.NewTestFixture()
+
+	tests := []struct {
+		name        string
+		datasetFile string
+		fill        int
+		buffered    int
+		expect      int
+		validate    bool
+		err         bool
+	}{
+		{name: "replays partial buffer", datasetFile: "dataset_array.json", fill: 2, buffered: 2, expect: 3},
+		{name: "stops at end of stream", datasetFile: "dataset_array.json", fill: 10, buffered: 3, expect: 3},
+		{name: "holds back error", datasetFile: "invalid_dataset_array.json", fill: 10, buffered: 0, expect: 0, validate: true, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fp, err := f.DatasetFilePath(test.datasetFile)
+			require.NoError(t, err)
+
+			file, err := os.Open(fp)
+			require.NoError(t, err)
+
+			defer file.Close()
+
+			var opts []streamreader.JSONStreamIteratorOption
+			if test.validate {
+				opts = append(opts, streamreader.WithDatasetValidation())
+			}
+
+			bi := streamreader.NewBufferedIterator(streamreader.NewJSONStreamIterator(file, log, opts...))
+
+			assert.Equal(t, test.buffered, len(bi.Fill(test.fill)))
+
+			coll := make([]map[string]interface{}, 0)
+			var iterErr error
+			for bi.HasNext() {
+				obj, err := bi.Next()
+				if errors.Is(err, etl.Done) {
+					break
+				}
+				if err != nil {
+					iterErr = err
+					break
+				}
+
+				coll = append(coll, obj)
+			}
+
+			assert.Equal(t, test.expect, len(coll))
+			if test.err {
+				assert.ErrorIs(t, iterErr, streamreader.ErrInvalidDatasetJSON)
+			} else {
+				assert.NoError(t, iterErr)
+			}
+		})
+	}
+}
+
+func TestBufferedIteratorErrors(t *testing.T) {
+	si := streamreader.NewJSONStreamIterator(strings.NewReader(`[{"id":1},2,{"id":3},{"id":4}]`), zap.NewNop())
+	bi := streamreader.NewBufferedIterator(si)
+
+	// the failed record counts towards the sample, which carries on past it
+	assert.Equal(t, []map[string]interface{}{{"id": 1.0}, {"id": 3.0}}, bi.Fill(3))
+
+	var ids []interface{}
+	var errs []error
+	for bi.HasNext() {
+		obj, err := bi.Next()
+		if errors.Is(err, etl.Done) {
+			break
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ids = append(ids, obj["id"])
+	}
+
+	assert.Equal(t, []interface{}{1.0, 3.0, 4.0}, ids)
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], streamreader.ErrInvalidRecord)
+}
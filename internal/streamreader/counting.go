@@ -0,0 +1,50 @@
+package streamreader
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+)
+
+var ErrNotSeekable = errors.New("reader is not seekable")
+
+// CountingReader tracks how many bytes have been read through it. Seeking
+// moves the count to the new offset, so after a pre-scan and a rewind it
+// reports the position in the input rather than the total bytes read.
+type CountingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func NewCountingReader(r io.Reader) *CountingReader {
+	return &CountingReader{r: r}
+}
+
+func (c *CountingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+
+	return n, err
+}
+
+// Seek seeks the underlying reader when it supports it.
+func (c *CountingReader) Seek(offset int64, whence int) (int64, error) {
+	s, ok := c.r.(io.Seeker)
+	if !ok {
+		return 0, ErrNotSeekable
+	}
+
+	pos, err := s.Seek(offset, whence)
+	if err != nil {
+		return pos, err
+	}
+
+	c.n.Store(pos)
+
+	return pos, nil
+}
+
+// BytesRead returns the number of bytes read, safe to call while reading.
+func (c *CountingReader) BytesRead() int64 {
+	return c.n.Load()
+}
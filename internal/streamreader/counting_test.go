@@ -0,0 +1,34 @@
+//go:build unit
+
+package streamreader_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ralucas/centipede/internal/streamreader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountingReader(t *testing.T) {
+	cr := streamreader.NewCountingReader(strings.NewReader("[{\"a\":1},{\"a\":2}]"))
+
+	buf := make([]byte, 5)
+	_, err := io.ReadFull(cr, buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), cr.BytesRead())
+
+	_, err = io.ReadAll(cr)
+	require.NoError(t, err)
+	assert.Equal(t, int64(17), cr.BytesRead())
+
+	pos, err := cr.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), pos)
+	assert.Equal(t, int64(0), cr.BytesRead())
+
+	_, err = streamreader.NewCountingReader(io.MultiReader()).Seek(0, io.SeekStart)
+	assert.ErrorIs(t, err, streamreader.ErrNotSeekable)
+}
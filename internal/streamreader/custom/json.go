@@ -3,6 +3,7 @@ package custom
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 
 	"github.com/ralucas/centipede/internal/schema"
@@ -164,7 +165,8 @@ func (r *CustomJSONStreamReadIterator) Next() (map[string]interface{}, error) {
 			continue
 		}
 		if err != nil {
-			return nil, err
+			r.hasNext = false
+			return nil, fmt.Errorf("%w: %w", etl.ErrStreamBroken, err)
 		}
 		if n > 0 && len(b) > n && b[n] != '\x00' {
 			r.hasNext = true
@@ -186,14 +188,6 @@ func (r *CustomJSONStreamReadIterator) IsArray() bool {
 }
 
 func (r *CustomJSONStreamReadIterator) toMap(data []byte) (map[string]interface{}, error) {
-	if r.validate {
-		d := &schema.DatasetJson{}
-		if err := d.UnmarshalJSON(data); err != nil {
-			r.logger.Error("failed validation", zap.Error(err))
-			return nil, errors.Join(streamreader.ErrInvalidDatasetJSON, err)
-		}
-	}
-
 	var m map[string]interface{}
 
 	err := json.Unmarshal(data, &m)
@@ -202,6 +196,14 @@ func (r *CustomJSONStreamReadIterator) toMap(data []byte) (map[string]interface{
 		return nil, err
 	}
 
+	if r.validate {
+		d := &schema.DatasetJson{}
+		if err := d.UnmarshalJSON(data); err != nil {
+			r.logger.Error("failed validation", zap.Error(err))
+			return nil, streamreader.NewInvalidDatasetError(m, err)
+		}
+	}
+
 	return m, nil
 }
 
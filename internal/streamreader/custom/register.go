@@ -0,0 +1,29 @@
+package custom
+
+import (
+	"io"
+
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+)
+
+// Config configures the custom source.
+type Config struct {
+	// Validate checks records against the dataset schema.
+	Validate bool `json:"validate"`
+	// ChunkSize is the number of bytes read at a time, 0 is the default.
+	ChunkSize int `json:"chunk_size"`
+}
+
+func init() {
+	etl.RegisterSource("custom", func(r io.Reader, conf Config, log *zap.Logger) (etl.StreamIterator, error) {
+		var opts []JSONStreamReadIteratorOption
+		if conf.Validate {
+			opts = append(opts, WithDatasetValidation())
+		}
+		if conf.ChunkSize > 0 {
+			opts = append(opts, WithChunkSize(conf.ChunkSize))
+		}
+		return NewCustomJSONStreamReadIterator(r, log, opts...), nil
+	})
+}
@@ -3,7 +3,9 @@ package streamreader
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"regexp"
 	"sync/atomic"
 
 	"github.com/ralucas/centipede/internal/schema"
@@ -12,31 +14,92 @@ import (
 	"go.uber.org/zap"
 )
 
-var ErrInvalidDatasetJSON = errors.New("json does not conform to dataset schema")
+var (
+	ErrInvalidDatasetJSON = errors.New("json does not conform to dataset schema")
+	ErrInvalidRecord      = errors.New("json does not decode into the record type")
+)
+
+// fieldPattern matches the field named by generated schema errors such as
+// "field accessLevel in DatasetJson: required".
+var fieldPattern = regexp.MustCompile(`^field (\S+)`)
+
+// InvalidDatasetError is a record that failed schema validation. It matches
+// ErrInvalidDatasetJSON and keeps the decoded record so it can be rejected.
+type InvalidDatasetError struct {
+	// Field is the violated field, when the schema error names one.
+	Field  string
+	Err    error
+	record map[string]interface{}
+}
+
+func NewInvalidDatasetError(record map[string]interface{}, err error) *InvalidDatasetError {
+	e := &InvalidDatasetError{Err: err, record: record}
+	if m := fieldPattern.FindStringSubmatch(err.Error()); m != nil {
+		e.Field = m[1]
+	}
+
+	return e
+}
+
+func (e *InvalidDatasetError) Error() string {
+	return errors.Join(ErrInvalidDatasetJSON, e.Err).Error()
+}
 
-type JSONStreamIterator struct {
+func (e *InvalidDatasetError) Is(target error) bool {
+	return target == ErrInvalidDatasetJSON
+}
+
+func (e *InvalidDatasetError) Unwrap() error {
+	return e.Err
+}
+
+func (e *InvalidDatasetError) RawRecord() map[string]interface{} {
+	return e.record
+}
+
+// JSONIterator decodes the objects of a json array into T, such as
+// schema.DatasetJson, whose own unmarshalling checks the schema.
+type JSONIterator[T any] struct {
 	reader      io.Reader
 	logger      *zap.Logger
 	hasNext     *atomic.Bool
 	chunkSize   int
 	dec         *json.Decoder
-	validate    bool
+	validate    func(T) error
 	initialized *atomic.Bool
 }
 
-type JSONStreamIteratorOption func(*JSONStreamIterator)
+// JSONStreamIterator decodes the objects of a json array into maps.
+type JSONStreamIterator = JSONIterator[map[string]interface{}]
+
+type JSONIteratorOption[T any] func(*JSONIterator[T])
+
+type JSONStreamIteratorOption = JSONIteratorOption[map[string]interface{}]
 
 // WithDatasetValidation uses the published schema from
 // https://project-open-data.cio.gov/v1.1/schema/dataset.json
 // to validate against.
 func WithDatasetValidation() JSONStreamIteratorOption {
 	return func(r *JSONStreamIterator) {
-		r.validate = true
+		r.validate = func(m map[string]interface{}) error {
+			if err := validateDataset(m); err != nil {
+				return NewInvalidDatasetError(m, err)
+			}
+			return nil
+		}
 	}
 }
 
 func NewJSONStreamIterator(reader io.Reader, log *zap.Logger, opts ...JSONStreamIteratorOption) *JSONStreamIterator {
-	r := &JSONStreamIterator{
+	return NewJSONIterator(reader, log, opts...)
+}
+
+// NewJSONIterator reads the objects of a json array into T. An object that
+// doesn't decode into T fails with ErrInvalidRecord, and iteration carries
+// on with the next object, while malformed json fails with
+// etl.ErrStreamBroken and ends it.
+func NewJSONIterator[T any](reader io.Reader, log *zap.Logger, opts ...JSONIteratorOption[T]) *JSONIterator[T] {
+	r := &JSONIterator[T]{
 		reader:      reader,
 		logger:      log,
 		hasNext:     &atomic.Bool{},
@@ -53,12 +116,12 @@ func NewJSONStreamIterator(reader io.Reader, log *zap.Logger, opts ...JSONStream
 	return r
 }
 
-func (r *JSONStreamIterator) initialize() error {
+func (r *JSONIterator[T]) initialize() error {
 	if !r.initialized.Load() {
 		t, err := r.dec.Token()
 		if err != nil {
 			r.logger.Error("failed to decode opening array bracket", zap.Any("opener", t), zap.Error(err))
-			return err
+			return fmt.Errorf("%w: %w", etl.ErrStreamBroken, err)
 		}
 	// find root key, handle if it doesn't exist
 	}
@@ -69,7 +132,9 @@ func (r *JSONStreamIterator) initialize() error {
 
 // Iterator Pattern to get next json object. On error it will mark
 // HasNext as false.
-func (r *JSONStreamIterator) Next() (map[string]interface{}, error) {
+func (r *JSONIterator[T]) Next() (T, error) {
+	var v T
+
 	// set hasNext to false on each call, which will cause iteration
 	// to end on first error
 	r.hasNext.Store(false)
@@ -78,45 +143,88 @@ func (r *JSONStreamIterator) Next() (map[string]interface{}, error) {
 	if !r.initialized.Load() {
 		err := r.initialize()
 		if err != nil {
-			return nil, err
+			return v, err
 		}
 	}
 
 	if r.dec.More() {
-		var m map[string]interface{}
-		err := r.dec.Decode(&m)
-		if err != nil {
-			r.logger.Error("failed to decode", zap.Error(err))
-			return nil, err
+		if err := r.decode(&v); err != nil {
+			return v, err
 		}
-		if r.validate {
-			if ok, err := r.validateDataset(m); !ok {
-				return nil, errors.Join(ErrInvalidDatasetJSON, err)
+
+		if r.validate != nil {
+			if err := r.validate(v); err != nil {
+				return v, err
 			}
 		}
 
-		r.hasNext.Store(true)
-		return m, nil
+		return v, nil
+	}
+
+	return v, etl.Done
+}
+
+// decode reads the next object into v. Any object decodes into a map in a
+// single pass, other types go through a raw message first so an object that
+// doesn't fit T is skipped rather than ending the stream. Malformed json
+// ends the stream and fails with etl.ErrStreamBroken.
+func (r *JSONIterator[T]) decode(v *T) error {
+	if m, ok := any(v).(*map[string]interface{}); ok {
+		err := r.dec.Decode(m)
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case err == nil:
+			r.hasNext.Store(true)
+			return nil
+		case errors.As(err, &typeErr):
+			// the value was fully read, it just isn't an object
+			r.hasNext.Store(true)
+			return fmt.Errorf("%w: %w", ErrInvalidRecord, err)
+		default:
+			r.logger.Error("failed to decode", zap.Error(err))
+			return fmt.Errorf("%w: %w", etl.ErrStreamBroken, err)
+		}
+	}
+
+	var raw json.RawMessage
+	if err := r.dec.Decode(&raw); err != nil {
+		r.logger.Error("failed to decode", zap.Error(err))
+		return fmt.Errorf("%w: %w", etl.ErrStreamBroken, err)
+	}
+
+	// the object was fully read, so one that doesn't fit T can be skipped
+	r.hasNext.Store(true)
+
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidRecord, err)
 	}
 
-	return nil, etl.Done
+	return nil
 }
 
-func (r *JSONStreamIterator) HasNext() bool {
-	return r.hasNext.Load()
+// HasNext reports whether the array has another object, peeking at the
+// input once the opening bracket is read, so the stream doesn't end with a
+// Next returning etl.Done. It is false after an error that ends the stream.
+func (r *JSONIterator[T]) HasNext() bool {
+	if !r.hasNext.Load() {
+		return false
+	}
+
+	// a missing opening bracket is reported by Next
+	if !r.initialized.Load() {
+		return true
+	}
+
+	return r.dec.More()
 }
 
-func (r *JSONStreamIterator) validateDataset(m map[string]interface{}) (bool, error) {
+func validateDataset(m map[string]interface{}) error {
 	d := &schema.DatasetJson{}
 
 	b, err := json.Marshal(m)
 	if err != nil {
-		return false, err
-	}
-
-	if err = d.UnmarshalJSON(b); err != nil {
-		return false, err
+		return err
 	}
 
-	return true, nil
+	return d.UnmarshalJSON(b)
 }
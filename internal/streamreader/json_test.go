@@ -5,8 +5,10 @@ package streamreader_test
 import (
 	"errors"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/ralucas/centipede/internal/schema"
 	"github.com/ralucas/centipede/internal/streamreader"
 	"github.com/ralucas/centipede/pkg/etl"
 	"github.com/ralucas/centipede/test/fixtures"
@@ -74,3 +76,117 @@ func TestStreamIterator(t *testing.T) {
 		})
 	}
 }
+
+func TestJSONIterator(t *testing.T) {
+	f := fixtures.NewTestFixture()
+
+	tests := []struct {
+		name        string
+		datasetFile string
+		titles      []string
+		invalid     int
+	}{
+		{name: "decodes datasets", datasetFile: "dataset_array.json", titles: []string{
+			"Networx Business Volume FY2013, 3rd Qtr",
+			"2015 GSA Common Baseline Implementation Plan and CIO Assignment Plan",
+			"Award Exploration Tool",
+		}},
+		{name: "skips invalid datasets", datasetFile: "invalid_dataset_array.json", invalid: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fp, err := f.DatasetFilePath(test.datasetFile)
+			require.NoError(t, err)
+
+			file, err := os.Open(fp)
+			require.NoError(t, err)
+
+			defer file.Close()
+
+			sr := streamreader.NewJSONIterator[schema.DatasetJson](file, zap.NewNop())
+
+			var titles []string
+			var invalid int
+			for sr.HasNext() {
+				d, err := sr.Next()
+				if errors.Is(err, etl.Done) {
+					break
+				}
+				if errors.Is(err, streamreader.ErrInvalidRecord) {
+					invalid++
+					continue
+				}
+				require.NoError(t, err)
+
+				titles = append(titles, d.Title)
+			}
+
+			assert.Equal(t, test.titles, titles)
+			assert.Equal(t, test.invalid, invalid)
+		})
+	}
+}
+
+func TestStreamIteratorErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		ids   []interface{}
+		err   error
+	}{
+		{name: "carries on after a value that isn't an object", input: `[{"id":1},2,{"id":3}]`, ids: []interface{}{1.0, 3.0}, err: streamreader.ErrInvalidRecord},
+		{name: "ends on malformed json", input: `[{"id":1},{bad},{"id":3}]`, ids: []interface{}{1.0}, err: etl.ErrStreamBroken},
+		{name: "ends without an array", input: `}`, err: etl.ErrStreamBroken},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sr := streamreader.NewJSONStreamIterator(strings.NewReader(test.input), zap.NewNop())
+
+			var ids []interface{}
+			var errs []error
+			for sr.HasNext() {
+				obj, err := sr.Next()
+				if errors.Is(err, etl.Done) {
+					break
+				}
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				ids = append(ids, obj["id"])
+			}
+
+			assert.Equal(t, test.ids, ids)
+			require.Len(t, errs, 1)
+			assert.ErrorIs(t, errs[0], test.err)
+		})
+	}
+}
+
+func TestStreamIteratorHasNext(t *testing.T) {
+	f := fixtures.NewTestFixture()
+
+	fp, err := f.DatasetFilePath("dataset_array.json")
+	require.NoError(t, err)
+
+	file, err := os.Open(fp)
+	require.NoError(t, err)
+
+	defer file.Close()
+
+	sr := streamreader.NewJSONStreamIterator(file, zap.NewNop())
+
+	var calls int
+	for sr.HasNext() {
+		_, err := sr.Next()
+		require.NoError(t, err)
+		calls++
+	}
+
+	assert.Equal(t, 3, calls)
+
+	_, err = sr.Next()
+	assert.ErrorIs(t, err, etl.Done)
+}
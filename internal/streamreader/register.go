@@ -0,0 +1,32 @@
+package streamreader
+
+import (
+	"io"
+
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+)
+
+// JSONConfig configures the json and rejects sources.
+type JSONConfig struct {
+	// Validate checks records against the dataset schema.
+	Validate bool `json:"validate"`
+}
+
+func init() {
+	etl.RegisterSource("json", func(r io.Reader, conf JSONConfig, log *zap.Logger) (etl.StreamIterator, error) {
+		var opts []JSONStreamIteratorOption
+		if conf.Validate {
+			opts = append(opts, WithDatasetValidation())
+		}
+		return NewJSONStreamIterator(r, log, opts...), nil
+	})
+
+	etl.RegisterSource("rejects", func(r io.Reader, conf JSONConfig, log *zap.Logger) (etl.StreamIterator, error) {
+		var opts []RejectIteratorOption
+		if conf.Validate {
+			opts = append(opts, WithRejectValidation())
+		}
+		return NewRejectIterator(r, log, opts...), nil
+	})
+}
@@ -0,0 +1,98 @@
+package streamreader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+)
+
+// Reject is one line of a reject file: a record that failed, why, and where
+// it was in the input.
+type Reject struct {
+	Index int64     `json:"index"`
+	Stage etl.Stage `json:"stage"`
+	// Branch is the branch the record failed in, if not the main pipeline.
+	Branch string `json:"branch,omitempty"`
+	Error  string `json:"error"`
+	// Field is the violated field of a schema validation failure.
+	Field string `json:"field,omitempty"`
+	// Others are the failures of the record in other branches, a record is
+	// written once however many branches it fails in.
+	Others []RejectCause          `json:"others,omitempty"`
+	Record map[string]interface{} `json:"record"`
+}
+
+// RejectCause is a failure of a rejected record in another branch.
+type RejectCause struct {
+	Stage  etl.Stage `json:"stage"`
+	Branch string    `json:"branch,omitempty"`
+	Error  string    `json:"error"`
+}
+
+// RejectIterator replays the records of a reject file. Entries without a
+// record, from input that could not be decoded at all, are skipped.
+type RejectIterator struct {
+	dec      *json.Decoder
+	logger   *zap.Logger
+	hasNext  bool
+	validate bool
+}
+
+type RejectIteratorOption func(*RejectIterator)
+
+// WithRejectValidation validates replayed records against the dataset
+// schema, like WithDatasetValidation.
+func WithRejectValidation() RejectIteratorOption {
+	return func(r *RejectIterator) {
+		r.validate = true
+	}
+}
+
+func NewRejectIterator(reader io.Reader, log *zap.Logger, opts ...RejectIteratorOption) *RejectIterator {
+	r := &RejectIterator{
+		dec:     json.NewDecoder(reader),
+		logger:  log,
+		hasNext: true,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+func (r *RejectIterator) Next() (map[string]interface{}, error) {
+	for {
+		var reject Reject
+		if err := r.dec.Decode(&reject); err != nil {
+			r.hasNext = false
+			if errors.Is(err, io.EOF) {
+				return nil, etl.Done
+			}
+			r.logger.Error("failed to decode reject", zap.Error(err))
+			return nil, fmt.Errorf("%w: %w", etl.ErrStreamBroken, err)
+		}
+
+		if reject.Record == nil {
+			r.logger.Warn("skipping reject without a record", zap.Int64("index", reject.Index), zap.String("error", reject.Error))
+			continue
+		}
+
+		if r.validate {
+			if err := validateDataset(reject.Record); err != nil {
+				return nil, NewInvalidDatasetError(reject.Record, err)
+			}
+		}
+
+		return reject.Record, nil
+	}
+}
+
+func (r *RejectIterator) HasNext() bool {
+	return r.hasNext
+}
@@ -0,0 +1,65 @@
+//go:build unit
+
+package streamreader_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ralucas/centipede/internal/streamreader"
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRejectIterator(t *testing.T) {
+	log, err := zap.NewDevelopment()
+	require.NoError(t, err)
+
+	input := strings.Join([]string{
+		`{"index":0,"stage":"read","error":"invalid character","record":null}`,
+		`{"index":1,"stage":"read","error":"required","field":"accessLevel","record":{"title":"a"}}`,
+		`{"index":4,"stage":"transform","error":"test","record":{"title":"b"}}`,
+	}, "\n")
+
+	t.Run("replays records", func(t *testing.T) {
+		ri := streamreader.NewRejectIterator(strings.NewReader(input), log)
+
+		var titles []interface{}
+		for ri.HasNext() {
+			obj, err := ri.Next()
+			if errors.Is(err, etl.Done) {
+				break
+			}
+			require.NoError(t, err)
+			titles = append(titles, obj["title"])
+		}
+
+		assert.Equal(t, []interface{}{"a", "b"}, titles)
+	})
+
+	t.Run("validates records", func(t *testing.T) {
+		ri := streamreader.NewRejectIterator(strings.NewReader(input), log, streamreader.WithRejectValidation())
+
+		_, err := ri.Next()
+		assert.ErrorIs(t, err, streamreader.ErrInvalidDatasetJSON)
+
+		var invalid *streamreader.InvalidDatasetError
+		require.ErrorAs(t, err, &invalid)
+		assert.Equal(t, map[string]interface{}{"title": "a"}, invalid.RawRecord())
+		assert.NotEmpty(t, invalid.Field)
+
+		// an invalid record does not end the replay
+		assert.True(t, ri.HasNext())
+	})
+
+	t.Run("fails on malformed entries", func(t *testing.T) {
+		ri := streamreader.NewRejectIterator(strings.NewReader(`{"index":`), log)
+
+		_, err := ri.Next()
+		assert.ErrorIs(t, err, etl.ErrStreamBroken)
+		assert.False(t, ri.HasNext())
+	})
+}
@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+var ErrUnknownExporter = errors.New("unknown trace exporter")
+
+const serviceName = "centipede"
+
+// Exporter is where spans are sent.
+type Exporter string
+
+const (
+	// ExporterOTLP sends spans over OTLP/HTTP, configured by the standard
+	// OTEL_EXPORTER_OTLP_* environment variables.
+	ExporterOTLP Exporter = "otlp"
+	// ExporterStdout pretty prints spans as JSON to stdout.
+	ExporterStdout Exporter = "stdout"
+	// ExporterFile writes spans as JSON to a file.
+	ExporterFile Exporter = "file"
+)
+
+func ParseExporter(s string) (Exporter, error) {
+	switch e := Exporter(strings.ToLower(s)); e {
+	case ExporterOTLP, ExporterStdout, ExporterFile:
+		return e, nil
+	default:
+		return "", fmt.Errorf("%w: %q, expected otlp, stdout or file", ErrUnknownExporter, s)
+	}
+}
+
+// NewTracerProvider returns a provider batching spans to the exporter. w is
+// only used by the file exporter. The provider must be shut down to flush
+// the spans still buffered.
+func NewTracerProvider(ctx context.Context, exporter Exporter, w io.Writer) (*sdktrace.TracerProvider, error) {
+	var exp sdktrace.SpanExporter
+	var err error
+
+	switch exporter {
+	case ExporterOTLP:
+		exp, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		exp, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterFile:
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		err = fmt.Errorf("%w: %q", ErrUnknownExporter, exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	), nil
+}
@@ -0,0 +1,56 @@
+//go:build unit
+
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ralucas/centipede/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExporter(t *testing.T) {
+	for _, s := range []string{"otlp", "stdout", "file", "OTLP"} {
+		_, err := tracing.ParseExporter(s)
+		assert.NoError(t, err, s)
+	}
+
+	_, err := tracing.ParseExporter("jaeger")
+	assert.ErrorIs(t, err, tracing.ErrUnknownExporter)
+}
+
+func TestNewTracerProviderFile(t *testing.T) {
+	var buf bytes.Buffer
+
+	tp, err := tracing.NewTracerProvider(context.TODO(), tracing.ExporterFile, &buf)
+	require.NoError(t, err)
+
+	_, span := tp.Tracer("test").Start(context.TODO(), "etl.run")
+	span.End()
+
+	// spans are batched until shutdown
+	require.NoError(t, tp.Shutdown(context.TODO()))
+
+	var exported struct {
+		Name     string
+		Resource []struct {
+			Key   string
+			Value struct{ Value interface{} }
+		}
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &exported))
+
+	assert.Equal(t, "etl.run", exported.Name)
+
+	var service interface{}
+	for _, kv := range exported.Resource {
+		if kv.Key == "service.name" {
+			service = kv.Value.Value
+		}
+	}
+	assert.Equal(t, "centipede", service)
+}
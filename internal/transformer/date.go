@@ -0,0 +1,194 @@
+package transformer
+
+import (
+	"context"
+	"time"
+
+	"github.com/ralucas/centipede/internal/iso8601"
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+)
+
+const (
+	StartSuffix = "_start"
+	EndSuffix   = "_end"
+)
+
+// DateTransformer normalizes DCAT-US date fields before handing the
+// record to the next transformer. Point-in-time fields are reformatted,
+// interval fields are split into _start/_end columns and periodicity
+// fields are decoded into human readable labels.
+type DateTransformer struct {
+	next              etl.Transformer
+	logger            *zap.Logger
+	layout            string
+	location          *time.Location
+	dateFields        map[string]bool
+	intervalFields    map[string]bool
+	periodicityFields map[string]bool
+}
+
+type DateTransformerOption func(*DateTransformer)
+
+// WithDateLayout sets the Go time layout used for normalized output.
+func WithDateLayout(layout string) DateTransformerOption {
+	return func(t *DateTransformer) {
+		t.layout = layout
+	}
+}
+
+// WithLocation sets the timezone that normalized timestamps are converted
+// to. Values without a zone are also interpreted in this location.
+func WithLocation(loc *time.Location) DateTransformerOption {
+	return func(t *DateTransformer) {
+		t.location = loc
+	}
+}
+
+// WithDateFields overrides the point-in-time fields, defaults to
+// modified and issued.
+func WithDateFields(fields ...string) DateTransformerOption {
+	return func(t *DateTransformer) {
+		t.dateFields = toSet(fields)
+	}
+}
+
+// WithIntervalFields overrides the interval fields, defaults to temporal.
+func WithIntervalFields(fields ...string) DateTransformerOption {
+	return func(t *DateTransformer) {
+		t.intervalFields = toSet(fields)
+	}
+}
+
+// WithPeriodicityFields overrides the periodicity fields, defaults to
+// accrualPeriodicity.
+func WithPeriodicityFields(fields ...string) DateTransformerOption {
+	return func(t *DateTransformer) {
+		t.periodicityFields = toSet(fields)
+	}
+}
+
+func NewDateTransformer(next etl.Transformer, log *zap.Logger, opts ...DateTransformerOption) *DateTransformer {
+	t := &DateTransformer{
+		next:              next,
+		logger:            log,
+		layout:            time.RFC3339,
+		location:          time.UTC,
+		dateFields:        toSet([]string{"modified", "issued"}),
+		intervalFields:    toSet([]string{"temporal"}),
+		periodicityFields: toSet([]string{"accrualPeriodicity"}),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// Columns replaces each interval field with its _start and _end columns.
+func (t *DateTransformer) Columns(fields []string) []string {
+	columns := t.expand(fields)
+	if cm, ok := t.next.(etl.ColumnMapper); ok {
+		return cm.Columns(columns)
+	}
+
+	return columns
+}
+
+func (t *DateTransformer) Transform(ctx context.Context, data map[string]interface{}, fields []string) ([][]string, error) {
+	normalized := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		normalized[k] = v
+	}
+
+	for _, field := range fields {
+		s, _ := data[field].(string)
+
+		switch {
+		case t.intervalFields[field]:
+			normalized[field+StartSuffix], normalized[field+EndSuffix] = t.splitInterval(field, s)
+		case s == "":
+			continue
+		case t.dateFields[field]:
+			normalized[field] = t.normalizeDate(field, s)
+		case t.periodicityFields[field]:
+			label, err := iso8601.PeriodicityLabel(s)
+			if err != nil {
+				t.logger.Debug("unable to decode periodicity, keeping raw value", zap.String("field", field), zap.Error(err))
+				continue
+			}
+			normalized[field] = label
+		}
+	}
+
+	return t.next.Transform(ctx, normalized, t.expand(fields))
+}
+
+// Flush forwards to the next transformer when it holds rows back.
+func (t *DateTransformer) Flush(ctx context.Context, emit func([][]string) error) error {
+	if f, ok := t.next.(etl.Flusher); ok {
+		return f.Flush(ctx, emit)
+	}
+
+	return nil
+}
+
+// expand is Columns without delegating to the next transformer, as the
+// next transformer maps its own columns.
+func (t *DateTransformer) expand(fields []string) []string {
+	columns := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if t.intervalFields[field] {
+			columns = append(columns, field+StartSuffix, field+EndSuffix)
+		} else {
+			columns = append(columns, field)
+		}
+	}
+
+	return columns
+}
+
+func (t *DateTransformer) normalizeDate(field, s string) string {
+	ts, err := iso8601.ParseTimeInLocation(s, t.location)
+	if err != nil {
+		// modified may legitimately be a repeating interval such as R/P1D
+		t.logger.Debug("unable to parse date, keeping raw value", zap.String("field", field), zap.Error(err))
+		return s
+	}
+
+	return t.format(ts)
+}
+
+// splitInterval returns the start and end of an interval. One that doesn't
+// parse is kept raw as its start, so the value isn't lost.
+func (t *DateTransformer) splitInterval(field, s string) (string, string) {
+	if s == "" {
+		return "", ""
+	}
+
+	iv, err := iso8601.ParseIntervalInLocation(s, t.location)
+	if err != nil {
+		t.logger.Warn("unable to parse interval, keeping raw value as its start", zap.String("field", field), zap.Error(err))
+		return s, ""
+	}
+
+	return t.format(iv.Start), t.format(iv.End)
+}
+
+func (t *DateTransformer) format(ts time.Time) string {
+	if ts.IsZero() {
+		return ""
+	}
+
+	return ts.In(t.location).Format(t.layout)
+}
+
+func toSet(fields []string) map[string]bool {
+	set := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		set[f] = true
+	}
+
+	return set
+}
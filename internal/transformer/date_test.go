@@ -0,0 +1,92 @@
+//go:build unit
+
+package transformer_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ralucas/centipede/internal/transformer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDateTransform(t *testing.T) {
+	log, err := zap.NewDevelopment()
+	require.NoError(t, err)
+
+	testFields := []string{"modified", "temporal", "accrualPeriodicity"}
+	testMap := map[string]interface{}{
+		"modified":           "2019-06-12T10:30:00-05:00",
+		"temporal":           "2000-01-01/2010-12-31",
+		"accrualPeriodicity": "R/P3M",
+	}
+
+	t.Run("columns split intervals", func(t *testing.T) {
+		tf := transformer.NewDateTransformer(transformer.NewRowTransformer(log), log)
+
+		assert.Equal(t,
+			[]string{"modified", "temporal_start", "temporal_end", "accrualPeriodicity"},
+			tf.Columns(testFields),
+		)
+	})
+
+	t.Run("normalizes to utc by default", func(t *testing.T) {
+		tf := transformer.NewDateTransformer(transformer.NewRowTransformer(log), log)
+
+		result, err := tf.Transform(context.TODO(), testMap, testFields)
+		require.NoError(t, err)
+
+		require.Equal(t, 1, len(result))
+		assert.Equal(t,
+			[]string{"2019-06-12T15:30:00Z", "2000-01-01T00:00:00Z", "2010-12-31T00:00:00Z", "quarterly"},
+			result[0],
+		)
+	})
+
+	t.Run("uses layout and location", func(t *testing.T) {
+		loc, err := time.LoadLocation("America/New_York")
+		require.NoError(t, err)
+
+		tf := transformer.NewDateTransformer(
+			transformer.NewRowTransformer(log),
+			log,
+			transformer.WithDateLayout("2006-01-02 15:04"),
+			transformer.WithLocation(loc),
+		)
+
+		result, err := tf.Transform(context.TODO(), testMap, testFields)
+		require.NoError(t, err)
+
+		assert.Equal(t, "2019-06-12 11:30", result[0][0])
+		assert.Equal(t, "2000-01-01 00:00", result[0][1])
+	})
+
+	t.Run("keeps unparseable values", func(t *testing.T) {
+		tf := transformer.NewDateTransformer(transformer.NewRowTransformer(log), log)
+
+		result, err := tf.Transform(context.TODO(), map[string]interface{}{
+			"modified":           "R/P1D",
+			"temporal":           "",
+			"accrualPeriodicity": "sometimes",
+		}, testFields)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"R/P1D", "", "", "sometimes"}, result[0])
+	})
+
+	t.Run("keeps unparseable intervals as their start", func(t *testing.T) {
+		tf := transformer.NewDateTransformer(transformer.NewRowTransformer(log), log)
+
+		result, err := tf.Transform(context.TODO(), map[string]interface{}{
+			"modified":           "",
+			"temporal":           "sometime in the 90s",
+			"accrualPeriodicity": "",
+		}, testFields)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"", "sometime in the 90s", "", ""}, result[0])
+	})
+}
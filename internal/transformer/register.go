@@ -0,0 +1,99 @@
+package transformer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ralucas/centipede/internal/extsort"
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+)
+
+const mebibyte = 1 << 20
+
+// DateConfig configures the normalize_dates transformer. Unset fields keep
+// the DateTransformer defaults.
+type DateConfig struct {
+	Layout            string   `json:"layout"`
+	Timezone          string   `json:"timezone"`
+	DateFields        []string `json:"date_fields"`
+	IntervalFields    []string `json:"interval_fields"`
+	PeriodicityFields []string `json:"periodicity_fields"`
+}
+
+// SortConfig configures the sort transformer.
+type SortConfig struct {
+	// By are the output columns sorted on, e.g. modified:desc.
+	By []string `json:"by"`
+	// MemoryMiB is the memory budget before rows spill to disk.
+	MemoryMiB int    `json:"memory_mib"`
+	Dir       string `json:"dir"`
+}
+
+func init() {
+	etl.RegisterTransformer("rows", func(next etl.Transformer, _ []string, _ struct{}, log *zap.Logger) (etl.Transformer, error) {
+		if next != nil {
+			return nil, fmt.Errorf("%w: rows must start the chain", etl.ErrInvalidChain)
+		}
+		return NewRowTransformer(log), nil
+	})
+
+	etl.RegisterTransformer("normalize_dates", func(next etl.Transformer, _ []string, conf DateConfig, log *zap.Logger) (etl.Transformer, error) {
+		var opts []DateTransformerOption
+
+		if conf.Layout != "" {
+			opts = append(opts, WithDateLayout(conf.Layout))
+		}
+		if conf.Timezone != "" {
+			loc, err := time.LoadLocation(conf.Timezone)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, WithLocation(loc))
+		}
+		if len(conf.DateFields) > 0 {
+			opts = append(opts, WithDateFields(conf.DateFields...))
+		}
+		if len(conf.IntervalFields) > 0 {
+			opts = append(opts, WithIntervalFields(conf.IntervalFields...))
+		}
+		if len(conf.PeriodicityFields) > 0 {
+			opts = append(opts, WithPeriodicityFields(conf.PeriodicityFields...))
+		}
+
+		return NewDateTransformer(orRows(next, log), log, opts...), nil
+	})
+
+	// the sort transformer owns a sorter, which is cleaned up by Close
+	etl.RegisterTransformer("sort", func(next etl.Transformer, fields []string, conf SortConfig, log *zap.Logger) (etl.Transformer, error) {
+		next = orRows(next, log)
+
+		columns := fields
+		if cm, ok := next.(etl.ColumnMapper); ok {
+			columns = cm.Columns(fields)
+		}
+
+		keys, err := extsort.ParseKeys(conf.By, columns)
+		if err != nil {
+			return nil, err
+		}
+
+		sorter := extsort.NewSorter(
+			keys,
+			log,
+			extsort.WithMemoryBudget(int64(conf.MemoryMiB)*mebibyte),
+			extsort.WithSpillDir(conf.Dir),
+		)
+
+		return NewSortTransformer(next, sorter, log), nil
+	})
+}
+
+// orRows starts a chain with the row transformer when next is nil.
+func orRows(next etl.Transformer, log *zap.Logger) etl.Transformer {
+	if next == nil {
+		return NewRowTransformer(log)
+	}
+
+	return next
+}
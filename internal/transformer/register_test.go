@@ -0,0 +1,66 @@
+//go:build unit
+
+package transformer_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRegisteredChain(t *testing.T) {
+	log := zap.NewNop()
+	fields := []string{"modified", "keyword"}
+
+	tf, err := etl.NewTransformer("normalize_dates", nil, fields, map[string]interface{}{"layout": "2006-01-02"}, log)
+	require.NoError(t, err)
+
+	tf, err = etl.NewTransformer("sort", tf, fields, map[string]interface{}{"by": []string{"modified:desc", "keyword"}}, log)
+	require.NoError(t, err)
+
+	for _, m := range []map[string]interface{}{
+		{"modified": "2019-06-12T10:00:00Z", "keyword": []interface{}{"b", "a"}},
+		{"modified": "2021-03-30T15:14:53.668Z", "keyword": []interface{}{"c"}},
+	} {
+		_, err := tf.Transform(context.TODO(), m, fields)
+		require.NoError(t, err)
+	}
+
+	var result [][]string
+	err = tf.(etl.Flusher).Flush(context.TODO(), func(rows [][]string) error {
+		result = append(result, rows...)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]string{
+		{"2021-03-30", "c"},
+		{"2019-06-12", "a"},
+		{"2019-06-12", "b"},
+	}, result)
+
+	closer, ok := tf.(io.Closer)
+	require.True(t, ok)
+	assert.NoError(t, closer.Close())
+}
+
+func TestRegisteredErrors(t *testing.T) {
+	log := zap.NewNop()
+
+	rows, err := etl.NewTransformer("rows", nil, nil, nil, log)
+	require.NoError(t, err)
+
+	_, err = etl.NewTransformer("rows", rows, nil, nil, log)
+	assert.ErrorIs(t, err, etl.ErrInvalidChain)
+
+	_, err = etl.NewTransformer("normalize_dates", rows, nil, map[string]interface{}{"timezone": "Mars/Olympus"}, log)
+	assert.Error(t, err)
+
+	_, err = etl.NewTransformer("sort", rows, []string{"title"}, map[string]interface{}{"by": []string{"modified"}}, log)
+	assert.Error(t, err)
+}
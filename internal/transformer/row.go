@@ -2,7 +2,8 @@ package transformer
 
 import (
 	"context"
-	"reflect"
+
+	"github.com/ralucas/centipede/internal/flatten"
 
 	"go.uber.org/zap"
 )
@@ -30,47 +31,5 @@ func (t *RowTransformer) Headers(data map[string]interface{}) []string {
 }
 
 func (t *RowTransformer) Transform(ctx context.Context, data map[string]interface{}, fields []string) ([][]string, error) {
-	return mapTo2DSlice(data, fields, maxRowsCount(data)), nil
-}
-
-func maxRowsCount(m map[string]interface{}) int {
-	rc := 1
-
-	for _, v := range m {
-		if isListType(v) {
-			sv := v.([]interface{})
-			if len(sv) > rc {
-				rc = len(sv)
-			}
-		}
-	}
-
-	return rc
-}
-
-func mapTo2DSlice(m map[string]interface{}, fields []string, rowsCount int) [][]string {
-	arr := make([][]string, rowsCount)
-	for row := 0; row < rowsCount; row++ {
-		arr[row] = make([]string, len(fields))
-		for col, field := range fields {
-			v := m[field]
-			if isListType(v) {
-				va := v.([]interface{})
-				if row < len(va) {
-					arr[row][col] = va[row].(string)
-				} else {
-					arr[row][col] = ""
-				}
-			} else {
-				arr[row][col] = v.(string)
-			}
-		}
-	}
-
-	return arr
-}
-
-func isListType(v interface{}) bool {
-	rt := reflect.TypeOf(v)
-	return rt.Kind() == reflect.Array || rt.Kind() == reflect.Slice
+	return flatten.Rows(data, fields), nil
 }
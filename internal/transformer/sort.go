@@ -0,0 +1,80 @@
+package transformer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ralucas/centipede/internal/extsort"
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+)
+
+// SortTransformer holds back the rows of the next transformer and emits
+// them fully sorted at the end of the stream.
+type SortTransformer struct {
+	mu     sync.Mutex
+	next   etl.Transformer
+	sorter *extsort.Sorter
+	logger *zap.Logger
+}
+
+func NewSortTransformer(next etl.Transformer, sorter *extsort.Sorter, log *zap.Logger) *SortTransformer {
+	return &SortTransformer{
+		next:   next,
+		sorter: sorter,
+		logger: log,
+	}
+}
+
+func (t *SortTransformer) Columns(fields []string) []string {
+	if cm, ok := t.next.(etl.ColumnMapper); ok {
+		return cm.Columns(fields)
+	}
+
+	return fields
+}
+
+func (t *SortTransformer) Transform(ctx context.Context, data map[string]interface{}, fields []string) ([][]string, error) {
+	rows, err := t.next.Transform(ctx, data, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, t.add(rows)
+}
+
+// Flush first collects any rows the next transformer held back, then emits
+// everything in sorted order.
+func (t *SortTransformer) Flush(ctx context.Context, emit func([][]string) error) error {
+	if f, ok := t.next.(etl.Flusher); ok {
+		if err := f.Flush(ctx, t.add); err != nil {
+			return err
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.logger.Debug("emitting sorted rows")
+
+	return t.sorter.Emit(ctx, emit)
+}
+
+func (t *SortTransformer) add(rows [][]string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, row := range rows {
+		if err := t.sorter.Add(row); err != nil {
+			t.logger.Error("failed to buffer row for sorting", zap.Error(err))
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close removes the sorter's spilled runs.
+func (t *SortTransformer) Close() error {
+	return t.sorter.Close()
+}
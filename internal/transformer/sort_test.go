@@ -0,0 +1,48 @@
+//go:build unit
+
+package transformer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ralucas/centipede/internal/extsort"
+	"github.com/ralucas/centipede/internal/transformer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSortTransform(t *testing.T) {
+	log, err := zap.NewDevelopment()
+	require.NoError(t, err)
+
+	testFields := []string{"modified", "keyword"}
+
+	keys, err := extsort.ParseKeys([]string{"modified:desc", "keyword"}, testFields)
+	require.NoError(t, err)
+
+	tf := transformer.NewSortTransformer(transformer.NewRowTransformer(log), extsort.NewSorter(keys, log), log)
+
+	for _, m := range []map[string]interface{}{
+		{"modified": "2019-06-12", "keyword": []interface{}{"b", "a"}},
+		{"modified": "2021-03-30T15:14:53.668Z", "keyword": []interface{}{"c"}},
+	} {
+		rows, err := tf.Transform(context.TODO(), m, testFields)
+		require.NoError(t, err)
+		assert.Empty(t, rows)
+	}
+
+	var result [][]string
+	err = tf.Flush(context.TODO(), func(rows [][]string) error {
+		result = append(result, rows...)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]string{
+		{"2021-03-30T15:14:53.668Z", "c"},
+		{"2019-06-12", "a"},
+		{"2019-06-12", "b"},
+	}, result)
+}
@@ -0,0 +1,138 @@
+package etl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrInvalidBranch = errors.New("invalid branch")
+
+// BranchOf is a pipeline of its own fed every record the processor reads,
+// so several outputs are produced from a single pass over the input. The
+// records are shared between branches and must not be modified.
+type BranchOf[In, Ext, Out any] struct {
+	// Name identifies the branch in errors, the run report and traces.
+	Name        string
+	Extractor   ExtractorOf[In, Ext]
+	Transformer TransformerOf[Ext, Out]
+	Loader      LoaderOf[Out]
+	Fields      []string
+	Output      io.Writer
+	// Buffer is the number of records queued for the branch before it
+	// holds up the reader, defaults to the batch size. A larger buffer lets
+	// a branch fall further behind the others.
+	Buffer int
+}
+
+// Branch is a branch of the json object pipeline.
+type Branch = BranchOf[map[string]interface{}, map[string]interface{}, []string]
+
+// WithBranch feeds every record read to b alongside the processor's own
+// extractor, transformer and loader. Branches share the workers setting,
+// batching, ordering and error policy, and a fatal failure in any branch
+// cancels them all.
+func (e *Processor[In, Ext, Out]) WithBranch(b BranchOf[In, Ext, Out]) *Processor[In, Ext, Out] {
+	e.extraBranches = append(e.extraBranches, b)
+	return e
+}
+
+type branchKey struct{}
+
+// BranchName returns the name of the branch a stage, middleware or hook is
+// running in, which is empty for the processor's own pipeline.
+func BranchName(ctx context.Context) string {
+	name, _ := ctx.Value(branchKey{}).(string)
+	return name
+}
+
+// branch is a Branch with the processor's middleware applied.
+type branch[In, Ext, Out any] struct {
+	name         string
+	extractor    ExtractorOf[In, Ext]
+	transformer  TransformerOf[Ext, Out]
+	loader       LoaderOf[Out]
+	flusher      FlusherOf[Out]
+	columnMapper ColumnMapper
+	headerLoader HeaderLoader
+	fields       []string
+	output       io.Writer
+	buffer       int
+	counters     *runCounters
+}
+
+func (e *Processor[In, Ext, Out]) newBranch(b BranchOf[In, Ext, Out]) *branch[In, Ext, Out] {
+	br := &branch[In, Ext, Out]{
+		name:     b.Name,
+		fields:   b.Fields,
+		output:   b.Output,
+		buffer:   b.Buffer,
+		counters: &runCounters{},
+	}
+
+	br.flusher, _ = b.Transformer.(FlusherOf[Out])
+	br.columnMapper, _ = b.Transformer.(ColumnMapper)
+	br.extractor, br.transformer, br.loader = e.wrap(b.Extractor, b.Transformer, b.Loader)
+	br.headerLoader, _ = br.loader.(HeaderLoader)
+
+	if br.buffer <= 0 {
+		br.buffer = e.batchSize
+	}
+
+	return br
+}
+
+// validateBranches checks every branch is complete and has its own name.
+func validateBranches[In, Ext, Out any](branches []BranchOf[In, Ext, Out]) error {
+	names := make(map[string]bool, len(branches))
+
+	for _, b := range branches {
+		if b.Name == "" {
+			return fmt.Errorf("%w: a branch needs a name", ErrInvalidBranch)
+		}
+		if names[b.Name] {
+			return fmt.Errorf("%w: %q is used by more than one branch", ErrInvalidBranch, b.Name)
+		}
+		names[b.Name] = true
+
+		if b.Extractor == nil || b.Transformer == nil || b.Loader == nil || b.Output == nil {
+			return fmt.Errorf("%w: %q needs an extractor, transformer, loader and output", ErrInvalidBranch, b.Name)
+		}
+	}
+
+	return nil
+}
+
+// headers returns the output columns of the branch.
+func (b *branch[In, Ext, Out]) headers() []string {
+	if b.columnMapper != nil {
+		return b.columnMapper.Columns(b.fields)
+	}
+
+	return b.fields
+}
+
+// loadHeader loads the output columns as the first row when rows are
+// []string, otherwise it hands them to a HeaderLoader, either way through
+// the loader middleware.
+func (b *branch[In, Ext, Out]) loadHeader(ctx context.Context) error {
+	if rows, ok := any([][]string{b.headers()}).([]Out); ok {
+		return b.loader.Load(ctx, rows, b.output)
+	}
+
+	if b.headerLoader != nil {
+		return b.headerLoader.LoadHeader(ctx, b.headers(), b.output)
+	}
+
+	return nil
+}
+
+// context tags ctx with the name of the branch.
+func (b *branch[In, Ext, Out]) context(ctx context.Context) context.Context {
+	if b.name == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, branchKey{}, b.name)
+}
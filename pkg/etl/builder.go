@@ -0,0 +1,214 @@
+package etl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ralucas/centipede/internal/flatten"
+	"go.uber.org/zap"
+)
+
+var ErrIncompletePipeline = errors.New("incomplete pipeline")
+
+// Step builds one transformer of a chain, following next, which is nil for
+// the first step. It is a TransformerFactory with its config already bound.
+type Step func(next Transformer, fields []string, log *zap.Logger) (Transformer, error)
+
+// TransformerStep is the step building the transformer registered under
+// name from config.
+func TransformerStep(name string, config interface{}) Step {
+	return func(next Transformer, fields []string, log *zap.Logger) (Transformer, error) {
+		return NewTransformer(name, next, fields, config, log)
+	}
+}
+
+// SinkOf is the output of a pipeline, rows of type T loaded by Loader are
+// written to Output.
+type SinkOf[T any] struct {
+	Loader LoaderOf[T]
+	Output io.Writer
+}
+
+// Sink is the output of rows of strings.
+type Sink = SinkOf[[]string]
+
+// Builder assembles an ETLProcessor step by step:
+//
+//	report, err := etl.New().
+//		From(sources.JSON(r)).
+//		Select("title", "modified").
+//		Transform(transforms.Sort([]string{"modified:desc"})).
+//		To(sinks.CSV(w)).
+//		Run(ctx)
+//
+// Without Extract, the selected fields are looked up in each record, and
+// without Transform, each record makes a row, lists spreading over several.
+// These are what the map extractor and the rows transformer do.
+type Builder struct {
+	source    StreamIterator
+	extractor Extractor
+	fields    []string
+	steps     []Step
+	sink      *Sink
+	rejecter  Rejecter
+	configure []func(*ETLProcessor)
+	logger    *zap.Logger
+	opts      []ETLProcessorOption
+}
+
+// New starts a pipeline run by a processor with opts.
+func New(opts ...ETLProcessorOption) *Builder {
+	return &Builder{
+		logger: zap.NewNop(),
+		opts:   opts,
+	}
+}
+
+// From reads the pipeline's records from si.
+func (b *Builder) From(si StreamIterator) *Builder {
+	b.source = si
+	return b
+}
+
+// Select sets the fields extracted from each record, which are the
+// columns of the output.
+func (b *Builder) Select(fields ...string) *Builder {
+	b.fields = append(b.fields, fields...)
+	return b
+}
+
+// Extract reads the selected fields with ex.
+func (b *Builder) Extract(ex Extractor) *Builder {
+	b.extractor = ex
+	return b
+}
+
+// Transform appends steps to the transformer chain.
+func (b *Builder) Transform(steps ...Step) *Builder {
+	b.steps = append(b.steps, steps...)
+	return b
+}
+
+// To loads the rows into s.
+func (b *Builder) To(s Sink) *Builder {
+	b.sink = &s
+	return b
+}
+
+// Reject hands every failed record to r.
+func (b *Builder) Reject(r Rejecter) *Builder {
+	b.rejecter = r
+	return b
+}
+
+// Configure calls fn with the processor once it is built, to set its
+// hooks, branches or middleware.
+func (b *Builder) Configure(fn func(*ETLProcessor)) *Builder {
+	b.configure = append(b.configure, fn)
+	return b
+}
+
+// Logger sets the logger of the processor and of the components the
+// builder creates.
+func (b *Builder) Logger(log *zap.Logger) *Builder {
+	b.logger = log
+	return b
+}
+
+// Run builds the processor and processes every record of the source.
+func (b *Builder) Run(ctx context.Context) (*RunReport, error) {
+	switch {
+	case b.source == nil:
+		return nil, fmt.Errorf("%w: no source, use From", ErrIncompletePipeline)
+	case len(b.fields) == 0:
+		return nil, fmt.Errorf("%w: no fields, use Select", ErrIncompletePipeline)
+	case b.sink == nil || b.sink.Loader == nil || b.sink.Output == nil:
+		return nil, fmt.Errorf("%w: no sink, use To", ErrIncompletePipeline)
+	}
+
+	ex := b.extractor
+	if ex == nil {
+		ex = fieldExtractor{}
+	}
+
+	tf, closers, err := b.chain()
+	defer func() {
+		for i := len(closers) - 1; i >= 0; i-- {
+			if err := closers[i].Close(); err != nil {
+				b.logger.Warn("failed to close transformer", zap.Error(err))
+			}
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	p := NewETLProcessor(ex, tf, b.sink.Loader, b.source, b.logger, b.opts...)
+	if b.rejecter != nil {
+		p.WithRejecter(b.rejecter)
+	}
+	for _, fn := range b.configure {
+		fn(p)
+	}
+
+	return p.Process(ctx, b.sink.Output, b.fields)
+}
+
+// chain builds the transformer chain from the steps, returning the links
+// that hold resources to release once the run is done.
+func (b *Builder) chain() (Transformer, []io.Closer, error) {
+	steps := b.steps
+	if len(steps) == 0 {
+		steps = []Step{rowsStep}
+	}
+
+	var (
+		tf      Transformer
+		closers []io.Closer
+	)
+
+	for _, step := range steps {
+		next, err := step(tf, b.fields, b.logger)
+		if err != nil {
+			return nil, closers, err
+		}
+
+		if c, ok := next.(io.Closer); ok {
+			closers = append(closers, c)
+		}
+		tf = next
+	}
+
+	return tf, closers, nil
+}
+
+// fieldExtractor is the default extractor, looking the fields up in the
+// record like the map extractor.
+type fieldExtractor struct{}
+
+func (fieldExtractor) Extract(_ context.Context, data map[string]interface{}, fields []string) (map[string]interface{}, error) {
+	extract := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		val, err := flatten.Lookup(data, field)
+		if err != nil {
+			return nil, err
+		}
+		extract[field] = val
+	}
+
+	return extract, nil
+}
+
+// rowTransformer is the default transformer, building rows like the rows
+// transformer.
+type rowTransformer struct{}
+
+func (rowTransformer) Transform(_ context.Context, data map[string]interface{}, fields []string) ([][]string, error) {
+	return flatten.Rows(data, fields), nil
+}
+
+func rowsStep(next Transformer, _ []string, _ *zap.Logger) (Transformer, error) {
+	return rowTransformer{}, nil
+}
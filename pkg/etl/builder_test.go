@@ -0,0 +1,111 @@
+//go:build unit
+
+package etl_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/ralucas/centipede/pkg/sinks"
+	"github.com/ralucas/centipede/pkg/transforms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilderIncomplete(t *testing.T) {
+	var out bytes.Buffer
+	records := func() etl.StreamIterator {
+		return &sliceIterator{records: []map[string]interface{}{{"title": "air"}}}
+	}
+
+	tests := []struct {
+		name    string
+		builder *etl.Builder
+		want    string
+	}{
+		{"no source", etl.New().Select("title").To(sinks.CSV(&out)), "no source"},
+		{"no fields", etl.New().From(records()).To(sinks.CSV(&out)), "no fields"},
+		{"no sink", etl.New().From(records()).Select("title"), "no sink"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.builder.Run(context.TODO())
+			assert.ErrorIs(t, err, etl.ErrIncompletePipeline)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestBuilderSteps(t *testing.T) {
+	var out bytes.Buffer
+
+	_, err := etl.New().
+		From(&sliceIterator{records: []map[string]interface{}{{"title": "air"}, {"title": "water"}}}).
+		Select("title").
+		Transform(etl.TransformerStep("rows", nil), etl.TransformerStep("test_case", caseConfig{Upper: true})).
+		To(sinks.CSV(&out)).
+		Run(context.TODO())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"title", "AIR", "WATER"}, strings.Fields(out.String()))
+
+	_, err = etl.New().
+		From(&sliceIterator{}).
+		Select("title").
+		Transform(etl.TransformerStep("test_case", nil)).
+		To(sinks.CSV(&out)).
+		Run(context.TODO())
+	assert.ErrorIs(t, err, etl.ErrInvalidChain)
+}
+
+func TestBuilderDefaults(t *testing.T) {
+	var out bytes.Buffer
+
+	_, err := etl.New().
+		From(&sliceIterator{records: []map[string]interface{}{
+			{"title": "air", "contactPoint": map[string]interface{}{"fn": "ann"}, "keyword": []interface{}{"a", "b"}},
+		}}).
+		Select("title", "contactPoint.fn", "keyword").
+		To(sinks.CSV(&out)).
+		Run(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, "title,contactPoint.fn,keyword\nair,ann,a\nair,ann,b\n", out.String())
+}
+
+func TestBuilderFlushedRows(t *testing.T) {
+	records := []map[string]interface{}{
+		{"title": "air", "publisher": "EPA", "keyword": []interface{}{"a", "b"}},
+		{"title": "water", "publisher": "EPA", "keyword": []interface{}{"c"}},
+		{"title": "soil", "publisher": "USGS", "keyword": []interface{}{"d"}},
+	}
+
+	tests := []struct {
+		name   string
+		fields []string
+		steps  []etl.Step
+		rows   int64
+	}{
+		{name: "sort", fields: []string{"title", "keyword"}, steps: []etl.Step{transforms.Rows(), transforms.Sort([]string{"title:asc"})}, rows: 4},
+		{name: "group by", fields: []string{"publisher"}, steps: []etl.Step{transforms.Aggregate([]string{"publisher"}, nil)}, rows: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			report, err := etl.New().
+				From(&sliceIterator{records: records}).
+				Select(tt.fields...).
+				Transform(tt.steps...).
+				To(sinks.CSV(&out)).
+				Run(context.TODO())
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.rows, report.RowsProduced)
+			assert.Equal(t, tt.rows, report.RowsWritten)
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package etl
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Stage is the part of the pipeline an error occurred in.
+type Stage string
+
+const (
+	StageHeader    Stage = "header"
+	StageRead      Stage = "read"
+	StageExtract   Stage = "extract"
+	StageTransform Stage = "transform"
+	StageLoad      Stage = "load"
+	StageFlush     Stage = "flush"
+	StageReject    Stage = "reject"
+)
+
+// RecordError is an error from one stage of the pipeline. Index is the
+// zero-based position of the record in the input, or -1 when the error is
+// not tied to a record, such as writing the header. Branch names the branch
+// the error occurred in, it is empty for the processor's own pipeline and
+// for read errors.
+type RecordError struct {
+	Index  int64
+	Stage  Stage
+	Branch string
+	Err    error
+	// Others are the failures of the same record in other branches, set on
+	// the error handed to a rejecter so the record is rejected once.
+	Others []*RecordError
+}
+
+func (e *RecordError) Error() string {
+	var prefix string
+	if e.Branch != "" {
+		prefix = e.Branch + ": "
+	}
+
+	if e.Index < 0 {
+		return fmt.Sprintf("%s%s: %s", prefix, e.Stage, e.Err)
+	}
+
+	return fmt.Sprintf("%srecord %d: %s: %s", prefix, e.Index, e.Stage, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// errorCollector gathers the errors of a run according to its policy. The
+// first fatal error cancels the run through cancel.
+type errorCollector struct {
+	mu      sync.Mutex
+	policy  ErrorPolicy
+	errs    []*RecordError
+	budget  error
+	skipped int64
+	// failed holds the records counted as failed, so a record failing in
+	// several branches counts once
+	failed map[int64]bool
+	report *RunReport
+	instr  Instrumentation
+	cancel func()
+	logger *zap.Logger
+}
+
+// add reports a failure and returns true when the record is skipped and
+// the run carries on. Every error is counted by stage and type, while a
+// record failing in several branches counts as one failed record.
+func (c *errorCollector) add(err *RecordError) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.report.countError(err)
+	c.instr.StageError(err.Stage)
+
+	counted := c.failed[err.Index]
+	if err.Index >= 0 && !counted {
+		if c.failed == nil {
+			c.failed = make(map[int64]bool)
+		}
+		c.failed[err.Index] = true
+		c.report.Failed++
+	}
+
+	if !c.policy.Skips() || err.Index < 0 || errors.Is(err.Err, ErrStreamBroken) {
+		c.fatal(err)
+		return false
+	}
+
+	if counted {
+		return true
+	}
+
+	c.skipped++
+	c.logger.Warn("skipping failed record", zap.Int64("index", err.Index), zap.String("stage", string(err.Stage)), zap.Error(err.Err))
+
+	if c.policy.MaxErrors > 0 && c.skipped > int64(c.policy.MaxErrors) && c.budget == nil {
+		c.budget = fmt.Errorf("%w: %d records failed, the limit is %d", ErrErrorBudgetExceeded, c.skipped, c.policy.MaxErrors)
+		c.fatal(err)
+		return false
+	}
+
+	return true
+}
+
+// finish checks the error rate once all records have been read.
+func (c *errorCollector) finish(records int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.policy.MaxErrorRate == 0 || records == 0 || c.budget != nil {
+		return
+	}
+
+	if rate := float64(c.skipped) / float64(records); rate > c.policy.MaxErrorRate {
+		c.budget = fmt.Errorf("%w: %d of %d records failed (%.2f%%), the limit is %.2f%%",
+			ErrErrorBudgetExceeded, c.skipped, records, rate*100, c.policy.MaxErrorRate*100)
+	}
+}
+
+func (c *errorCollector) fatal(err *RecordError) {
+	c.errs = append(c.errs, err)
+	if len(c.errs) == 1 {
+		c.cancel()
+	}
+}
+
+// err joins the budget error, if any, and the fatal errors in input order.
+func (c *errorCollector) err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.errs) == 0 && c.budget == nil {
+		return nil
+	}
+
+	slices.SortStableFunc(c.errs, func(a, b *RecordError) int {
+		return cmp.Compare(a.Index, b.Index)
+	})
+
+	errs := make([]error, 0, len(c.errs)+1)
+	if c.budget != nil {
+		errs = append(errs, c.budget)
+	}
+	for _, err := range c.errs {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// rejectBook holds the failures of records until every branch is done with
+// them, so a record failing in several branches is rejected once with all
+// of its failures.
+type rejectBook[In any] struct {
+	mu       sync.Mutex
+	branches int
+	pending  map[uint64]*pendingReject[In]
+}
+
+type pendingReject[In any] struct {
+	data    In
+	errs    []*RecordError
+	settled int
+}
+
+func newRejectBook[In any](branches int) *rejectBook[In] {
+	return &rejectBook[In]{
+		branches: branches,
+		pending:  make(map[uint64]*pendingReject[In]),
+	}
+}
+
+// fail adds a failure of rec.
+func (b *rejectBook[In]) fail(rec record[In], err *RecordError) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	p := b.entry(rec.seq)
+	p.data = rec.data
+	p.errs = append(p.errs, err)
+}
+
+// settle marks rec as done by a branch, returning the record to reject
+// once every branch is done with it and one of them failed it.
+func (b *rejectBook[In]) settle(rec record[In]) (*RecordError, In, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	p := b.entry(rec.seq)
+	if p.settled++; p.settled < b.branches {
+		var zero In
+		return nil, zero, false
+	}
+
+	delete(b.pending, rec.seq)
+
+	return p.rejected()
+}
+
+// drain returns the failed records some branch never finished with, as
+// happens when the run is cancelled, in input order.
+func (b *rejectBook[In]) drain() []*pendingReject[In] {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var failed []*pendingReject[In]
+	for seq, p := range b.pending {
+		if len(p.errs) > 0 {
+			failed = append(failed, p)
+		}
+		delete(b.pending, seq)
+	}
+
+	slices.SortFunc(failed, func(a, b *pendingReject[In]) int {
+		return cmp.Compare(a.errs[0].Index, b.errs[0].Index)
+	})
+
+	return failed
+}
+
+func (b *rejectBook[In]) entry(seq uint64) *pendingReject[In] {
+	p, ok := b.pending[seq]
+	if !ok {
+		p = &pendingReject[In]{}
+		b.pending[seq] = p
+	}
+
+	return p
+}
+
+// rejected returns the first failure carrying the others, or false when
+// no branch failed the record.
+func (p *pendingReject[In]) rejected() (*RecordError, In, bool) {
+	if len(p.errs) == 0 {
+		var zero In
+		return nil, zero, false
+	}
+
+	re := *p.errs[0]
+	re.Others = p.errs[1:]
+
+	return &re, p.data, true
+}
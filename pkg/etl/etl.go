@@ -4,97 +4,773 @@ import (
 	"context"
 	"errors"
 	"io"
+	"runtime"
 	"sync"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-type ETLProcessor struct {
-	streamIterator StreamIterator
-	extractor      Extractor
-	transformer    Transformer
-	loader         Loader
+// Processor streams records of type In from a StreamIteratorOf, extracts
+// them into Ext, transforms them into rows of type Out and loads the rows.
+type Processor[In, Ext, Out any] struct {
+	settings
+	streamIterator StreamIteratorOf[In]
+	extractor      ExtractorOf[In, Ext]
+	transformer    TransformerOf[Ext, Out]
+	loader         LoaderOf[Out]
 	logger         *zap.Logger
+	rejecter       RejecterOf[In]
+	hooks          []HooksOf[Out]
+	extraBranches  []BranchOf[In, Ext, Out]
+
+	extractorMiddleware   []ExtractorMiddlewareOf[In, Ext]
+	transformerMiddleware []TransformerMiddlewareOf[Ext, Out]
+	loaderMiddleware      []LoaderMiddlewareOf[Out]
+}
+
+// ETLProcessor is the processor of json objects into rows of strings.
+type ETLProcessor = Processor[map[string]interface{}, map[string]interface{}, []string]
+
+// settings are the options of a processor that don't depend on its types.
+type settings struct {
+	workers         int
+	batchSize       int
+	batchTimeout    time.Duration
+	ordered         bool
+	reorderWindow   int
+	errorPolicy     ErrorPolicy
+	byteCounter     ByteCounter
+	instr           Instrumentation
+	tracer          trace.Tracer
+	recordSpanEvery uint64
+}
+
+const (
+	defaultBatchSize     = 100
+	defaultBatchTimeout  = time.Second
+	defaultReorderWindow = 1000
+)
+
+// ETLProcessorOption configures a processor of any types. The rejecter,
+// hooks, branches and middleware depend on the types of a processor, so
+// they are set with its methods and a mismatch doesn't compile.
+type ETLProcessorOption func(*settings)
+
+// WithWorkers sets the number of records extracted and transformed
+// concurrently, defaults to GOMAXPROCS.
+func WithWorkers(n int) ETLProcessorOption {
+	return func(s *settings) {
+		if n > 0 {
+			s.workers = n
+		}
+	}
+}
+
+// WithBatchSize sets the number of records moved through the pipeline
+// together. The rows of a batch are written with a single Load.
+func WithBatchSize(n int) ETLProcessorOption {
+	return func(s *settings) {
+		if n > 0 {
+			s.batchSize = n
+		}
+	}
+}
+
+// WithBatchTimeout sets how long a partial batch waits for more records
+// before it is sent on, so a slow input still makes progress.
+func WithBatchTimeout(d time.Duration) ETLProcessorOption {
+	return func(s *settings) {
+		if d > 0 {
+			s.batchTimeout = d
+		}
+	}
+}
+
+// WithOrderedOutput loads rows in input order while still transforming
+// records concurrently. At most window records, rounded down to whole
+// batches but at least one, are in flight at once, so a slow record holds
+// back a bounded number of rows.
+func WithOrderedOutput(window int) ETLProcessorOption {
+	return func(s *settings) {
+		s.ordered = true
+		if window > 0 {
+			s.reorderWindow = window
+		}
+	}
+}
+
+// WithErrorPolicy sets how failed records are handled, defaults to failing
+// the run on the first error.
+func WithErrorPolicy(policy ErrorPolicy) ETLProcessorOption {
+	return func(s *settings) {
+		s.errorPolicy = policy
+	}
+}
+
+// WithByteCounter reports the bytes read from c, such as a counting reader
+// wrapping the input, in the run report.
+func WithByteCounter(c ByteCounter) ETLProcessorOption {
+	return func(s *settings) {
+		s.byteCounter = c
+	}
+}
+
+// WithInstrumentation reports live measurements of the run to instr. It
+// can be given more than once, every instrumentation receives each
+// measurement.
+func WithInstrumentation(instr Instrumentation) ETLProcessorOption {
+	return func(s *settings) {
+		if instr == nil {
+			return
+		}
+
+		switch cur := s.instr.(type) {
+		case nopInstrumentation:
+			s.instr = instr
+		case multiInstrumentation:
+			s.instr = append(cur, instr)
+		default:
+			s.instr = multiInstrumentation{cur, instr}
+		}
+	}
+}
+
+func NewETLProcessor(e Extractor, t Transformer, l Loader, si StreamIterator, log *zap.Logger, opts ...ETLProcessorOption) *ETLProcessor {
+	return NewProcessor(e, t, l, si, log, opts...)
 }
 
-func NewETLProcessor(e Extractor, t Transformer, l Loader, si StreamIterator, log *zap.Logger) *ETLProcessor {
-	return &ETLProcessor{
+// NewProcessor builds a processor of records of type In, which are
+// extracted into Ext and transformed into rows of type Out.
+func NewProcessor[In, Ext, Out any](e ExtractorOf[In, Ext], t TransformerOf[Ext, Out], l LoaderOf[Out], si StreamIteratorOf[In], log *zap.Logger, opts ...ETLProcessorOption) *Processor[In, Ext, Out] {
+	p := &Processor[In, Ext, Out]{
+		settings: settings{
+			workers:         runtime.GOMAXPROCS(0),
+			batchSize:       defaultBatchSize,
+			batchTimeout:    defaultBatchTimeout,
+			reorderWindow:   defaultReorderWindow,
+			errorPolicy:     ErrorPolicy{Mode: ErrorModeFail},
+			instr:           nopInstrumentation{},
+			tracer:          newNoopTracer(),
+			recordSpanEvery: 1,
+		},
 		extractor:      e,
 		transformer:    t,
 		loader:         l,
 		streamIterator: si,
 		logger:         log,
 	}
+
+	for _, opt := range opts {
+		opt(&p.settings)
+	}
+
+	return p
+}
+
+// WithRejecter hands every failed record to r, whether it fails the run or
+// is skipped.
+func (e *Processor[In, Ext, Out]) WithRejecter(r RejecterOf[In]) *Processor[In, Ext, Out] {
+	e.rejecter = r
+	return e
+}
+
+// record is an input record tagged with its position in the stream. A
+// record that failed to be read and was skipped still holds its place in
+// the sequence.
+type record[In any] struct {
+	seq    uint64
+	data   In
+	failed bool
+}
+
+// result is the transformed rows of a record, which keeps the raw record
+// for rejecting it should loading fail.
+type result[In, Out any] struct {
+	record[In]
+	rows []Out
+}
+
+// batch is a run of consecutive records, numbered in the order it was
+// formed.
+type batch[In any] struct {
+	seq     uint64
+	records []record[In]
 }
 
-// Process streams file and fans out to the ETL pipelines.
-func (e *ETLProcessor) Process(ctx context.Context, output io.Writer, fields []string) error {
-	// write the header first
+// batchResult is the results of a batch, one per record.
+type batchResult[In, Out any] struct {
+	seq     uint64
+	results []result[In, Out]
+}
+
+// failFunc reports a failed record and returns true when it is skipped and
+// the run carries on.
+type failFunc[In any] func(rec record[In], stage Stage, err error) bool
+
+// Process streams the input through a fixed pool of workers in batches. The
+// reader, the batcher, the workers and the loader are connected by bounded
+// channels, so the reader blocks once the workers and loader fall behind.
+// Every record read is fed to each branch, which has its own batcher,
+// workers and loader, so a branch only holds up the reader once its own
+// queue is full.
+//
+// Failed records are handled according to the error policy. A fatal
+// failure cancels the run, records already being processed are finished,
+// and every fatal error is returned joined as RecordErrors in input order.
+// The run report is returned either way.
+func (e *Processor[In, Ext, Out]) Process(ctx context.Context, output io.Writer, fields []string) (*RunReport, error) {
+	ctx, span := e.tracer.Start(ctx, "etl.run", trace.WithAttributes(
+		attribute.StringSlice("etl.fields", fields),
+		attribute.Int("etl.workers", e.workers),
+		attribute.Int("etl.batch_size", e.batchSize),
+		attribute.Bool("etl.ordered", e.ordered),
+		attribute.Int("etl.branches", len(e.extraBranches)),
+	))
+
+	e.onStart(ctx, fields)
+
+	report, err := e.process(ctx, output, fields)
+
+	e.onFinish(ctx, report, err)
+
+	span.SetAttributes(
+		attribute.Int64("etl.records.read", report.Read),
+		attribute.Int64("etl.records.skipped", report.Skipped),
+		attribute.Int64("etl.records.failed", report.Failed),
+		attribute.Int64("etl.rows.written", report.RowsWritten),
+		attribute.Int64("etl.bytes.read", report.BytesRead),
+	)
+	endSpan(span, err)
+
+	return report, err
+}
+
+func (e *Processor[In, Ext, Out]) process(ctx context.Context, output io.Writer, fields []string) (*RunReport, error) {
+	report := &RunReport{
+		StartedAt:     time.Now(),
+		ErrorsByStage: make(map[Stage]int64),
+		ErrorsByType:  make(map[string]int64),
+	}
+
+	// the processor's own pipeline is the first branch
+	branches := []*branch[In, Ext, Out]{e.newBranch(BranchOf[In, Ext, Out]{
+		Extractor:   e.extractor,
+		Transformer: e.transformer,
+		Loader:      e.loader,
+		Fields:      fields,
+		Output:      output,
+	})}
+	defer func() {
+		e.finishReport(report, branches)
+	}()
+
+	if err := validateBranches(e.extraBranches); err != nil {
+		e.logger.Error("invalid branches", zap.Error(err))
+		return report, err
+	}
+
+	for _, b := range e.extraBranches {
+		branches = append(branches, e.newBranch(b))
+	}
+
+	// write the headers first
 	e.logger.Debug("loading headers")
-	e.loader.Load(ctx, [][]string{fields}, output)
+	for _, b := range branches {
+		if err := b.loadHeader(b.context(ctx)); err != nil {
+			e.logger.Error("failed to load headers", zap.String("branch", b.name), zap.Error(err))
+			re := &RecordError{Index: -1, Stage: StageHeader, Branch: b.name, Err: err}
+			e.runFailed(ctx, report, re)
+			return report, re
+		}
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	collector := &errorCollector{policy: e.errorPolicy, report: report, instr: e.instr, cancel: cancel, logger: e.logger}
 
-	errorc := make(chan error)
+	// with several branches a record is rejected once they are all done
+	// with it, read failures happen before the branches so are not held
+	rejects := newRejectBook[In](len(branches))
+	failIn := func(branch string) failFunc[In] {
+		return func(rec record[In], stage Stage, err error) bool {
+			if runCtx.Err() != nil && errors.Is(err, context.Canceled) {
+				// a consequence of the cancellation, not a failure of its own
+				return false
+			}
+
+			re := &RecordError{Index: int64(rec.seq), Stage: stage, Branch: branch, Err: err}
+			e.onError(ctx, re)
+			if stage == StageRead || len(branches) == 1 {
+				e.reject(ctx, collector, re, rec.data)
+			} else {
+				rejects.fail(rec, re)
+			}
+
+			return collector.add(re)
+		}
+	}
+	settle := func(br batchResult[In, Out]) {
+		if len(branches) == 1 {
+			return
+		}
+
+		for _, res := range br.results {
+			if re, data, ok := rejects.settle(res.record); ok {
+				e.reject(ctx, collector, re, data)
+			}
+		}
+	}
+
+	queues := make([]chan record[In], len(branches))
+	for i, b := range branches {
+		queues[i] = make(chan record[In], b.buffer)
+	}
+
+	var read int64
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		defer func() {
+			for _, q := range queues {
+				close(q)
+			}
+		}()
+		read = e.read(runCtx, queues, failIn(""))
+	}()
 
 	var wg sync.WaitGroup
+	for i, b := range branches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.runBranch(b.context(runCtx), b, queues[i], failIn(b.name), settle)
+		}()
+	}
+	wg.Wait()
 
-	for e.streamIterator.HasNext() {
-		select {
-		case <-ctx.Done():
-			e.logger.Info("recieved done, shutting down")
-			return nil
-		case err := <-errorc:
-			e.logger.Error("received error", zap.String("err", err.Error()))
+	// the reader stops at its next record once the run is cancelled
+	<-readDone
+
+	for _, p := range rejects.drain() {
+		re, data, _ := p.rejected()
+		e.reject(ctx, collector, re, data)
+	}
+
+	report.Read = read
+	report.Skipped = collector.skipped
+
+	if runCtx.Err() == nil {
+		collector.finish(read)
+	}
+
+	if err := collector.err(); err != nil {
+		e.logger.Error("received error", zap.String("err", err.Error()))
+		return report, err
+	}
+
+	if ctx.Err() != nil {
+		e.logger.Info("recieved done, shutting down")
+		return report, nil
+	}
+
+	for _, b := range branches {
+		if err := e.flush(b.context(ctx), b); err != nil {
+			e.logger.Error("failed to flush", zap.String("branch", b.name), zap.Error(err))
+			re := &RecordError{Index: -1, Stage: StageFlush, Branch: b.name, Err: err}
+			e.runFailed(ctx, report, re)
+			return report, re
+		}
+	}
+
+	e.logger.Info("ETL process finished")
+
+	return report, nil
+}
+
+// runBranch batches, transforms and loads the records queued for a branch
+// until the queue is closed or the run is cancelled. Each batch is handed to
+// settle once the branch is done with its records.
+func (e *Processor[In, Ext, Out]) runBranch(
+	ctx context.Context,
+	b *branch[In, Ext, Out],
+	records <-chan record[In],
+	fail failFunc[In],
+	settle func(batchResult[In, Out]),
+) {
+	load := func(br batchResult[In, Out]) {
+		e.loadBatch(ctx, b, br, fail)
+		settle(br)
+	}
+
+	var reorder *reorderBuffer[In, Out]
+	if e.ordered {
+		// the window is in records, the buffer orders whole batches
+		window := max(1, e.reorderWindow/e.batchSize)
+		reorder = newReorderBuffer(window, load)
+	}
+
+	batches := make(chan batch[In], e.workers)
+	results := make(chan batchResult[In, Out], e.workers)
+
+	go func() {
+		defer close(batches)
+		e.batch(ctx, records, batches, reorder)
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < e.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.work(ctx, b, batches, results, fail)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	e.logger.Debug("loading", zap.String("branch", b.name))
+	for br := range results {
+		if ctx.Err() != nil {
+			// keep draining so the workers can exit
+			continue
+		}
+
+		if reorder != nil {
+			reorder.deliver(br)
+		} else {
+			load(br)
+		}
+	}
+}
+
+// flush loads the rows a branch's transformer held back, if it holds any.
+func (e *Processor[In, Ext, Out]) flush(ctx context.Context, b *branch[In, Ext, Out]) error {
+	if b.flusher == nil {
+		return nil
+	}
+
+	e.logger.Debug("flushing held back rows", zap.String("branch", b.name))
+	ctx, span := e.tracer.Start(ctx, "etl.flush", trace.WithAttributes(branchAttrs(b)...))
+
+	var flushed int
+	err := b.flusher.Flush(ctx, func(rows []Out) error {
+		b.counters.rowsProduced.Add(int64(len(rows)))
+		if err := b.loader.Load(ctx, rows, b.output); err != nil {
 			return err
+		}
+		flushed += len(rows)
+		b.counters.rowsWritten.Add(int64(len(rows)))
+		e.instr.RowsWritten(len(rows))
+		return nil
+	})
+
+	span.SetAttributes(attrRows.Int(flushed))
+	endSpan(span, err)
+
+	return err
+}
+
+// runFailed records a failure that is not tied to a record and always
+// fails the run.
+func (e *Processor[In, Ext, Out]) runFailed(ctx context.Context, report *RunReport, re *RecordError) {
+	report.countError(re)
+	e.instr.StageError(re.Stage)
+	e.onError(ctx, re)
+}
+
+// finishReport fills in the counts and timings once the run is over and
+// logs the report.
+func (e *Processor[In, Ext, Out]) finishReport(report *RunReport, branches []*branch[In, Ext, Out]) {
+	report.FinishedAt = time.Now()
+	report.DurationSeconds = report.FinishedAt.Sub(report.StartedAt).Seconds()
+	if report.DurationSeconds > 0 {
+		report.RecordsPerSecond = float64(report.Read) / report.DurationSeconds
+	}
+
+	if e.byteCounter != nil {
+		report.BytesRead = e.byteCounter.BytesRead()
+	}
+
+	counters := branches[0].counters
+	report.Extracted = counters.extracted.Load()
+	report.Transformed = counters.transformed.Load()
+	report.RowsProduced = counters.rowsProduced.Load()
+	report.RowsWritten = counters.rowsWritten.Load()
+
+	for _, b := range branches[1:] {
+		if report.Branches == nil {
+			report.Branches = make(map[string]*BranchReport, len(branches)-1)
+		}
+
+		report.Branches[b.name] = &BranchReport{
+			Extracted:    b.counters.extracted.Load(),
+			Transformed:  b.counters.transformed.Load(),
+			RowsProduced: b.counters.rowsProduced.Load(),
+			RowsWritten:  b.counters.rowsWritten.Load(),
+		}
+	}
+
+	e.logger.Info("run report", zap.Object("report", report))
+}
+
+// read feeds records from the stream iterator to the queue of every branch
+// until it is exhausted or the run is cancelled, returning the number of
+// records read.
+func (e *Processor[In, Ext, Out]) read(ctx context.Context, queues []chan record[In], fail failFunc[In]) int64 {
+	var seq uint64
+
+	start := time.Now()
+	for obj, err := range Records(ctx, e.streamIterator) {
+		if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			return int64(seq)
+		}
+
+		e.instr.StageDuration(StageRead, time.Since(start))
+		e.instr.RecordsRead(1)
+
+		rec := record[In]{seq: seq}
+		switch {
+		case err != nil:
+			e.logger.Error("failed to read", zap.Error(err))
+			var rre RawRecordErrorOf[In]
+			if errors.As(err, &rre) {
+				rec.data = rre.RawRecord()
+			}
+			if !fail(rec, StageRead, err) {
+				return int64(seq)
+			}
+			rec.failed = true
 		default:
-			obj, err := e.streamIterator.Next()
-			if err != nil {
-				if errors.Is(err, Done) {
-					e.logger.Debug("done reading")
-					break
-				} else {
-					return err
-				}
+			rec.data = obj
+		}
+
+		for _, q := range queues {
+			select {
+			case q <- rec:
+			case <-ctx.Done():
+				return int64(seq)
 			}
-			wg.Add(1)
-			go func(errCh chan error, c context.Context, data map[string]interface{}, fds []string, wo io.Writer) {
-				err := e.runPipeline(c, data, fds, wo)
-				if err != nil {
-					errCh <- err
-					return
-				}
-				wg.Done()
-			}(errorc, ctx, obj, fields, output)
 		}
+
+		seq++
+		start = time.Now()
 	}
 
-	e.logger.Debug("waiting")
-	wg.Wait()
+	e.logger.Debug("done reading")
 
-	e.logger.Info("ETL process finished")
+	return int64(seq)
+}
+
+// batch groups records into batches of up to batchSize, sending a partial
+// batch once batchTimeout has passed since its first record.
+func (e *Processor[In, Ext, Out]) batch(ctx context.Context, records <-chan record[In], batches chan<- batch[In], reorder *reorderBuffer[In, Out]) {
+	var seq uint64
+	var cur []record[In]
+	var timer *time.Timer
+	var timeout <-chan time.Time
+
+	send := func() bool {
+		if timer != nil {
+			timer.Stop()
+			timer, timeout = nil, nil
+		}
+
+		if len(cur) == 0 {
+			return true
+		}
+
+		if reorder != nil {
+			if err := reorder.acquire(ctx); err != nil {
+				return false
+			}
+		}
 
-	return nil
+		select {
+		case batches <- batch[In]{seq: seq, records: cur}:
+		case <-ctx.Done():
+			return false
+		}
+
+		seq++
+		cur = make([]record[In], 0, e.batchSize)
+
+		return true
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timeout:
+			e.logger.Debug("batch timed out", zap.Int("records", len(cur)))
+			if !send() {
+				return
+			}
+		case rec, ok := <-records:
+			if !ok {
+				send()
+				return
+			}
+
+			cur = append(cur, rec)
+			if timer == nil {
+				timer = time.NewTimer(e.batchTimeout)
+				timeout = timer.C
+			}
+
+			if len(cur) >= e.batchSize && !send() {
+				return
+			}
+		}
+	}
 }
 
-func (e *ETLProcessor) runPipeline(ctx context.Context, raw map[string]interface{}, fields []string, output io.Writer) error {
-	extract, err := e.extractor.Extract(ctx, raw, fields)
+// work extracts and transforms batches until the batches channel is closed
+// or the run is cancelled. Skipped records keep an empty result so the
+// batch still accounts for them.
+func (e *Processor[In, Ext, Out]) work(ctx context.Context, b *branch[In, Ext, Out], batches <-chan batch[In], results chan<- batchResult[In, Out], fail failFunc[In]) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case bt, ok := <-batches:
+			if !ok {
+				return
+			}
+
+			e.instr.WorkersBusy(1)
+
+			br := batchResult[In, Out]{seq: bt.seq, results: make([]result[In, Out], 0, len(bt.records))}
+
+			for _, rec := range bt.records {
+				res := result[In, Out]{record: rec}
+
+				if !rec.failed {
+					rows, stage, err := e.extractTransform(ctx, b, rec)
+					if stage != StageExtract {
+						b.counters.extracted.Add(1)
+					}
+					if err != nil && !fail(rec, stage, err) {
+						e.instr.WorkersBusy(-1)
+						return
+					}
+					if err == nil {
+						b.counters.transformed.Add(1)
+						b.counters.rowsProduced.Add(int64(len(rows)))
+						e.onRecord(ctx, rec, rows)
+					}
+					res.rows = rows
+				}
+
+				br.results = append(br.results, res)
+			}
+
+			e.instr.WorkersBusy(-1)
+
+			select {
+			case results <- br:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// loadBatch writes the rows of every record in a batch with a single Load.
+// When it fails, each record with rows is reported as failed.
+func (e *Processor[In, Ext, Out]) loadBatch(ctx context.Context, b *branch[In, Ext, Out], br batchResult[In, Out], fail failFunc[In]) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	var rows []Out
+	var loaded int
+	for _, res := range br.results {
+		if len(res.rows) > 0 {
+			rows = append(rows, res.rows...)
+			loaded++
+		}
+	}
+
+	if len(rows) == 0 {
+		return
+	}
+
+	e.logger.Debug("transformed, loading...", zap.String("branch", b.name), zap.Int("records", len(br.results)), zap.Int("rows", len(rows)))
+
+	loadCtx, span := e.tracer.Start(ctx, "etl.load", trace.WithAttributes(branchAttrs(b,
+		attrBatchSeq.Int64(int64(br.seq)),
+		attrBatchRecords.Int(len(br.results)),
+		attrRecordIndex.Int64(int64(br.results[0].seq)),
+		attrRows.Int(len(rows)),
+	)...))
+
+	start := time.Now()
+	err := b.loader.Load(loadCtx, rows, b.output)
+	e.instr.StageDuration(StageLoad, time.Since(start))
+	endSpan(span, err)
+
 	if err != nil {
-		e.logger.Error("failed to extract", zap.Error(err))
-		return err
+		e.logger.Error("failed to load", zap.String("branch", b.name), zap.Error(err))
+		for _, res := range br.results {
+			if len(res.rows) > 0 && !fail(res.record, StageLoad, err) {
+				return
+			}
+		}
+		return
 	}
 
-	e.logger.Debug("extracted, transforming...")
-	transform, err := e.transformer.Transform(ctx, extract, fields)
+	b.counters.rowsWritten.Add(int64(len(rows)))
+	e.instr.RecordsWritten(loaded)
+	e.instr.RowsWritten(len(rows))
+}
+
+// reject hands a failed record to the rejecter. Losing a rejected record
+// would defeat the dead-letter output, so failing to reject fails the run.
+func (e *Processor[In, Ext, Out]) reject(ctx context.Context, collector *errorCollector, re *RecordError, data In) {
+	if e.rejecter == nil {
+		return
+	}
+
+	if err := e.rejecter.Reject(ctx, re, data); err != nil {
+		e.logger.Error("failed to reject record", zap.Int64("index", re.Index), zap.Error(err))
+		rejectErr := &RecordError{Index: -1, Stage: StageReject, Err: err}
+		e.onError(ctx, rejectErr)
+		collector.add(rejectErr)
+	}
+}
+
+// extractTransform runs a record through the extractor and transformer of
+// a branch, returning the stage that failed alongside any error.
+func (e *Processor[In, Ext, Out]) extractTransform(ctx context.Context, b *branch[In, Ext, Out], rec record[In]) ([]Out, Stage, error) {
+	extractCtx, span := e.startRecordSpan(ctx, "etl.extract", b, rec)
+	start := time.Now()
+	extract, err := b.extractor.Extract(extractCtx, rec.data, b.fields)
+	e.instr.StageDuration(StageExtract, time.Since(start))
+	endSpan(span, err)
 	if err != nil {
-		e.logger.Error("failed to transform", zap.Error(err))
-		return err
+		e.logger.Error("failed to extract", zap.String("branch", b.name), zap.Error(err))
+		return nil, StageExtract, err
 	}
 
-	e.logger.Debug("transformed, loading...")
-	err = e.loader.Load(ctx, transform, output)
+	e.logger.Debug("extracted, transforming...")
+	transformCtx, span := e.startRecordSpan(ctx, "etl.transform", b, rec)
+	start = time.Now()
+	transform, err := b.transformer.Transform(transformCtx, extract, b.fields)
+	e.instr.StageDuration(StageTransform, time.Since(start))
+	span.SetAttributes(attrRows.Int(len(transform)))
+	endSpan(span, err)
 	if err != nil {
-		e.logger.Error("failed to load", zap.Error(err))
-		return err
+		e.logger.Error("failed to transform", zap.String("branch", b.name), zap.Error(err))
+		return nil, StageTransform, err
 	}
 
-	return nil
+	return transform, "", nil
 }
@@ -51,6 +51,6 @@ func TestProcessWithLargeFile(t *testing.T) {
 	defer testFile.Close()
 
 	fmt.Println("running the etl process...")
-	err = processor.Process(context.TODO(), testFile, testFields)
+	_, err = processor.Process(context.TODO(), testFile, testFields)
 	assert.NoError(t, err)
 }
@@ -3,11 +3,23 @@
 package etl_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"math/rand"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ralucas/centipede/internal/extractor"
 	"github.com/ralucas/centipede/internal/loader"
@@ -17,7 +29,13 @@ import (
 	"github.com/ralucas/centipede/test/fixtures"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 func TestProcess(t *testing.T) {
@@ -70,7 +88,7 @@ func TestProcess(t *testing.T) {
 			require.NoError(t, err)
 			defer testFile.Close()
 
-			err = processor.Process(context.TODO(), testFile, testFields)
+			_, err = processor.Process(context.TODO(), testFile, testFields)
 			if test.err == nil {
 				assert.NoError(t, err)
 			} else {
@@ -79,3 +97,1263 @@ func TestProcess(t *testing.T) {
 		})
 	}
 }
+
+// jitterTransformer delays each record by a random amount so that records
+// finish transforming out of order.
+type jitterTransformer struct {
+	next *transformer.RowTransformer
+}
+
+func (j jitterTransformer) Transform(ctx context.Context, data map[string]interface{}, fields []string) ([][]string, error) {
+	time.Sleep(time.Duration(rand.Intn(500)) * time.Microsecond)
+	return j.next.Transform(ctx, data, fields)
+}
+
+// newTestRecords returns a json array of n records, each identified and
+// titled by its position and holding the keywords a and b.
+func newTestRecords(t *testing.T, n int) []byte {
+	t.Helper()
+
+	records := make([]map[string]interface{}, n)
+	for i := range records {
+		records[i] = map[string]interface{}{
+			"identifier": strconv.Itoa(i),
+			"title":      "title " + strconv.Itoa(i),
+			"keyword":    []interface{}{"a", "b"},
+		}
+	}
+
+	input, err := json.Marshal(records)
+	require.NoError(t, err)
+
+	return input
+}
+
+// testStages replaces stages of a test processor. Its zero value reads the
+// input with the json iterator, extracts with the map extractor, builds rows
+// with the row transformer and loads them with the csv loader.
+type testStages struct {
+	si etl.StreamIterator
+	ex etl.Extractor
+	tf etl.Transformer
+	ld etl.Loader
+}
+
+// newTestProcessor builds a processor of input, or of stages.si when set.
+func newTestProcessor(t *testing.T, input []byte, stages testStages, opts ...etl.ETLProcessorOption) *etl.ETLProcessor {
+	t.Helper()
+
+	log := zap.NewNop()
+
+	if stages.si == nil {
+		stages.si = streamreader.NewJSONStreamIterator(bytes.NewReader(input), log)
+	}
+	if stages.ex == nil {
+		stages.ex = extractor.NewMapExtractor(log)
+	}
+	if stages.tf == nil {
+		stages.tf = transformer.NewRowTransformer(log)
+	}
+	if stages.ld == nil {
+		stages.ld = loader.NewCSVLoader(log)
+	}
+
+	return etl.NewETLProcessor(stages.ex, stages.tf, stages.ld, stages.si, log, opts...)
+}
+
+func TestProcessOrdered(t *testing.T) {
+	const n = 500
+
+	input := newTestRecords(t, n)
+
+	for _, window := range []int{1, 8, 1000} {
+		t.Run(fmt.Sprintf("window %d", window), func(t *testing.T) {
+			processor := newTestProcessor(t, input,
+				testStages{tf: jitterTransformer{next: transformer.NewRowTransformer(zap.NewNop())}},
+				etl.WithOrderedOutput(window),
+			)
+
+			var out bytes.Buffer
+			_, err := processor.Process(context.TODO(), &out, []string{"identifier"})
+			require.NoError(t, err)
+
+			rows, err := csv.NewReader(&out).ReadAll()
+			require.NoError(t, err)
+			require.Len(t, rows, n+1)
+
+			assert.Equal(t, []string{"identifier"}, rows[0])
+			for i, row := range rows[1:] {
+				assert.Equal(t, []string{strconv.Itoa(i)}, row)
+			}
+		})
+	}
+}
+
+// gaugeTransformer records the highest number of concurrent Transform calls.
+type gaugeTransformer struct {
+	next    *transformer.RowTransformer
+	current atomic.Int32
+	peak    atomic.Int32
+}
+
+func (g *gaugeTransformer) Transform(ctx context.Context, data map[string]interface{}, fields []string) ([][]string, error) {
+	n := g.current.Add(1)
+	defer g.current.Add(-1)
+
+	for {
+		peak := g.peak.Load()
+		if n <= peak || g.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+
+	time.Sleep(100 * time.Microsecond)
+
+	return g.next.Transform(ctx, data, fields)
+}
+
+func TestProcessWorkers(t *testing.T) {
+	const n = 200
+
+	input := newTestRecords(t, n)
+
+	for _, workers := range []int{1, 4} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			tf := &gaugeTransformer{next: transformer.NewRowTransformer(zap.NewNop())}
+
+			processor := newTestProcessor(t, input, testStages{tf: tf}, etl.WithWorkers(workers))
+
+			var out bytes.Buffer
+			report, err := processor.Process(context.TODO(), &out, []string{"identifier"})
+			require.NoError(t, err)
+
+			rows, err := csv.NewReader(&out).ReadAll()
+			require.NoError(t, err)
+			require.Len(t, rows, n+1)
+			assert.Equal(t, []string{"identifier"}, rows[0])
+
+			expect := make([][]string, n)
+			for i := range expect {
+				expect[i] = []string{strconv.Itoa(i)}
+			}
+			// a single worker keeps the input order, several may not
+			if workers == 1 {
+				assert.Equal(t, expect, rows[1:])
+			} else {
+				assert.ElementsMatch(t, expect, rows[1:])
+			}
+
+			assert.Equal(t, int64(n), report.RowsWritten)
+			assert.LessOrEqual(t, tf.peak.Load(), int32(workers))
+		})
+	}
+}
+
+// failingTransformer fails on the records whose identifier is in fail.
+type failingTransformer struct {
+	next *transformer.RowTransformer
+	fail map[string]bool
+}
+
+// failOn fails the records with the given identifiers and builds rows of
+// the others.
+func failOn(ids ...string) failingTransformer {
+	f := failingTransformer{
+		next: transformer.NewRowTransformer(zap.NewNop()),
+		fail: make(map[string]bool, len(ids)),
+	}
+	for _, id := range ids {
+		f.fail[id] = true
+	}
+
+	return f
+}
+
+func (f failingTransformer) Transform(ctx context.Context, data map[string]interface{}, fields []string) ([][]string, error) {
+	if f.fail[data["identifier"].(string)] {
+		return nil, errTest
+	}
+
+	return f.next.Transform(ctx, data, fields)
+}
+
+// failingLoader fails every load after the first after loads.
+type failingLoader struct {
+	after int
+	loads atomic.Int32
+}
+
+func (f *failingLoader) Load(_ context.Context, _ [][]string, _ io.Writer) error {
+	if int(f.loads.Add(1)) > f.after {
+		return errTest
+	}
+
+	return nil
+}
+
+var errTest = errors.New("test")
+
+// anyRecord expects an error for some record without knowing which.
+const anyRecord = -2
+
+func TestProcessErrors(t *testing.T) {
+	const n = 100
+
+	input := newTestRecords(t, n)
+
+	all := make([]string, n)
+	for i := range all {
+		all[i] = strconv.Itoa(i)
+	}
+
+	tests := []struct {
+		name   string
+		stages testStages
+		opts   []etl.ETLProcessorOption
+		stage  etl.Stage
+		index  int64
+	}{
+		{
+			name:   "transform failure",
+			stages: testStages{tf: failOn("42")},
+			opts:   []etl.ETLProcessorOption{etl.WithWorkers(4)},
+			stage:  etl.StageTransform,
+			index:  42,
+		},
+		{
+			name:   "every record fails",
+			stages: testStages{tf: failOn(all...)},
+			opts:   []etl.ETLProcessorOption{etl.WithWorkers(8)},
+			stage:  etl.StageTransform,
+			index:  anyRecord,
+		},
+		{
+			name:   "header failure",
+			stages: testStages{ld: &failingLoader{}},
+			stage:  etl.StageHeader,
+			index:  -1,
+		},
+		{
+			name:   "load failure",
+			stages: testStages{ld: &failingLoader{after: 1}},
+			opts:   []etl.ETLProcessorOption{etl.WithWorkers(4)},
+			stage:  etl.StageLoad,
+			index:  anyRecord,
+		},
+		{
+			name:   "ordered load failure",
+			stages: testStages{ld: &failingLoader{after: 11}},
+			opts:   []etl.ETLProcessorOption{etl.WithWorkers(4), etl.WithOrderedOutput(8), etl.WithBatchSize(1)},
+			stage:  etl.StageLoad,
+			index:  10,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			processor := newTestProcessor(t, input, test.stages, test.opts...)
+
+			done := make(chan error, 1)
+			go func() {
+				_, err := processor.Process(context.TODO(), io.Discard, []string{"identifier"})
+				done <- err
+			}()
+
+			var err error
+			select {
+			case err = <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatal("process did not return")
+			}
+
+			require.ErrorIs(t, err, errTest)
+
+			var re *etl.RecordError
+			require.ErrorAs(t, err, &re)
+			assert.Equal(t, test.stage, re.Stage)
+			if test.index != anyRecord {
+				assert.Equal(t, test.index, re.Index)
+			} else {
+				assert.GreaterOrEqual(t, re.Index, int64(0))
+			}
+		})
+	}
+}
+
+func TestProcessErrorPolicy(t *testing.T) {
+	const n = 100
+
+	input := newTestRecords(t, n)
+
+	failing := []string{"3", "50", "51", "98", "99"}
+
+	var expect [][]string
+	for i := 0; i < n; i++ {
+		if id := strconv.Itoa(i); !slices.Contains(failing, id) {
+			expect = append(expect, []string{id})
+		}
+	}
+
+	tests := []struct {
+		name   string
+		policy etl.ErrorPolicy
+		err    error
+	}{
+		{name: "skip", policy: etl.ErrorPolicy{Mode: etl.ErrorModeSkip}},
+		{name: "within max errors", policy: etl.ErrorPolicy{Mode: etl.ErrorModeThreshold, MaxErrors: 5}},
+		{name: "exceeds max errors", policy: etl.ErrorPolicy{Mode: etl.ErrorModeThreshold, MaxErrors: 4}, err: etl.ErrErrorBudgetExceeded},
+		{name: "within max error rate", policy: etl.ErrorPolicy{Mode: etl.ErrorModeThreshold, MaxErrorRate: 0.05}},
+		{name: "exceeds max error rate", policy: etl.ErrorPolicy{Mode: etl.ErrorModeThreshold, MaxErrorRate: 0.01}, err: etl.ErrErrorBudgetExceeded},
+		{name: "fail", policy: etl.ErrorPolicy{Mode: etl.ErrorModeFail}, err: errTest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			processor := newTestProcessor(t, input,
+				testStages{tf: failOn(failing...)},
+				etl.WithWorkers(4),
+				etl.WithErrorPolicy(test.policy),
+			)
+
+			var out bytes.Buffer
+			_, err := processor.Process(context.TODO(), &out, []string{"identifier"})
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+
+			rows, err := csv.NewReader(&out).ReadAll()
+			require.NoError(t, err)
+			assert.ElementsMatch(t, expect, rows[1:])
+		})
+	}
+
+	t.Run("skips invalid datasets", func(t *testing.T) {
+		fp, err := fixtures.NewTestFixture().DatasetFilePath("invalid_dataset_array.json")
+		require.NoError(t, err)
+
+		file, err := os.Open(fp)
+		require.NoError(t, err)
+		defer file.Close()
+
+		processor := newTestProcessor(t, nil,
+			testStages{si: streamreader.NewJSONStreamIterator(file, zap.NewNop(), streamreader.WithDatasetValidation())},
+			etl.WithErrorPolicy(etl.ErrorPolicy{Mode: etl.ErrorModeSkip}),
+		)
+
+		_, err = processor.Process(context.TODO(), io.Discard, []string{"identifier"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("fails on malformed json", func(t *testing.T) {
+		input := `[{"identifier":"a"},{bad},{"identifier":"c"},{"identifier":"d"}]`
+
+		for _, mode := range []etl.ErrorMode{etl.ErrorModeSkip, etl.ErrorModeFail} {
+			t.Run(string(mode), func(t *testing.T) {
+				processor := newTestProcessor(t, []byte(input), testStages{},
+					etl.WithErrorPolicy(etl.ErrorPolicy{Mode: mode}),
+				)
+
+				report, err := processor.Process(context.TODO(), io.Discard, []string{"identifier"})
+				assert.ErrorIs(t, err, etl.ErrStreamBroken)
+				assert.Zero(t, report.Skipped)
+			})
+		}
+	})
+}
+
+// recordingRejecter collects the indexes and stages of rejected records.
+type recordingRejecter struct {
+	mu      sync.Mutex
+	rejects map[int64]etl.Stage
+	records map[int64]map[string]interface{}
+	errs    []*etl.RecordError
+}
+
+func newRecordingRejecter() *recordingRejecter {
+	return &recordingRejecter{
+		rejects: make(map[int64]etl.Stage),
+		records: make(map[int64]map[string]interface{}),
+	}
+}
+
+func (r *recordingRejecter) Reject(_ context.Context, err *etl.RecordError, record map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rejects[err.Index] = err.Stage
+	r.records[err.Index] = record
+	r.errs = append(r.errs, err)
+
+	return nil
+}
+
+func TestProcessRejects(t *testing.T) {
+	log, err := zap.NewDevelopment()
+	require.NoError(t, err)
+
+	fp, err := fixtures.NewTestFixture().DatasetFilePath("invalid_dataset_array.json")
+	require.NoError(t, err)
+
+	file, err := os.Open(fp)
+	require.NoError(t, err)
+	defer file.Close()
+
+	rejecter := newRecordingRejecter()
+
+	processor := etl.NewETLProcessor(
+		extractor.NewMapExtractor(log),
+		transformer.NewRowTransformer(log),
+		loader.NewCSVLoader(log),
+		streamreader.NewJSONStreamIterator(file, log, streamreader.WithDatasetValidation()),
+		log,
+		etl.WithErrorPolicy(etl.ErrorPolicy{Mode: etl.ErrorModeSkip}),
+	).WithRejecter(rejecter)
+
+	_, err = processor.Process(context.TODO(), io.Discard, []string{"identifier"})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[int64]etl.Stage{0: etl.StageRead, 1: etl.StageRead}, rejecter.rejects)
+	for _, record := range rejecter.records {
+		assert.NotNil(t, record)
+	}
+
+	t.Run("once across branches", func(t *testing.T) {
+		input := newTestRecords(t, 50)
+
+		for _, mode := range []etl.ErrorMode{etl.ErrorModeSkip, etl.ErrorModeFail} {
+			t.Run(string(mode), func(t *testing.T) {
+				rejecter := newRecordingRejecter()
+
+				processor := newTestProcessor(t, input,
+					testStages{tf: failOn("3")},
+					etl.WithWorkers(4),
+					etl.WithBatchSize(4),
+					etl.WithErrorPolicy(etl.ErrorPolicy{Mode: mode}),
+				).WithRejecter(rejecter).WithBranch(etl.Branch{
+					Name:        "failing",
+					Extractor:   extractor.NewMapExtractor(zap.NewNop()),
+					Transformer: failOn("3", "5"),
+					Loader:      loader.NewCSVLoader(zap.NewNop()),
+					Fields:      []string{"identifier"},
+					Output:      io.Discard,
+				})
+
+				report, err := processor.Process(context.TODO(), io.Discard, []string{"identifier"})
+
+				counts := make(map[int64]int)
+				for _, re := range rejecter.errs {
+					counts[re.Index]++
+				}
+				assert.Equal(t, 1, counts[3])
+
+				if mode == etl.ErrorModeFail {
+					assert.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+
+				assert.Equal(t, map[int64]int{3: 1, 5: 1}, counts)
+				assert.Equal(t, int64(2), report.Failed)
+				assert.Equal(t, int64(2), report.Skipped)
+				assert.Equal(t, int64(3), report.ErrorsByStage[etl.StageTransform])
+
+				for _, re := range rejecter.errs {
+					if re.Index != 3 {
+						assert.Empty(t, re.Others)
+						continue
+					}
+
+					require.Len(t, re.Others, 1)
+					branches := []string{re.Branch, re.Others[0].Branch}
+					assert.ElementsMatch(t, []string{"", "failing"}, branches)
+				}
+			})
+		}
+	})
+}
+
+// countingLoader counts Load calls and the rows written.
+type countingLoader struct {
+	next  *loader.CSVLoader
+	loads atomic.Int32
+}
+
+func (c *countingLoader) Load(ctx context.Context, rows [][]string, w io.Writer) error {
+	c.loads.Add(1)
+	return c.next.Load(ctx, rows, w)
+}
+
+// slowIterator yields records with a delay, to exercise batch timeouts.
+type slowIterator struct {
+	n, i  int
+	delay time.Duration
+}
+
+func (s *slowIterator) HasNext() bool { return s.i < s.n }
+
+func (s *slowIterator) Next() (map[string]interface{}, error) {
+	time.Sleep(s.delay)
+	s.i++
+	return map[string]interface{}{"identifier": strconv.Itoa(s.i)}, nil
+}
+
+func TestProcessBatches(t *testing.T) {
+	const n = 250
+
+	input := newTestRecords(t, n)
+
+	for _, ordered := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ordered %t", ordered), func(t *testing.T) {
+			l := &countingLoader{next: loader.NewCSVLoader(zap.NewNop())}
+
+			opts := []etl.ETLProcessorOption{etl.WithWorkers(4), etl.WithBatchSize(100)}
+			if ordered {
+				opts = append(opts, etl.WithOrderedOutput(200))
+			}
+
+			processor := newTestProcessor(t, input, testStages{ld: l}, opts...)
+
+			_, err := processor.Process(context.TODO(), io.Discard, []string{"identifier"})
+			require.NoError(t, err)
+
+			// the header, then two full batches and a partial one
+			assert.Equal(t, int32(4), l.loads.Load())
+		})
+	}
+
+	t.Run("partial batches time out", func(t *testing.T) {
+		l := &countingLoader{next: loader.NewCSVLoader(zap.NewNop())}
+
+		processor := newTestProcessor(t, nil,
+			testStages{si: &slowIterator{n: 3, delay: 50 * time.Millisecond}, ld: l},
+			etl.WithBatchSize(100),
+			etl.WithBatchTimeout(10*time.Millisecond),
+		)
+
+		_, err := processor.Process(context.TODO(), io.Discard, []string{"identifier"})
+		require.NoError(t, err)
+
+		// the header, then each record on its own once the timeout passes
+		assert.Equal(t, int32(4), l.loads.Load())
+	})
+}
+
+func TestProcessReport(t *testing.T) {
+	const n = 100
+
+	input := newTestRecords(t, n)
+	counted := streamreader.NewCountingReader(bytes.NewReader(input))
+
+	processor := newTestProcessor(t, nil,
+		testStages{si: streamreader.NewJSONStreamIterator(counted, zap.NewNop()), tf: failOn("1", "2")},
+		etl.WithErrorPolicy(etl.ErrorPolicy{Mode: etl.ErrorModeSkip}),
+		etl.WithByteCounter(counted),
+	)
+
+	report, err := processor.Process(context.TODO(), io.Discard, []string{"identifier", "keyword"})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(len(input)), report.BytesRead)
+	assert.Equal(t, int64(n), report.Read)
+	assert.Equal(t, int64(n), report.Extracted)
+	assert.Equal(t, int64(n-2), report.Transformed)
+	assert.Equal(t, int64(2), report.Skipped)
+	assert.Equal(t, int64(2), report.Failed)
+	// each keyword explodes into its own row
+	assert.Equal(t, int64(2*(n-2)), report.RowsProduced)
+	assert.Equal(t, int64(2*(n-2)), report.RowsWritten)
+	assert.Equal(t, map[etl.Stage]int64{etl.StageTransform: 2}, report.ErrorsByStage)
+	assert.Equal(t, map[string]int64{"test": 2}, report.ErrorsByType)
+	assert.False(t, report.FinishedAt.Before(report.StartedAt))
+
+	t.Run("logged under its json keys", func(t *testing.T) {
+		data, err := json.Marshal(report)
+		require.NoError(t, err)
+
+		var fields map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &fields))
+
+		enc := zapcore.NewMapObjectEncoder()
+		require.NoError(t, report.MarshalLogObject(enc))
+
+		assert.ElementsMatch(t, slices.Collect(maps.Keys(fields)), slices.Collect(maps.Keys(enc.Fields)))
+		assert.Equal(t, map[string]interface{}{"test": int64(2)}, enc.Fields["errors_by_type"])
+	})
+
+	t.Run("returned on failure", func(t *testing.T) {
+		processor := newTestProcessor(t, input, testStages{ld: &failingLoader{}})
+
+		report, err := processor.Process(context.TODO(), io.Discard, []string{"identifier"})
+		assert.Error(t, err)
+		require.NotNil(t, report)
+		assert.Equal(t, map[etl.Stage]int64{etl.StageHeader: 1}, report.ErrorsByStage)
+	})
+}
+
+type countingInstrumentation struct {
+	mu             sync.Mutex
+	read           int
+	recordsWritten int
+	rowsWritten    int
+	busy           int
+	maxBusy        int
+	durations      map[etl.Stage]int
+	errors         map[etl.Stage]int
+}
+
+func (c *countingInstrumentation) RecordsRead(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.read += n
+}
+
+func (c *countingInstrumentation) RecordsWritten(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.recordsWritten += n
+}
+
+func (c *countingInstrumentation) RowsWritten(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rowsWritten += n
+}
+
+func (c *countingInstrumentation) StageDuration(stage etl.Stage, _ time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.durations[stage]++
+}
+
+func (c *countingInstrumentation) StageError(stage etl.Stage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errors[stage]++
+}
+
+func (c *countingInstrumentation) WorkersBusy(delta int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.busy += delta
+	c.maxBusy = max(c.maxBusy, c.busy)
+}
+
+func TestProcessInstrumentation(t *testing.T) {
+	const n = 100
+
+	input := newTestRecords(t, n)
+
+	instr := &countingInstrumentation{durations: make(map[etl.Stage]int), errors: make(map[etl.Stage]int)}
+	other := &countingInstrumentation{durations: make(map[etl.Stage]int), errors: make(map[etl.Stage]int)}
+
+	processor := newTestProcessor(t, input,
+		testStages{tf: failOn("1")},
+		etl.WithErrorPolicy(etl.ErrorPolicy{Mode: etl.ErrorModeSkip}),
+		etl.WithWorkers(4),
+		etl.WithBatchSize(10),
+		etl.WithInstrumentation(instr),
+		etl.WithInstrumentation(other),
+	)
+
+	_, err := processor.Process(context.TODO(), io.Discard, []string{"identifier", "keyword"})
+	require.NoError(t, err)
+
+	assert.Equal(t, n, instr.read)
+	assert.Equal(t, n-1, instr.recordsWritten)
+	assert.Equal(t, 2*(n-1), instr.rowsWritten)
+	assert.Equal(t, 0, instr.busy)
+	assert.LessOrEqual(t, instr.maxBusy, 4)
+	assert.Equal(t, n, instr.durations[etl.StageRead])
+	assert.Equal(t, n, instr.durations[etl.StageExtract])
+	assert.Equal(t, n, instr.durations[etl.StageTransform])
+	assert.Equal(t, n/10, instr.durations[etl.StageLoad])
+	assert.Equal(t, map[etl.Stage]int{etl.StageTransform: 1}, instr.errors)
+
+	// every instrumentation is given each measurement
+	assert.Equal(t, n, other.read)
+	assert.Equal(t, 2*(n-1), other.rowsWritten)
+	assert.Equal(t, instr.errors, other.errors)
+}
+
+// spanCheckingExtractor records whether the context it was handed carries
+// a span.
+type spanCheckingExtractor struct {
+	next   etl.Extractor
+	traced atomic.Int64
+}
+
+func (s *spanCheckingExtractor) Extract(ctx context.Context, data map[string]interface{}, fields []string) (map[string]interface{}, error) {
+	if trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		s.traced.Add(1)
+	}
+
+	return s.next.Extract(ctx, data, fields)
+}
+
+func TestProcessTracing(t *testing.T) {
+	const n = 100
+
+	input := newTestRecords(t, n)
+
+	tests := []struct {
+		name    string
+		rate    float64
+		records int
+	}{
+		{"every record", 1, n},
+		{"sampled", 0.1, n / 10},
+		{"no records", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+
+			ex := &spanCheckingExtractor{next: extractor.NewMapExtractor(zap.NewNop())}
+
+			processor := newTestProcessor(t, input,
+				testStages{ex: ex, tf: failOn("0")},
+				etl.WithErrorPolicy(etl.ErrorPolicy{Mode: etl.ErrorModeSkip}),
+				etl.WithWorkers(4),
+				etl.WithBatchSize(10),
+				etl.WithTracerProvider(tp),
+				etl.WithRecordSpanRate(tt.rate),
+			)
+
+			_, err := processor.Process(context.TODO(), io.Discard, []string{"identifier", "keyword"})
+			require.NoError(t, err)
+
+			byName := make(map[string][]sdktrace.ReadOnlySpan)
+			for _, s := range spans.Ended() {
+				byName[s.Name()] = append(byName[s.Name()], s)
+			}
+
+			require.Len(t, byName["etl.run"], 1)
+			run := byName["etl.run"][0]
+			assert.Contains(t, run.Attributes(), attribute.Int64("etl.records.read", n))
+
+			assert.Len(t, byName["etl.extract"], tt.records)
+			assert.Len(t, byName["etl.transform"], tt.records)
+			assert.Len(t, byName["etl.load"], n/10)
+			// the extractor is handed the record span, or the run span when
+			// the record is not sampled
+			assert.Equal(t, int64(n), ex.traced.Load())
+
+			for _, s := range spans.Ended() {
+				if s.Name() != "etl.run" {
+					assert.Equal(t, run.SpanContext().SpanID(), s.Parent().SpanID(), s.Name())
+				}
+			}
+
+			if tt.records > 0 {
+				// record 0 fails to transform and is always sampled
+				var failed int
+				for _, s := range byName["etl.transform"] {
+					if s.Status().Code == codes.Error {
+						failed++
+						assert.Contains(t, s.Attributes(), attribute.Int64("etl.record.index", 0))
+					}
+				}
+				assert.Equal(t, 1, failed)
+			}
+		})
+	}
+}
+
+// holdingTransformer holds every row back until it is flushed and renames
+// its columns.
+type holdingTransformer struct {
+	mu   sync.Mutex
+	rows [][]string
+}
+
+func (h *holdingTransformer) Transform(_ context.Context, data map[string]interface{}, fields []string) ([][]string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	row := make([]string, len(fields))
+	for i, f := range fields {
+		row[i] = fmt.Sprint(data[f])
+	}
+	h.rows = append(h.rows, row)
+
+	return nil, nil
+}
+
+func (h *holdingTransformer) Flush(_ context.Context, emit func([][]string) error) error {
+	return emit(h.rows)
+}
+
+func (h *holdingTransformer) Columns(fields []string) []string {
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = "held_" + f
+	}
+	return columns
+}
+
+func TestProcessMiddleware(t *testing.T) {
+	input := []byte(`[{"identifier": "1", "secret": "a"}, {"identifier": "2", "secret": "b"}]`)
+
+	var mu sync.Mutex
+	var calls []string
+	called := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, name)
+	}
+
+	named := func(name string) etl.ExtractorMiddleware {
+		return func(next etl.Extractor) etl.Extractor {
+			return etl.ExtractorFunc(func(ctx context.Context, data map[string]interface{}, fields []string) (map[string]interface{}, error) {
+				called(name)
+				return next.Extract(ctx, data, fields)
+			})
+		}
+	}
+
+	redact := func(next etl.Extractor) etl.Extractor {
+		return etl.ExtractorFunc(func(ctx context.Context, data map[string]interface{}, fields []string) (map[string]interface{}, error) {
+			called("redact")
+			out, err := next.Extract(ctx, data, fields)
+			if err == nil {
+				out["secret"] = "REDACTED"
+			}
+			return out, err
+		})
+	}
+
+	var transformed, loaded atomic.Int64
+
+	t.Run("wraps each stage in order", func(t *testing.T) {
+		calls = nil
+
+		var buf bytes.Buffer
+		processor := newTestProcessor(t, input, testStages{}, etl.WithWorkers(1)).
+			WithExtractorMiddleware(named("outer"), named("inner")).
+			WithExtractorMiddleware(redact).
+			WithTransformerMiddleware(func(next etl.Transformer) etl.Transformer {
+				return etl.TransformerFunc(func(ctx context.Context, data map[string]interface{}, fields []string) ([][]string, error) {
+					transformed.Add(1)
+					return next.Transform(ctx, data, fields)
+				})
+			}).
+			WithLoaderMiddleware(func(next etl.Loader) etl.Loader {
+				return etl.LoaderFunc(func(ctx context.Context, data [][]string, w io.Writer) error {
+					loaded.Add(1)
+					return next.Load(ctx, data, w)
+				})
+			})
+
+		_, err := processor.Process(context.TODO(), &buf, []string{"identifier", "secret"})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"outer", "inner", "redact", "outer", "inner", "redact"}, calls)
+		assert.Equal(t, "identifier,secret\n1,REDACTED\n2,REDACTED\n", buf.String())
+		assert.Equal(t, int64(2), transformed.Load())
+		// the header and a single batch
+		assert.Equal(t, int64(2), loaded.Load())
+	})
+
+	t.Run("keeps flusher and column mapper", func(t *testing.T) {
+		var buf bytes.Buffer
+		processor := newTestProcessor(t, input, testStages{tf: &holdingTransformer{}},
+			etl.WithWorkers(1),
+			etl.WithOrderedOutput(10),
+		).WithTransformerMiddleware(func(next etl.Transformer) etl.Transformer {
+			return etl.TransformerFunc(next.Transform)
+		})
+
+		_, err := processor.Process(context.TODO(), &buf, []string{"identifier"})
+		require.NoError(t, err)
+
+		assert.Equal(t, "held_identifier\n1\n2\n", buf.String())
+	})
+}
+
+func TestProcessHooks(t *testing.T) {
+	const n = 50
+
+	input := newTestRecords(t, n)
+
+	var mu sync.Mutex
+	var events []string
+	var indexes []int64
+	var errs []*etl.RecordError
+	var finished *etl.RunReport
+
+	hooks := etl.Hooks{
+		OnStart: func(_ context.Context, fields []string) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, "start "+strings.Join(fields, ","))
+		},
+		OnRecord: func(_ context.Context, index int64, rows [][]string) {
+			mu.Lock()
+			defer mu.Unlock()
+			indexes = append(indexes, index)
+			assert.Equal(t, [][]string{{strconv.FormatInt(index, 10)}}, rows)
+		},
+		OnError: func(_ context.Context, err *etl.RecordError) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+		OnFinish: func(_ context.Context, report *etl.RunReport, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, "finish")
+			finished = report
+			assert.NoError(t, err)
+		},
+	}
+
+	var second atomic.Int64
+
+	processor := newTestProcessor(t, input,
+		testStages{tf: failOn("7")},
+		etl.WithErrorPolicy(etl.ErrorPolicy{Mode: etl.ErrorModeSkip}),
+		etl.WithWorkers(4),
+	).WithHooks(hooks).WithHooks(etl.Hooks{OnRecord: func(context.Context, int64, [][]string) {
+		second.Add(1)
+	}})
+
+	report, err := processor.Process(context.TODO(), io.Discard, []string{"identifier"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"start identifier", "finish"}, events)
+	assert.Len(t, indexes, n-1)
+	assert.NotContains(t, indexes, int64(7))
+	assert.Equal(t, int64(n-1), second.Load())
+
+	require.Len(t, errs, 1)
+	assert.Equal(t, int64(7), errs[0].Index)
+	assert.Equal(t, etl.StageTransform, errs[0].Stage)
+
+	assert.Same(t, report, finished)
+}
+
+func TestProcessBranches(t *testing.T) {
+	log := zap.NewNop()
+
+	const n = 200
+
+	input := newTestRecords(t, n)
+
+	readAll := func(t *testing.T, buf *bytes.Buffer) [][]string {
+		rows, err := csv.NewReader(buf).ReadAll()
+		require.NoError(t, err)
+		return rows
+	}
+
+	t.Run("one pass feeds every branch", func(t *testing.T) {
+		var main, keywords, held bytes.Buffer
+
+		var mu sync.Mutex
+		seen := make(map[string]int)
+
+		processor := newTestProcessor(t, input,
+			testStages{tf: jitterTransformer{next: transformer.NewRowTransformer(log)}},
+			etl.WithOrderedOutput(50),
+			etl.WithBatchSize(10),
+		).WithBranch(etl.Branch{
+			Name:        "keywords",
+			Extractor:   extractor.NewMapExtractor(log),
+			Transformer: jitterTransformer{next: transformer.NewRowTransformer(log)},
+			Loader:      loader.NewCSVLoader(log),
+			Fields:      []string{"identifier", "keyword"},
+			Output:      &keywords,
+		}).WithBranch(etl.Branch{
+			Name:        "held",
+			Extractor:   extractor.NewMapExtractor(log),
+			Transformer: &holdingTransformer{},
+			Loader:      loader.NewCSVLoader(log),
+			Fields:      []string{"title"},
+			Output:      &held,
+			Buffer:      n,
+		}).WithHooks(etl.Hooks{OnRecord: func(ctx context.Context, _ int64, _ [][]string) {
+			mu.Lock()
+			defer mu.Unlock()
+			seen[etl.BranchName(ctx)]++
+		}})
+
+		report, err := processor.Process(context.TODO(), &main, []string{"identifier", "title"})
+		require.NoError(t, err)
+
+		mainRows := readAll(t, &main)
+		require.Len(t, mainRows, n+1)
+		assert.Equal(t, []string{"identifier", "title"}, mainRows[0])
+		for i, row := range mainRows[1:] {
+			assert.Equal(t, []string{strconv.Itoa(i), "title " + strconv.Itoa(i)}, row)
+		}
+
+		keywordRows := readAll(t, &keywords)
+		require.Len(t, keywordRows, 2*n+1)
+		assert.Equal(t, []string{"identifier", "keyword"}, keywordRows[0])
+		assert.Equal(t, []string{"0", "a"}, keywordRows[1])
+		assert.Equal(t, []string{"0", "b"}, keywordRows[2])
+
+		heldRows := readAll(t, &held)
+		require.Len(t, heldRows, n+1)
+		assert.Equal(t, []string{"held_title"}, heldRows[0])
+
+		assert.Equal(t, int64(n), report.Read)
+		assert.Equal(t, int64(n), report.RowsWritten)
+		assert.Equal(t, map[string]*etl.BranchReport{
+			"keywords": {Extracted: n, Transformed: n, RowsProduced: 2 * n, RowsWritten: 2 * n},
+			"held":     {Extracted: n, Transformed: n, RowsProduced: n, RowsWritten: n},
+		}, report.Branches)
+
+		assert.Equal(t, map[string]int{"": n, "keywords": n, "held": n}, seen)
+	})
+
+	t.Run("errors name their branch", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			policy etl.ErrorPolicy
+			err    string
+		}{
+			{"skip", etl.ErrorPolicy{Mode: etl.ErrorModeSkip}, ""},
+			{"fail", etl.ErrorPolicy{Mode: etl.ErrorModeFail}, "failing: record 3: transform: test"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var mu sync.Mutex
+				var errs []*etl.RecordError
+
+				var main, failing bytes.Buffer
+				processor := newTestProcessor(t, input, testStages{}, etl.WithErrorPolicy(tt.policy)).
+					WithHooks(etl.Hooks{OnError: func(_ context.Context, err *etl.RecordError) {
+						mu.Lock()
+						defer mu.Unlock()
+						errs = append(errs, err)
+					}}).
+					WithBranch(etl.Branch{
+						Name:        "failing",
+						Extractor:   extractor.NewMapExtractor(log),
+						Transformer: failOn("3"),
+						Loader:      loader.NewCSVLoader(log),
+						Fields:      []string{"identifier"},
+						Output:      &failing,
+					})
+
+				report, err := processor.Process(context.TODO(), &main, []string{"identifier"})
+				if tt.err != "" {
+					require.EqualError(t, err, tt.err)
+
+					var re *etl.RecordError
+					require.ErrorAs(t, err, &re)
+					assert.Equal(t, "failing", re.Branch)
+					return
+				}
+				require.NoError(t, err)
+
+				// only the failing branch loses the record
+				assert.Len(t, readAll(t, &main), n+1)
+				assert.Len(t, readAll(t, &failing), n)
+				assert.Equal(t, int64(1), report.Skipped)
+				assert.Equal(t, int64(n-1), report.Branches["failing"].Transformed)
+
+				require.Len(t, errs, 1)
+				assert.Equal(t, "failing", errs[0].Branch)
+				assert.Equal(t, int64(3), errs[0].Index)
+			})
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		complete := etl.Branch{
+			Name:        "a",
+			Extractor:   extractor.NewMapExtractor(log),
+			Transformer: transformer.NewRowTransformer(log),
+			Loader:      loader.NewCSVLoader(log),
+			Output:      io.Discard,
+		}
+		unnamed := complete
+		unnamed.Name = ""
+		incomplete := complete
+		incomplete.Name = "b"
+		incomplete.Loader = nil
+
+		for name, branches := range map[string][]etl.Branch{
+			"unnamed":    {unnamed},
+			"duplicate":  {complete, complete},
+			"incomplete": {complete, incomplete},
+		} {
+			t.Run(name, func(t *testing.T) {
+				processor := newTestProcessor(t, input, testStages{})
+				for _, b := range branches {
+					processor.WithBranch(b)
+				}
+
+				_, err := processor.Process(context.TODO(), io.Discard, []string{"identifier"})
+				assert.ErrorIs(t, err, etl.ErrInvalidBranch)
+			})
+		}
+	})
+}
+
+type dataset struct {
+	Title    string
+	Keywords []string
+}
+
+type keywordRow struct {
+	Title   string
+	Keyword string
+}
+
+// datasetIterator reads datasets until they run out.
+type datasetIterator struct {
+	datasets []dataset
+}
+
+func (it *datasetIterator) Next() (dataset, error) {
+	if len(it.datasets) == 0 {
+		return dataset{}, etl.Done
+	}
+
+	d := it.datasets[0]
+	it.datasets = it.datasets[1:]
+
+	return d, nil
+}
+
+func (it *datasetIterator) HasNext() bool {
+	return len(it.datasets) > 0
+}
+
+// headerLoader collects typed rows behind the columns it is handed.
+type headerLoader struct {
+	mu      sync.Mutex
+	columns []string
+	rows    []keywordRow
+}
+
+func (l *headerLoader) LoadHeader(_ context.Context, columns []string, _ io.Writer) error {
+	l.columns = columns
+	return nil
+}
+
+func (l *headerLoader) Load(_ context.Context, rows []keywordRow, _ io.Writer) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rows = append(l.rows, rows...)
+
+	return nil
+}
+
+// headerObserver is a loader middleware recording the header on its way to
+// the next loader.
+type headerObserver struct {
+	etl.LoaderOf[keywordRow]
+	columns []string
+}
+
+func (o *headerObserver) wrap(next etl.LoaderOf[keywordRow]) etl.LoaderOf[keywordRow] {
+	o.LoaderOf = next
+	return o
+}
+
+func (o *headerObserver) LoadHeader(ctx context.Context, columns []string, w io.Writer) error {
+	o.columns = columns
+	return o.LoaderOf.(etl.HeaderLoader).LoadHeader(ctx, columns, w)
+}
+
+func TestProcessTyped(t *testing.T) {
+	log := zap.NewNop()
+
+	newIterator := func() *datasetIterator {
+		return &datasetIterator{datasets: []dataset{
+			{Title: "air", Keywords: []string{"epa", "quality"}},
+			{Title: "water"},
+			{Title: "soil", Keywords: []string{"usda"}},
+		}}
+	}
+
+	ex := etl.ExtractorFuncOf[dataset, dataset](func(_ context.Context, d dataset, _ []string) (dataset, error) {
+		return d, nil
+	})
+
+	tf := etl.TransformerFuncOf[dataset, keywordRow](func(_ context.Context, d dataset, _ []string) ([]keywordRow, error) {
+		if d.Title == "soil" {
+			return nil, errors.New("no soil")
+		}
+
+		rows := make([]keywordRow, 0, len(d.Keywords))
+		for _, kw := range d.Keywords {
+			rows = append(rows, keywordRow{Title: d.Title, Keyword: kw})
+		}
+
+		return rows, nil
+	})
+
+	t.Run("typed stages, hooks and rejecter", func(t *testing.T) {
+		ld := &headerLoader{}
+
+		var recorded atomic.Int64
+		var rejected []string
+
+		p := etl.NewProcessor(ex, tf, ld, newIterator(), log,
+			etl.WithWorkers(2),
+			etl.WithErrorPolicy(etl.ErrorPolicy{Mode: etl.ErrorModeSkip}),
+		).WithHooks(etl.HooksOf[keywordRow]{
+			OnRecord: func(_ context.Context, _ int64, rows []keywordRow) {
+				recorded.Add(int64(len(rows)))
+			},
+		}).WithRejecter(rejectFunc(func(d dataset) {
+			rejected = append(rejected, d.Title)
+		}))
+
+		report, err := p.Process(context.TODO(), io.Discard, []string{"title", "keyword"})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"title", "keyword"}, ld.columns)
+		assert.ElementsMatch(t, []keywordRow{{"air", "epa"}, {"air", "quality"}}, ld.rows)
+		assert.Equal(t, int64(2), recorded.Load())
+		assert.Equal(t, []string{"soil"}, rejected)
+		assert.Equal(t, int64(3), report.Read)
+		assert.Equal(t, int64(1), report.Skipped)
+	})
+
+	t.Run("header through loader middleware", func(t *testing.T) {
+		ld := &headerLoader{}
+		observer := &headerObserver{}
+
+		var loaded atomic.Int64
+
+		p := etl.NewProcessor(ex, tf, ld, newIterator(), log,
+			etl.WithErrorPolicy(etl.ErrorPolicy{Mode: etl.ErrorModeSkip}),
+		).WithLoaderMiddleware(
+			// loads no header, so it passes the header on
+			func(next etl.LoaderOf[keywordRow]) etl.LoaderOf[keywordRow] {
+				return etl.LoaderFuncOf[keywordRow](func(ctx context.Context, rows []keywordRow, w io.Writer) error {
+					loaded.Add(1)
+					return next.Load(ctx, rows, w)
+				})
+			},
+			observer.wrap,
+		)
+
+		_, err := p.Process(context.TODO(), io.Discard, []string{"title", "keyword"})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"title", "keyword"}, observer.columns)
+		assert.Equal(t, []string{"title", "keyword"}, ld.columns)
+		assert.Len(t, ld.rows, 2)
+		assert.Positive(t, loaded.Load())
+	})
+}
+
+// rejectFunc adapts a function to a RejecterOf datasets.
+type rejectFunc func(d dataset)
+
+func (f rejectFunc) Reject(_ context.Context, _ *etl.RecordError, d dataset) error {
+	f(d)
+	return nil
+}
@@ -0,0 +1,80 @@
+//go:build unit
+
+package etl_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/ralucas/centipede/pkg/extractors"
+	"github.com/ralucas/centipede/pkg/sinks"
+	"github.com/ralucas/centipede/pkg/sources"
+	"github.com/ralucas/centipede/pkg/transforms"
+	"go.uber.org/zap"
+)
+
+const datasets = `[
+	{"title": "Air Quality", "modified": "2021-03-30T15:14:53Z", "keyword": ["air", "epa"]},
+	{"title": "Water Use", "modified": "2019-06-12T10:00:00Z", "keyword": ["water"]}
+]`
+
+func ExampleNew() {
+	report, err := etl.New(etl.WithWorkers(1)).
+		From(sources.JSON(strings.NewReader(datasets))).
+		Select("title", "modified", "keyword").
+		Transform(
+			transforms.NormalizeDates(transforms.WithLayout("2006-01-02")),
+			transforms.Sort([]string{"modified", "keyword"}),
+		).
+		To(sinks.CSV(os.Stdout)).
+		Run(context.Background())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(report.Read, "records read")
+	// Output:
+	// title,modified,keyword
+	// Water Use,2019-06-12,water
+	// Air Quality,2021-03-30,air
+	// Air Quality,2021-03-30,epa
+	// 2 records read
+}
+
+// summary is a row of the typed pipeline.
+type summary struct {
+	Title     string `json:"title"`
+	Publisher string `json:"publisher"`
+	Keywords  int    `json:"keywords"`
+}
+
+func ExampleNewProcessor() {
+	f, err := os.Open("../../test/testdata/dataset_array.json")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer f.Close()
+
+	summarize := etl.TransformerFuncOf[sources.Dataset, summary](func(_ context.Context, d sources.Dataset, _ []string) ([]summary, error) {
+		return []summary{{Title: d.Title, Publisher: d.Publisher.Name, Keywords: len(d.Keyword)}}, nil
+	})
+
+	sink := sinks.JSONLines[summary](os.Stdout)
+
+	p := etl.NewProcessor(extractors.Identity[sources.Dataset](), summarize, sink.Loader, sources.Datasets(f), zap.NewNop(),
+		etl.WithOrderedOutput(0),
+	)
+
+	if _, err := p.Process(context.Background(), sink.Output, nil); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// {"title":"Networx Business Volume FY2013, 3rd Qtr","publisher":"General Services Administration","keywords":2}
+	// {"title":"2015 GSA Common Baseline Implementation Plan and CIO Assignment Plan","publisher":"General Services Administration","keywords":6}
+	// {"title":"Award Exploration Tool","publisher":"Federal Acquisition Service","keywords":6}
+}
@@ -2,6 +2,10 @@ package etl
 
 import "context"
 
-type Extractor interface {
-	Extract(ctx context.Context, data map[string]interface{}, fields []string) (map[string]interface{}, error)
+// ExtractorOf picks the fields of a record of type In into an Out.
+type ExtractorOf[In, Out any] interface {
+	Extract(ctx context.Context, data In, fields []string) (Out, error)
 }
+
+// Extractor picks the fields of a json object into a map.
+type Extractor = ExtractorOf[map[string]interface{}, map[string]interface{}]
@@ -0,0 +1,61 @@
+package etl
+
+import "context"
+
+// HooksOf are called at points in the life of a run producing rows of type
+// Out. Any of them may be nil.
+type HooksOf[Out any] struct {
+	// OnStart is called before the header is written.
+	OnStart func(ctx context.Context, fields []string)
+	// OnRecord is called with the rows of each record once it is
+	// transformed. It is called from the workers, so concurrently and not
+	// necessarily in input order.
+	OnRecord func(ctx context.Context, index int64, rows []Out)
+	// OnError is called for every failure, skipped or not, before the error
+	// policy is applied.
+	OnError func(ctx context.Context, err *RecordError)
+	// OnFinish is called once the run is over, with its report and error.
+	OnFinish func(ctx context.Context, report *RunReport, err error)
+}
+
+// Hooks are the hooks of a run producing rows of strings.
+type Hooks = HooksOf[[]string]
+
+// WithHooks registers lifecycle hooks. It can be called more than once,
+// hooks are called in the order they were registered.
+func (e *Processor[In, Ext, Out]) WithHooks(h HooksOf[Out]) *Processor[In, Ext, Out] {
+	e.hooks = append(e.hooks, h)
+	return e
+}
+
+func (e *Processor[In, Ext, Out]) onStart(ctx context.Context, fields []string) {
+	for _, h := range e.hooks {
+		if h.OnStart != nil {
+			h.OnStart(ctx, fields)
+		}
+	}
+}
+
+func (e *Processor[In, Ext, Out]) onRecord(ctx context.Context, rec record[In], rows []Out) {
+	for _, h := range e.hooks {
+		if h.OnRecord != nil {
+			h.OnRecord(ctx, int64(rec.seq), rows)
+		}
+	}
+}
+
+func (e *Processor[In, Ext, Out]) onError(ctx context.Context, err *RecordError) {
+	for _, h := range e.hooks {
+		if h.OnError != nil {
+			h.OnError(ctx, err)
+		}
+	}
+}
+
+func (e *Processor[In, Ext, Out]) onFinish(ctx context.Context, report *RunReport, err error) {
+	for _, h := range e.hooks {
+		if h.OnFinish != nil {
+			h.OnFinish(ctx, report, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package etl
+
+import "time"
+
+// Instrumentation receives measurements from the processor as it runs, for
+// exporting as live metrics. Implementations must be safe for concurrent
+// use and should return quickly.
+type Instrumentation interface {
+	// RecordsRead counts records read from the stream iterator.
+	RecordsRead(n int)
+	// RecordsWritten counts records whose rows were loaded.
+	RecordsWritten(n int)
+	// RowsWritten counts rows loaded, including rows flushed at the end.
+	RowsWritten(n int)
+	// StageDuration observes how long a stage took for one record, or for
+	// one batch in the case of load.
+	StageDuration(stage Stage, d time.Duration)
+	// StageError counts a failure in a stage.
+	StageError(stage Stage)
+	// WorkersBusy moves the number of workers processing a batch by delta.
+	WorkersBusy(delta int)
+}
+
+// nopInstrumentation is used when no instrumentation is configured.
+type nopInstrumentation struct{}
+
+func (nopInstrumentation) RecordsRead(int)                    {}
+func (nopInstrumentation) RecordsWritten(int)                 {}
+func (nopInstrumentation) RowsWritten(int)                    {}
+func (nopInstrumentation) StageDuration(Stage, time.Duration) {}
+func (nopInstrumentation) StageError(Stage)                   {}
+func (nopInstrumentation) WorkersBusy(int)                    {}
+
+// multiInstrumentation hands every measurement to each of its
+// instrumentations.
+type multiInstrumentation []Instrumentation
+
+func (m multiInstrumentation) RecordsRead(n int) {
+	for _, i := range m {
+		i.RecordsRead(n)
+	}
+}
+
+func (m multiInstrumentation) RecordsWritten(n int) {
+	for _, i := range m {
+		i.RecordsWritten(n)
+	}
+}
+
+func (m multiInstrumentation) RowsWritten(n int) {
+	for _, i := range m {
+		i.RowsWritten(n)
+	}
+}
+
+func (m multiInstrumentation) StageDuration(stage Stage, d time.Duration) {
+	for _, i := range m {
+		i.StageDuration(stage, d)
+	}
+}
+
+func (m multiInstrumentation) StageError(stage Stage) {
+	for _, i := range m {
+		i.StageError(stage)
+	}
+}
+
+func (m multiInstrumentation) WorkersBusy(delta int) {
+	for _, i := range m {
+		i.WorkersBusy(delta)
+	}
+}
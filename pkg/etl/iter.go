@@ -0,0 +1,129 @@
+package etl
+
+import (
+	"context"
+	"errors"
+	"iter"
+)
+
+// IterableOf is the iteration contract of a stream of records of type T.
+// All yields each record, or the error of one that couldn't be read, until
+// the stream ends or the loop breaks. When ctx is done the sequence ends
+// by yielding ctx.Err(). Done is never yielded.
+type IterableOf[T any] interface {
+	All(ctx context.Context) iter.Seq2[T, error]
+}
+
+// Records returns the records of si as a sequence for a range loop:
+//
+//	for record, err := range etl.Records(ctx, si) {
+//		...
+//	}
+//
+// A stream implementing IterableOf is ranged over directly. Otherwise Next
+// is called while HasNext holds, and the sequence ends at Done or at an
+// error after which HasNext no longer holds, yielding that error first.
+func Records[T any](ctx context.Context, si StreamIteratorOf[T]) iter.Seq2[T, error] {
+	if it, ok := si.(IterableOf[T]); ok {
+		return it.All(ctx)
+	}
+
+	return func(yield func(T, error) bool) {
+		for si.HasNext() {
+			if err := ctx.Err(); err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			v, err := si.Next()
+			if errors.Is(err, Done) {
+				return
+			}
+
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
+
+// SeqIterator is a stream iterator over a sequence, built by FromSeq.
+type SeqIterator[T any] struct {
+	seq     iter.Seq2[T, error]
+	next    func() (T, error, bool)
+	stop    func()
+	pending bool
+	v       T
+	err     error
+}
+
+// FromSeq adapts a sequence of records, and errors reading them, to a
+// stream iterator. Records and the processor range over seq directly, Next
+// and HasNext pull from it in a goroutine of its own, which is released
+// once the sequence is exhausted or Stop is called.
+func FromSeq[T any](seq iter.Seq2[T, error]) *SeqIterator[T] {
+	return &SeqIterator[T]{seq: seq}
+}
+
+// All ranges over the sequence, ending it with ctx.Err() once ctx is done.
+func (s *SeqIterator[T]) All(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		if err := ctx.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+
+		for v, err := range s.seq {
+			if !yield(v, err) {
+				return
+			}
+
+			if err := ctx.Err(); err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+		}
+	}
+}
+
+func (s *SeqIterator[T]) HasNext() bool {
+	if s.pending {
+		return true
+	}
+
+	if s.next == nil {
+		s.next, s.stop = iter.Pull2(s.seq)
+	}
+
+	var ok bool
+	s.v, s.err, ok = s.next()
+	if !ok {
+		s.stop()
+		return false
+	}
+	s.pending = true
+
+	return true
+}
+
+func (s *SeqIterator[T]) Next() (T, error) {
+	if !s.HasNext() {
+		var zero T
+		return zero, Done
+	}
+
+	s.pending = false
+
+	return s.v, s.err
+}
+
+// Stop releases the goroutine pulling from the sequence when iteration
+// ends early.
+func (s *SeqIterator[T]) Stop() {
+	if s.stop != nil {
+		s.stop()
+	}
+}
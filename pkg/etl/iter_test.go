@@ -0,0 +1,156 @@
+//go:build unit
+
+package etl_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"iter"
+	"strings"
+	"testing"
+
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/ralucas/centipede/pkg/sinks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingIterator fails the read of the records at fail, carrying on past
+// them, and ends with a trailing Done.
+type failingIterator struct {
+	sliceIterator
+	seq  int
+	fail map[int]bool
+}
+
+func (f *failingIterator) Next() (map[string]interface{}, error) {
+	obj, err := f.sliceIterator.Next()
+	if err == nil && f.fail[f.seq] {
+		err = errors.New("unreadable")
+		obj = nil
+	}
+	f.seq++
+
+	return obj, err
+}
+
+func titles() []map[string]interface{} {
+	return []map[string]interface{}{{"title": "air"}, {"title": "water"}, {"title": "soil"}}
+}
+
+func collect(seq iter.Seq2[map[string]interface{}, error]) ([]interface{}, []error) {
+	var got []interface{}
+	var errs []error
+	for obj, err := range seq {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		got = append(got, obj["title"])
+	}
+
+	return got, errs
+}
+
+func TestRecords(t *testing.T) {
+	t.Run("ends without Done", func(t *testing.T) {
+		got, errs := collect(etl.Records(context.TODO(), etl.StreamIterator(&sliceIterator{records: titles()})))
+		assert.Equal(t, []interface{}{"air", "water", "soil"}, got)
+		assert.Empty(t, errs)
+	})
+
+	t.Run("yields read errors", func(t *testing.T) {
+		si := &failingIterator{sliceIterator: sliceIterator{records: titles()}, fail: map[int]bool{1: true}}
+
+		got, errs := collect(etl.Records(context.TODO(), etl.StreamIterator(si)))
+		assert.Equal(t, []interface{}{"air", "soil"}, got)
+		assert.Len(t, errs, 1)
+	})
+
+	t.Run("stops on break", func(t *testing.T) {
+		si := &sliceIterator{records: titles()}
+		for range etl.Records(context.TODO(), etl.StreamIterator(si)) {
+			break
+		}
+		assert.Len(t, si.records, 2)
+	})
+
+	t.Run("ends with the context error", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+
+		var got []interface{}
+		var errs []error
+		for obj, err := range etl.Records(ctx, etl.StreamIterator(&sliceIterator{records: titles()})) {
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			got = append(got, obj["title"])
+			cancel()
+		}
+
+		assert.Equal(t, []interface{}{"air"}, got)
+		require.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], context.Canceled)
+	})
+}
+
+func sequence(records []map[string]interface{}) iter.Seq2[map[string]interface{}, error] {
+	return func(yield func(map[string]interface{}, error) bool) {
+		for i, r := range records {
+			var err error
+			if i == 1 {
+				r, err = nil, errors.New("unreadable")
+			}
+			if !yield(r, err) {
+				return
+			}
+		}
+	}
+}
+
+func TestFromSeq(t *testing.T) {
+	t.Run("ranged", func(t *testing.T) {
+		got, errs := collect(etl.Records(context.TODO(), etl.StreamIterator(etl.FromSeq(sequence(titles())))))
+		assert.Equal(t, []interface{}{"air", "soil"}, got)
+		assert.Len(t, errs, 1)
+	})
+
+	t.Run("pulled", func(t *testing.T) {
+		si := etl.FromSeq(sequence(titles()))
+
+		var got []interface{}
+		var errs int
+		for si.HasNext() {
+			obj, err := si.Next()
+			if err != nil {
+				errs++
+				continue
+			}
+			got = append(got, obj["title"])
+		}
+
+		assert.Equal(t, []interface{}{"air", "soil"}, got)
+		assert.Equal(t, 1, errs)
+
+		_, err := si.Next()
+		assert.ErrorIs(t, err, etl.Done)
+	})
+
+	t.Run("processed", func(t *testing.T) {
+		var out bytes.Buffer
+
+		report, err := etl.New(etl.WithErrorPolicy(etl.ErrorPolicy{Mode: etl.ErrorModeSkip})).
+			From(etl.FromSeq(sequence(titles()))).
+			Select("title").
+			To(sinks.CSV(&out)).
+			Run(context.TODO())
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(3), report.Read)
+		assert.Equal(t, int64(1), report.Skipped)
+		assert.ElementsMatch(t, []string{"title", "air", "soil"}, strings.Fields(out.String()))
+	})
+}
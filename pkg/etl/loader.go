@@ -5,6 +5,18 @@ import (
 	"io"
 )
 
-type Loader interface {
-	Load(ctx context.Context, data [][]string, writer io.Writer) error
+// LoaderOf writes rows of type T to the output. The processor hands it
+// the rows of a whole batch of records at once, so it should write and
+// flush once per call.
+type LoaderOf[T any] interface {
+	Load(ctx context.Context, data []T, writer io.Writer) error
+}
+
+// Loader writes rows of strings, the first being the header.
+type Loader = LoaderOf[[]string]
+
+// HeaderLoader is implemented by loaders of rows other than []string that
+// write a header. It is called with the output columns before any row.
+type HeaderLoader interface {
+	LoadHeader(ctx context.Context, columns []string, writer io.Writer) error
 }
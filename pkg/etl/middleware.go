@@ -0,0 +1,123 @@
+package etl
+
+import (
+	"context"
+	"io"
+)
+
+// ExtractorFuncOf adapts a function to an ExtractorOf.
+type ExtractorFuncOf[In, Out any] func(ctx context.Context, data In, fields []string) (Out, error)
+
+func (f ExtractorFuncOf[In, Out]) Extract(ctx context.Context, data In, fields []string) (Out, error) {
+	return f(ctx, data, fields)
+}
+
+// ExtractorFunc adapts a function to an Extractor.
+type ExtractorFunc = ExtractorFuncOf[map[string]interface{}, map[string]interface{}]
+
+// TransformerFuncOf adapts a function to a TransformerOf.
+type TransformerFuncOf[In, Out any] func(ctx context.Context, data In, fields []string) ([]Out, error)
+
+func (f TransformerFuncOf[In, Out]) Transform(ctx context.Context, data In, fields []string) ([]Out, error) {
+	return f(ctx, data, fields)
+}
+
+// TransformerFunc adapts a function to a Transformer.
+type TransformerFunc = TransformerFuncOf[map[string]interface{}, []string]
+
+// LoaderFuncOf adapts a function to a LoaderOf.
+type LoaderFuncOf[T any] func(ctx context.Context, data []T, writer io.Writer) error
+
+func (f LoaderFuncOf[T]) Load(ctx context.Context, data []T, writer io.Writer) error {
+	return f(ctx, data, writer)
+}
+
+// LoaderFunc adapts a function to a Loader.
+type LoaderFunc = LoaderFuncOf[[]string]
+
+// ExtractorMiddlewareOf wraps an extractor with behaviour of its own, such
+// as timing or redaction, calling next to carry on.
+type ExtractorMiddlewareOf[In, Out any] func(next ExtractorOf[In, Out]) ExtractorOf[In, Out]
+
+// ExtractorMiddleware wraps an Extractor.
+type ExtractorMiddleware = ExtractorMiddlewareOf[map[string]interface{}, map[string]interface{}]
+
+// TransformerMiddlewareOf wraps a transformer, calling next to carry on.
+type TransformerMiddlewareOf[In, Out any] func(next TransformerOf[In, Out]) TransformerOf[In, Out]
+
+// TransformerMiddleware wraps a Transformer.
+type TransformerMiddleware = TransformerMiddlewareOf[map[string]interface{}, []string]
+
+// LoaderMiddlewareOf wraps a loader, calling next to carry on.
+type LoaderMiddlewareOf[T any] func(next LoaderOf[T]) LoaderOf[T]
+
+// LoaderMiddleware wraps a Loader.
+type LoaderMiddleware = LoaderMiddlewareOf[[]string]
+
+// WithExtractorMiddleware wraps the extractor in mw. The first middleware
+// is the outermost, so it sees each record first.
+func (e *Processor[In, Ext, Out]) WithExtractorMiddleware(mw ...ExtractorMiddlewareOf[In, Ext]) *Processor[In, Ext, Out] {
+	e.extractorMiddleware = append(e.extractorMiddleware, mw...)
+	return e
+}
+
+// WithTransformerMiddleware wraps the transformer in mw, the first being
+// the outermost. A Flusher or ColumnMapper is still found on the wrapped
+// transformer, its held back rows are flushed without passing through mw.
+func (e *Processor[In, Ext, Out]) WithTransformerMiddleware(mw ...TransformerMiddlewareOf[Ext, Out]) *Processor[In, Ext, Out] {
+	e.transformerMiddleware = append(e.transformerMiddleware, mw...)
+	return e
+}
+
+// WithLoaderMiddleware wraps the loader in mw, the first being the
+// outermost. The header and flushed rows are loaded through it too. A
+// HeaderLoader's header reaches a middleware whose loader is a HeaderLoader
+// itself, and is passed on to the next loader by one that is not.
+func (e *Processor[In, Ext, Out]) WithLoaderMiddleware(mw ...LoaderMiddlewareOf[Out]) *Processor[In, Ext, Out] {
+	e.loaderMiddleware = append(e.loaderMiddleware, mw...)
+	return e
+}
+
+// wrap applies the middleware to the stages of a branch, innermost last.
+func (e *Processor[In, Ext, Out]) wrap(ex ExtractorOf[In, Ext], tf TransformerOf[Ext, Out], ld LoaderOf[Out]) (ExtractorOf[In, Ext], TransformerOf[Ext, Out], LoaderOf[Out]) {
+	for i := len(e.extractorMiddleware) - 1; i >= 0; i-- {
+		ex = e.extractorMiddleware[i](ex)
+	}
+
+	for i := len(e.transformerMiddleware) - 1; i >= 0; i-- {
+		tf = e.transformerMiddleware[i](tf)
+	}
+
+	for i := len(e.loaderMiddleware) - 1; i >= 0; i-- {
+		ld = wrapLoader(e.loaderMiddleware[i], ld)
+	}
+
+	return ex, tf, ld
+}
+
+// wrapLoader applies mw to next, passing the header of a HeaderLoader on
+// when the middleware's loader does not load it itself.
+func wrapLoader[T any](mw LoaderMiddlewareOf[T], next LoaderOf[T]) LoaderOf[T] {
+	ld := mw(next)
+
+	if _, ok := ld.(HeaderLoader); ok {
+		return ld
+	}
+
+	if hl, ok := next.(HeaderLoader); ok {
+		return headerPassingLoader[T]{LoaderOf: ld, header: hl}
+	}
+
+	return ld
+}
+
+// headerPassingLoader is a middleware's loader passing the header on to the
+// loader it wraps.
+type headerPassingLoader[T any] struct {
+	LoaderOf[T]
+	header HeaderLoader
+}
+
+func (l headerPassingLoader[T]) LoadHeader(ctx context.Context, columns []string, writer io.Writer) error {
+	return l.header.LoadHeader(ctx, columns, writer)
+}
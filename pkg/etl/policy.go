@@ -0,0 +1,93 @@
+package etl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidErrorPolicy  = errors.New("invalid error policy")
+	ErrErrorBudgetExceeded = errors.New("error budget exceeded")
+)
+
+// ErrorMode decides what happens to the run when a record fails.
+type ErrorMode string
+
+const (
+	// ErrorModeFail cancels the run on the first failed record.
+	ErrorModeFail ErrorMode = "fail"
+	// ErrorModeSkip drops failed records and carries on, failing only if a
+	// configured budget is exceeded.
+	ErrorModeSkip ErrorMode = "skip"
+	// ErrorModeThreshold is skip with a required error budget.
+	ErrorModeThreshold ErrorMode = "threshold"
+)
+
+func ParseErrorMode(s string) (ErrorMode, error) {
+	switch m := ErrorMode(strings.ToLower(s)); m {
+	case ErrorModeFail, ErrorModeSkip, ErrorModeThreshold:
+		return m, nil
+	default:
+		return "", fmt.Errorf("%w: unknown mode %q, expected fail, skip or threshold", ErrInvalidErrorPolicy, s)
+	}
+}
+
+// ParseErrorRate parses a fraction such as 0.005 or a percentage such as
+// 0.5%.
+func ParseErrorRate(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	pct := strings.HasSuffix(s, "%")
+
+	rate, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid error rate %q", ErrInvalidErrorPolicy, s)
+	}
+
+	if pct {
+		rate /= 100
+	}
+
+	if rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("%w: error rate %q is not between 0 and 100%%", ErrInvalidErrorPolicy, s)
+	}
+
+	return rate, nil
+}
+
+// ErrorPolicy applies to read, extract, transform and load failures of
+// individual records. Header and flush failures always fail the run.
+// MaxErrors is checked as records fail, MaxErrorRate against all records
+// read once the input is exhausted. Zero disables a limit.
+type ErrorPolicy struct {
+	Mode         ErrorMode
+	MaxErrors    int
+	MaxErrorRate float64
+}
+
+func (p ErrorPolicy) Validate() error {
+	if _, err := ParseErrorMode(string(p.Mode)); err != nil {
+		return err
+	}
+
+	if p.MaxErrors < 0 {
+		return fmt.Errorf("%w: max errors must not be negative", ErrInvalidErrorPolicy)
+	}
+
+	if p.Mode == ErrorModeThreshold && p.MaxErrors == 0 && p.MaxErrorRate == 0 {
+		return fmt.Errorf("%w: threshold mode needs a max errors or max error rate", ErrInvalidErrorPolicy)
+	}
+
+	return nil
+}
+
+// Skips reports whether failed records are skipped rather than failing the
+// run.
+func (p ErrorPolicy) Skips() bool {
+	return p.Mode == ErrorModeSkip || p.Mode == ErrorModeThreshold
+}
@@ -0,0 +1,54 @@
+//go:build unit
+
+package etl_test
+
+import (
+	"testing"
+
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseErrorRate(t *testing.T) {
+	tests := []struct {
+		in     string
+		expect float64
+		err    bool
+	}{
+		{in: "", expect: 0},
+		{in: "0.5%", expect: 0.005},
+		{in: "0.005", expect: 0.005},
+		{in: "100%", expect: 1},
+		{in: "150%", err: true},
+		{in: "-1", err: true},
+		{in: "half", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			rate, err := etl.ParseErrorRate(test.in)
+			if test.err {
+				assert.ErrorIs(t, err, etl.ErrInvalidErrorPolicy)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, test.expect, rate, 1e-12)
+		})
+	}
+}
+
+func TestErrorPolicyValidate(t *testing.T) {
+	mode, err := etl.ParseErrorMode("SKIP")
+	require.NoError(t, err)
+	assert.Equal(t, etl.ErrorModeSkip, mode)
+
+	_, err = etl.ParseErrorMode("ignore")
+	assert.ErrorIs(t, err, etl.ErrInvalidErrorPolicy)
+
+	assert.NoError(t, etl.ErrorPolicy{Mode: etl.ErrorModeSkip}.Validate())
+	assert.NoError(t, etl.ErrorPolicy{Mode: etl.ErrorModeThreshold, MaxErrors: 10}.Validate())
+	assert.NoError(t, etl.ErrorPolicy{Mode: etl.ErrorModeThreshold, MaxErrorRate: 0.01}.Validate())
+	assert.ErrorIs(t, etl.ErrorPolicy{Mode: etl.ErrorModeThreshold}.Validate(), etl.ErrInvalidErrorPolicy)
+	assert.ErrorIs(t, etl.ErrorPolicy{Mode: etl.ErrorModeSkip, MaxErrors: -1}.Validate(), etl.ErrInvalidErrorPolicy)
+}
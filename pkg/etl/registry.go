@@ -0,0 +1,263 @@
+package etl
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrUnknownComponent       = errors.New("unknown component")
+	ErrInvalidComponentConfig = errors.New("invalid component config")
+	ErrInvalidChain           = errors.New("invalid transformer chain")
+)
+
+// Kind is the part of a pipeline a component fills.
+type Kind string
+
+const (
+	KindSource      Kind = "source"
+	KindExtractor   Kind = "extractor"
+	KindTransformer Kind = "transformer"
+	KindLoader      Kind = "loader"
+)
+
+// SourceFactory builds a stream iterator over r from its config.
+type SourceFactory[C any] func(r io.Reader, conf C, log *zap.Logger) (StreamIterator, error)
+
+// ExtractorFactory builds an extractor from its config.
+type ExtractorFactory[C any] func(conf C, log *zap.Logger) (Extractor, error)
+
+// TransformerFactory builds a transformer from its config. Transformers are
+// built as a chain, next is the transformer built before this one, or nil
+// for the first, and fields are those the pipeline extracts. A transformer
+// that can't follow next returns ErrInvalidChain.
+type TransformerFactory[C any] func(next Transformer, fields []string, conf C, log *zap.Logger) (Transformer, error)
+
+// LoaderFactory builds a loader from its config.
+type LoaderFactory[C any] func(conf C, log *zap.Logger) (Loader, error)
+
+type component struct {
+	check       func(config interface{}) error
+	source      func(r io.Reader, config interface{}, log *zap.Logger) (StreamIterator, error)
+	extractor   func(config interface{}, log *zap.Logger) (Extractor, error)
+	transformer func(next Transformer, fields []string, config interface{}, log *zap.Logger) (Transformer, error)
+	loader      func(config interface{}, log *zap.Logger) (Loader, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[Kind]map[string]component)
+)
+
+// RegisterSource makes a source available by name. The config handed to
+// NewSource is decoded into C, so C's json tags name its settings.
+// Registering the same name twice, or a nil factory, panics.
+func RegisterSource[C any](name string, factory SourceFactory[C]) {
+	if factory == nil {
+		panic("etl: RegisterSource factory is nil")
+	}
+
+	register(KindSource, name, component{
+		check: checkConfig[C],
+		source: func(r io.Reader, config interface{}, log *zap.Logger) (StreamIterator, error) {
+			conf, err := decodeConfig[C](config)
+			if err != nil {
+				return nil, err
+			}
+			return factory(r, conf, log)
+		},
+	})
+}
+
+// RegisterExtractor makes an extractor available by name.
+func RegisterExtractor[C any](name string, factory ExtractorFactory[C]) {
+	if factory == nil {
+		panic("etl: RegisterExtractor factory is nil")
+	}
+
+	register(KindExtractor, name, component{
+		check: checkConfig[C],
+		extractor: func(config interface{}, log *zap.Logger) (Extractor, error) {
+			conf, err := decodeConfig[C](config)
+			if err != nil {
+				return nil, err
+			}
+			return factory(conf, log)
+		},
+	})
+}
+
+// RegisterTransformer makes a transformer available by name.
+func RegisterTransformer[C any](name string, factory TransformerFactory[C]) {
+	if factory == nil {
+		panic("etl: RegisterTransformer factory is nil")
+	}
+
+	register(KindTransformer, name, component{
+		check: checkConfig[C],
+		transformer: func(next Transformer, fields []string, config interface{}, log *zap.Logger) (Transformer, error) {
+			conf, err := decodeConfig[C](config)
+			if err != nil {
+				return nil, err
+			}
+			return factory(next, fields, conf, log)
+		},
+	})
+}
+
+// RegisterLoader makes a loader available by name.
+func RegisterLoader[C any](name string, factory LoaderFactory[C]) {
+	if factory == nil {
+		panic("etl: RegisterLoader factory is nil")
+	}
+
+	register(KindLoader, name, component{
+		check: checkConfig[C],
+		loader: func(config interface{}, log *zap.Logger) (Loader, error) {
+			conf, err := decodeConfig[C](config)
+			if err != nil {
+				return nil, err
+			}
+			return factory(conf, log)
+		},
+	})
+}
+
+func register(kind Kind, name string, c component) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if registry[kind] == nil {
+		registry[kind] = make(map[string]component)
+	}
+	if _, dup := registry[kind][name]; dup {
+		panic(fmt.Sprintf("etl: %s %q registered twice", kind, name))
+	}
+
+	registry[kind][name] = c
+}
+
+func registered(kind Kind, name string) (component, error) {
+	registryMu.RLock()
+	c, ok := registry[kind][name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return component{}, fmt.Errorf("%w: no %s named %q, expected one of %s", ErrUnknownComponent, kind, name, strings.Join(Components(kind), ", "))
+	}
+
+	return c, nil
+}
+
+// Components returns the sorted names registered for kind.
+func Components(kind Kind) []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry[kind]))
+	for name := range registry[kind] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// CheckComponent reports whether a component of kind is registered under
+// name and its config decodes, without building it.
+func CheckComponent(kind Kind, name string, config interface{}) error {
+	c, err := registered(kind, name)
+	if err != nil {
+		return err
+	}
+
+	return c.check(config)
+}
+
+// NewSource builds the source registered under name. The config is either
+// the factory's config type itself, or a value such as a
+// map[string]interface{} decoded into it as JSON. A nil config is the zero
+// config.
+func NewSource(name string, r io.Reader, config interface{}, log *zap.Logger) (StreamIterator, error) {
+	c, err := registered(KindSource, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.source(r, config, log)
+}
+
+// NewExtractor builds the extractor registered under name.
+func NewExtractor(name string, config interface{}, log *zap.Logger) (Extractor, error) {
+	c, err := registered(KindExtractor, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.extractor(config, log)
+}
+
+// NewTransformer builds the transformer registered under name, following
+// next in the chain.
+func NewTransformer(name string, next Transformer, fields []string, config interface{}, log *zap.Logger) (Transformer, error) {
+	c, err := registered(KindTransformer, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.transformer(next, fields, config, log)
+}
+
+// NewLoader builds the loader registered under name.
+func NewLoader(name string, config interface{}, log *zap.Logger) (Loader, error) {
+	c, err := registered(KindLoader, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.loader(config, log)
+}
+
+func checkConfig[C any](config interface{}) error {
+	_, err := decodeConfig[C](config)
+	return err
+}
+
+// decodeConfig converts config to C, rejecting settings C doesn't have.
+func decodeConfig[C any](config interface{}) (C, error) {
+	var conf C
+
+	switch v := config.(type) {
+	case nil:
+		return conf, nil
+	case C:
+		return v, nil
+	case *C:
+		if v != nil {
+			conf = *v
+		}
+		return conf, nil
+	}
+
+	b, err := json.Marshal(config)
+	if err != nil {
+		return conf, fmt.Errorf("%w: %w", ErrInvalidComponentConfig, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&conf); err != nil {
+		return conf, fmt.Errorf("%w: %w", ErrInvalidComponentConfig, err)
+	}
+
+	return conf, nil
+}
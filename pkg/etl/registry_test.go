@@ -0,0 +1,144 @@
+//go:build unit
+
+package etl_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type caseConfig struct {
+	Upper  bool   `json:"upper"`
+	Prefix string `json:"prefix"`
+}
+
+// caseTransformer rewrites the cells of the rows of next.
+type caseTransformer struct {
+	next etl.Transformer
+	conf caseConfig
+}
+
+func (t *caseTransformer) Transform(ctx context.Context, data map[string]interface{}, fields []string) ([][]string, error) {
+	rows, err := t.next.Transform(ctx, data, fields)
+	for _, row := range rows {
+		for i, cell := range row {
+			if t.conf.Upper {
+				cell = strings.ToUpper(cell)
+			}
+			row[i] = t.conf.Prefix + cell
+		}
+	}
+
+	return rows, err
+}
+
+func init() {
+	etl.RegisterTransformer("test_case", func(next etl.Transformer, _ []string, conf caseConfig, _ *zap.Logger) (etl.Transformer, error) {
+		if next == nil {
+			return nil, etl.ErrInvalidChain
+		}
+		return &caseTransformer{next: next, conf: conf}, nil
+	})
+
+	etl.RegisterSource("test_empty", func(_ io.Reader, _ struct{}, _ *zap.Logger) (etl.StreamIterator, error) {
+		return &sliceIterator{}, nil
+	})
+}
+
+// sliceIterator reports HasNext until a Next call returns etl.Done.
+type sliceIterator struct {
+	records []map[string]interface{}
+	done    bool
+}
+
+func (s *sliceIterator) Next() (map[string]interface{}, error) {
+	if len(s.records) == 0 {
+		s.done = true
+		return nil, etl.Done
+	}
+
+	obj := s.records[0]
+	s.records = s.records[1:]
+
+	return obj, nil
+}
+
+func (s *sliceIterator) HasNext() bool {
+	return !s.done
+}
+
+func TestRegistryTransformer(t *testing.T) {
+	log := zap.NewNop()
+	fields := []string{"title"}
+	record := map[string]interface{}{"title": "air"}
+
+	rows, err := etl.NewTransformer("rows", nil, fields, nil, log)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		config interface{}
+		want   [][]string
+	}{
+		{"nil config", nil, [][]string{{"air"}}},
+		{"typed config", caseConfig{Upper: true}, [][]string{{"AIR"}}},
+		{"typed pointer", &caseConfig{Prefix: "> "}, [][]string{{"> air"}}},
+		{"decoded map", map[string]interface{}{"upper": true, "prefix": "> "}, [][]string{{"> AIR"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf, err := etl.NewTransformer("test_case", rows, fields, tt.config, log)
+			require.NoError(t, err)
+
+			got, err := tf.Transform(context.TODO(), record, fields)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err = etl.NewTransformer("test_case", nil, fields, nil, log)
+	assert.ErrorIs(t, err, etl.ErrInvalidChain)
+}
+
+func TestRegistryErrors(t *testing.T) {
+	log := zap.NewNop()
+
+	_, err := etl.NewTransformer("test_case", nil, nil, map[string]interface{}{"upper": "yes"}, log)
+	assert.ErrorIs(t, err, etl.ErrInvalidComponentConfig)
+
+	err = etl.CheckComponent(etl.KindTransformer, "test_case", map[string]interface{}{"lower": true})
+	assert.ErrorIs(t, err, etl.ErrInvalidComponentConfig)
+	assert.ErrorContains(t, err, `unknown field "lower"`)
+
+	_, err = etl.NewSource("test_missing", strings.NewReader(""), nil, log)
+	assert.ErrorIs(t, err, etl.ErrUnknownComponent)
+	assert.ErrorContains(t, err, `no source named "test_missing"`)
+	assert.ErrorContains(t, err, "test_empty")
+
+	_, err = etl.NewLoader("test_missing", nil, log)
+	assert.ErrorIs(t, err, etl.ErrUnknownComponent)
+
+	assert.NoError(t, etl.CheckComponent(etl.KindSource, "test_empty", map[string]interface{}{}))
+	assert.Panics(t, func() {
+		etl.RegisterSource("test_empty", func(_ io.Reader, _ struct{}, _ *zap.Logger) (etl.StreamIterator, error) {
+			return nil, nil
+		})
+	})
+}
+
+func TestRegistryComponents(t *testing.T) {
+	names := etl.Components(etl.KindTransformer)
+	assert.Contains(t, names, "test_case")
+	assert.Contains(t, names, "rows")
+	assert.IsIncreasing(t, names)
+
+	assert.Empty(t, etl.Components(etl.Kind("unknown")))
+}
@@ -0,0 +1,14 @@
+package etl
+
+import "context"
+
+// RejecterOf receives every record that fails, for example to write it to
+// a dead-letter file. record is the zero T when the raw record was never
+// decoded.
+type RejecterOf[T any] interface {
+	Reject(ctx context.Context, err *RecordError, record T) error
+}
+
+// Rejecter receives failed json objects, record is nil when it was never
+// decoded.
+type Rejecter = RejecterOf[map[string]interface{}]
@@ -0,0 +1,53 @@
+package etl
+
+import "context"
+
+// reorderBuffer re-sequences transformed batches so they are loaded in
+// input order. At most window batches are in flight between being formed
+// and being loaded, which bounds the rows held while waiting on a slow
+// batch.
+// Only the loader calls deliver, so it is not guarded by a lock.
+type reorderBuffer[In, Out any] struct {
+	next    uint64
+	pending map[uint64]batchResult[In, Out]
+	slots   chan struct{}
+	load    func(batchResult[In, Out])
+}
+
+func newReorderBuffer[In, Out any](window int, load func(batchResult[In, Out])) *reorderBuffer[In, Out] {
+	return &reorderBuffer[In, Out]{
+		pending: make(map[uint64]batchResult[In, Out]),
+		slots:   make(chan struct{}, window),
+		load:    load,
+	}
+}
+
+// acquire reserves a slot for the next batch, blocking while the window
+// is full.
+func (b *reorderBuffer[In, Out]) acquire(ctx context.Context) error {
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deliver hands over the results of a batch and loads every batch that is
+// now next in sequence.
+func (b *reorderBuffer[In, Out]) deliver(br batchResult[In, Out]) {
+	b.pending[br.seq] = br
+
+	for {
+		next, ok := b.pending[b.next]
+		if !ok {
+			return
+		}
+
+		delete(b.pending, b.next)
+		b.next++
+		<-b.slots
+
+		b.load(next)
+	}
+}
@@ -0,0 +1,120 @@
+package etl
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ByteCounter reports how far into the input the stream iterator has read.
+type ByteCounter interface {
+	BytesRead() int64
+}
+
+// RunReport summarizes a run of the processor. Records that are skipped or
+// fail count as read but not as transformed. The extract, transform and row
+// counts are those of the processor's own pipeline, each branch has its own
+// in Branches.
+type RunReport struct {
+	StartedAt        time.Time        `json:"started_at"`
+	FinishedAt       time.Time        `json:"finished_at"`
+	DurationSeconds  float64          `json:"duration_seconds"`
+	RecordsPerSecond float64          `json:"records_per_second"`
+	BytesRead        int64            `json:"bytes_read"`
+	Read             int64            `json:"records_read"`
+	Extracted        int64            `json:"records_extracted"`
+	Transformed      int64            `json:"records_transformed"`
+	Skipped          int64            `json:"records_skipped"`
+	Failed           int64            `json:"records_failed"`
+	RowsProduced     int64            `json:"rows_produced"`
+	RowsWritten      int64            `json:"rows_written"`
+	ErrorsByStage    map[Stage]int64  `json:"errors_by_stage"`
+	ErrorsByType     map[string]int64 `json:"errors_by_type"`
+
+	Branches map[string]*BranchReport `json:"branches,omitempty"`
+}
+
+// MarshalLogObject logs the report under the same keys as its JSON form.
+func (r *RunReport) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddTime("started_at", r.StartedAt)
+	enc.AddTime("finished_at", r.FinishedAt)
+	enc.AddFloat64("duration_seconds", r.DurationSeconds)
+	enc.AddFloat64("records_per_second", r.RecordsPerSecond)
+	enc.AddInt64("bytes_read", r.BytesRead)
+	enc.AddInt64("records_read", r.Read)
+	enc.AddInt64("records_extracted", r.Extracted)
+	enc.AddInt64("records_transformed", r.Transformed)
+	enc.AddInt64("records_skipped", r.Skipped)
+	enc.AddInt64("records_failed", r.Failed)
+	enc.AddInt64("rows_produced", r.RowsProduced)
+	enc.AddInt64("rows_written", r.RowsWritten)
+
+	_ = enc.AddObject("errors_by_stage", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		for stage, n := range r.ErrorsByStage {
+			enc.AddInt64(string(stage), n)
+		}
+		return nil
+	}))
+
+	_ = enc.AddObject("errors_by_type", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		for typ, n := range r.ErrorsByType {
+			enc.AddInt64(typ, n)
+		}
+		return nil
+	}))
+
+	if len(r.Branches) > 0 {
+		_ = enc.AddObject("branches", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			for name, b := range r.Branches {
+				_ = enc.AddObject(name, b)
+			}
+			return nil
+		}))
+	}
+
+	return nil
+}
+
+// BranchReport is the counts of a branch fed alongside the processor's own
+// pipeline.
+type BranchReport struct {
+	Extracted    int64 `json:"records_extracted"`
+	Transformed  int64 `json:"records_transformed"`
+	RowsProduced int64 `json:"rows_produced"`
+	RowsWritten  int64 `json:"rows_written"`
+}
+
+func (r *BranchReport) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt64("records_extracted", r.Extracted)
+	enc.AddInt64("records_transformed", r.Transformed)
+	enc.AddInt64("rows_produced", r.RowsProduced)
+	enc.AddInt64("rows_written", r.RowsWritten)
+
+	return nil
+}
+
+// runCounters are the report counts updated while the run is in flight.
+type runCounters struct {
+	extracted    atomic.Int64
+	transformed  atomic.Int64
+	rowsProduced atomic.Int64
+	rowsWritten  atomic.Int64
+}
+
+// countError adds a failure to the error counts.
+func (r *RunReport) countError(err *RecordError) {
+	r.ErrorsByStage[err.Stage]++
+	r.ErrorsByType[errorType(err.Err)]++
+}
+
+// errorType names the kind of an error by the leading part of its message,
+// which for wrapped sentinel errors is the sentinel's text, e.g. "json does
+// not conform to dataset schema".
+func errorType(err error) string {
+	msg, _, _ := strings.Cut(err.Error(), "\n")
+	msg, _, _ = strings.Cut(msg, ": ")
+
+	return msg
+}
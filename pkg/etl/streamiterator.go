@@ -2,9 +2,31 @@ package etl
 
 import "errors"
 
-var Done = errors.New("iterator done")
+var (
+	Done = errors.New("iterator done")
+	// ErrStreamBroken marks a read error after which the stream can't be
+	// read any further, such as malformed json. It fails the run under
+	// every error policy, as skipping it would drop the rest of the input.
+	ErrStreamBroken = errors.New("stream broken")
+)
 
-type StreamIterator interface {
-	Next() (map[string]interface{}, error)
+// StreamIteratorOf reads the records of a stream one at a time, decoded
+// as T.
+type StreamIteratorOf[T any] interface {
+	Next() (T, error)
 	HasNext() bool
 }
+
+// StreamIterator reads records decoded as json objects.
+type StreamIterator = StreamIteratorOf[map[string]interface{}]
+
+// RawRecordErrorOf is implemented by read errors that still hold the
+// decoded record, such as schema validation failures, so it can be rejected
+// along with the error.
+type RawRecordErrorOf[T any] interface {
+	error
+	RawRecord() T
+}
+
+// RawRecordError is a RawRecordErrorOf holding a json object.
+type RawRecordError = RawRecordErrorOf[map[string]interface{}]
@@ -0,0 +1,89 @@
+package etl
+
+import (
+	"context"
+	"math"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const tracerName = "github.com/ralucas/centipede/pkg/etl"
+
+// span attribute keys
+const (
+	attrRecordIndex  = attribute.Key("etl.record.index")
+	attrRows         = attribute.Key("etl.rows")
+	attrBatchSeq     = attribute.Key("etl.batch.seq")
+	attrBatchRecords = attribute.Key("etl.batch.records")
+	attrBranch       = attribute.Key("etl.branch")
+)
+
+// WithTracerProvider traces the run with a span for the whole run, spans
+// for the extract and transform of each record and a span for the load of
+// each batch. The span is carried by the context handed to the extractor,
+// transformer and loader.
+func WithTracerProvider(tp trace.TracerProvider) ETLProcessorOption {
+	return func(s *settings) {
+		if tp != nil {
+			s.tracer = tp.Tracer(tracerName)
+		}
+	}
+}
+
+// WithRecordSpanRate sets the share of records given extract and transform
+// spans, from 0 for none to 1, the default, for every record. Records are
+// sampled by their position in the input, so a rate of 0.01 traces every
+// hundredth record.
+func WithRecordSpanRate(rate float64) ETLProcessorOption {
+	return func(s *settings) {
+		switch {
+		case rate <= 0:
+			s.recordSpanEvery = 0
+		case rate >= 1:
+			s.recordSpanEvery = 1
+		default:
+			s.recordSpanEvery = uint64(math.Round(1 / rate))
+		}
+	}
+}
+
+func newNoopTracer() trace.Tracer {
+	return noop.NewTracerProvider().Tracer(tracerName)
+}
+
+// traced reports whether a record gets its own spans.
+func (s *settings) traced(seq uint64) bool {
+	return s.recordSpanEvery > 0 && seq%s.recordSpanEvery == 0
+}
+
+// startRecordSpan starts a span for a stage of a sampled record, otherwise
+// it returns ctx unchanged and a span that records nothing.
+func (e *Processor[In, Ext, Out]) startRecordSpan(ctx context.Context, name string, b *branch[In, Ext, Out], rec record[In]) (context.Context, trace.Span) {
+	if !e.traced(rec.seq) {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+
+	return e.tracer.Start(ctx, name, trace.WithAttributes(branchAttrs(b, attrRecordIndex.Int64(int64(rec.seq)))...))
+}
+
+// branchAttrs adds the branch name to attrs for spans of a named branch.
+func branchAttrs[In, Ext, Out any](b *branch[In, Ext, Out], attrs ...attribute.KeyValue) []attribute.KeyValue {
+	if b.name == "" {
+		return attrs
+	}
+
+	return append(attrs, attrBranch.String(b.name))
+}
+
+// endSpan marks the span as failed when err is set and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -2,6 +2,27 @@ package etl
 
 import "context"
 
-type Transformer interface {
-	Transform(ctx context.Context, data map[string]interface{}, fields []string) ([][]string, error)
+// TransformerOf turns an extracted record of type In into rows of type
+// Out, of which there may be none or several.
+type TransformerOf[In, Out any] interface {
+	Transform(ctx context.Context, data In, fields []string) ([]Out, error)
 }
+
+// Transformer turns an extracted map into rows of strings.
+type Transformer = TransformerOf[map[string]interface{}, []string]
+
+// ColumnMapper is implemented by transformers whose output columns differ
+// from the requested fields, e.g. when one field is split into several.
+type ColumnMapper interface {
+	Columns(fields []string) []string
+}
+
+// FlusherOf is implemented by transformers that hold rows back until the
+// end of the stream, such as aggregations. Flush hands the remaining rows
+// to emit, possibly over several calls.
+type FlusherOf[Out any] interface {
+	Flush(ctx context.Context, emit func([]Out) error) error
+}
+
+// Flusher is a FlusherOf rows of strings.
+type Flusher = FlusherOf[[]string]
@@ -0,0 +1,39 @@
+//go:build unit
+
+package extractors_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/ralucas/centipede/pkg/extractors"
+	"github.com/ralucas/centipede/pkg/sinks"
+	"github.com/ralucas/centipede/pkg/sources"
+)
+
+func ExampleDiscover() {
+	si := sources.JSON(strings.NewReader(`[
+		{"title": "Air Quality", "publisher": {"name": "EPA", "subOrganizationOf": {"name": "US Gov"}}},
+		{"title": "Water Use", "publisher": {"name": "USGS"}}
+	]`))
+
+	patterns := []string{"publisher.**"}
+	fields, si := extractors.Discover(si, patterns, 0)
+
+	_, err := etl.New(etl.WithWorkers(1)).
+		From(si).
+		Extract(extractors.Flatten(patterns)).
+		Select(fields...).
+		To(sinks.CSV(os.Stdout)).
+		Run(context.Background())
+	if err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// publisher.name,publisher.subOrganizationOf.name
+	// EPA,US Gov
+	// USGS,
+}
@@ -0,0 +1,109 @@
+// Package extractors picks the selected fields out of records.
+package extractors
+
+import (
+	"context"
+
+	"github.com/ralucas/centipede/internal/extractor"
+	"github.com/ralucas/centipede/internal/flatten"
+	"github.com/ralucas/centipede/internal/streamreader"
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+)
+
+// LateColumnPolicy decides what happens to leaf paths a flatten extractor
+// wasn't configured with.
+type LateColumnPolicy = extractor.LateColumnPolicy
+
+const (
+	// LateColumnDrop drops the values and warns once per path.
+	LateColumnDrop = extractor.LateColumnDrop
+	// LateColumnFail fails the record with ErrUnknownColumn.
+	LateColumnFail = extractor.LateColumnFail
+)
+
+// ErrUnknownColumn is the error of records with a late column under
+// LateColumnFail.
+var ErrUnknownColumn = extractor.ErrUnknownColumn
+
+// Option configures an extractor.
+type Option func(*options)
+
+type options struct {
+	logger      *zap.Logger
+	lateColumns LateColumnPolicy
+}
+
+// WithLogger sets the extractor's logger, which defaults to a no-op logger.
+func WithLogger(log *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = log
+	}
+}
+
+// WithLateColumns sets the late column policy of Flatten, which defaults
+// to LateColumnDrop.
+func WithLateColumns(policy LateColumnPolicy) Option {
+	return func(o *options) {
+		o.lateColumns = policy
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{logger: zap.NewNop()}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// Map extracts fields by their dotted paths, such as publisher.name. Array
+// values are kept whole and expanded into rows by the rows transformer.
+func Map(opts ...Option) etl.Extractor {
+	return extractor.NewMapExtractor(newOptions(opts).logger)
+}
+
+// Identity hands each record on whole, for pipelines whose transformer
+// reads typed records directly.
+func Identity[T any]() etl.ExtractorOf[T, T] {
+	return etl.ExtractorFuncOf[T, T](func(_ context.Context, data T, _ []string) (T, error) {
+		return data, nil
+	})
+}
+
+// Flatten extracts the leaf paths matching patterns, glob patterns such as
+// '*' or 'publisher.**' with '!' excluding. The fields selected are leaf
+// paths such as distribution.format, which Discover finds in a source.
+func Flatten(patterns []string, opts ...Option) etl.Extractor {
+	o := newOptions(opts)
+
+	fopts := []extractor.FlattenExtractorOption{extractor.WithFilter(flatten.NewFilter(patterns))}
+	if o.lateColumns != "" {
+		fopts = append(fopts, extractor.WithLateColumnPolicy(o.lateColumns))
+	}
+
+	return extractor.NewFlattenExtractor(o.logger, fopts...)
+}
+
+// DefaultSample is the number of records Discover reads when sample is not
+// positive.
+const DefaultSample = 1000
+
+// Discover returns the sorted leaf paths matching patterns among the first
+// sample records of si, and an iterator replaying si from its first record.
+// Paths first seen past the sample are late columns.
+func Discover(si etl.StreamIterator, patterns []string, sample int) ([]string, etl.StreamIterator) {
+	if sample <= 0 {
+		sample = DefaultSample
+	}
+
+	d := flatten.NewDiscoverer(flatten.NewFilter(patterns))
+
+	bi := streamreader.NewBufferedIterator(si)
+	for _, obj := range bi.Fill(sample) {
+		d.Add(obj)
+	}
+
+	return d.Fields(), bi
+}
@@ -0,0 +1,46 @@
+//go:build unit
+
+package sinks_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/ralucas/centipede/pkg/sinks"
+	"github.com/ralucas/centipede/pkg/sources"
+)
+
+func ExampleRejects() {
+	var rejects bytes.Buffer
+
+	report, err := etl.New(
+		etl.WithWorkers(1),
+		etl.WithErrorPolicy(etl.ErrorPolicy{Mode: etl.ErrorModeSkip}),
+	).
+		From(sources.JSON(strings.NewReader(`[{"title": "Air Quality"}]`), sources.WithValidation())).
+		Select("title").
+		To(sinks.CSV(os.Stdout)).
+		Reject(sinks.Rejects(&rejects)).
+		Run(context.Background())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(report.Failed, "rejected")
+
+	replayed, err := sources.Rejects(&rejects).Next()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(replayed["title"])
+	// Output:
+	// title
+	// 1 rejected
+	// Air Quality
+}
@@ -0,0 +1,57 @@
+// Package sinks writes the rows and rejected records of a pipeline.
+package sinks
+
+import (
+	"io"
+
+	"github.com/ralucas/centipede/internal/loader"
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+)
+
+// Option configures a sink.
+type Option func(*options)
+
+type options struct {
+	logger *zap.Logger
+}
+
+// WithLogger sets the sink's logger, which defaults to a no-op logger.
+func WithLogger(log *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = log
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{logger: zap.NewNop()}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// CSV writes rows to w as CSV, starting with a header of the columns.
+func CSV(w io.Writer, opts ...Option) etl.Sink {
+	return etl.Sink{
+		Loader: loader.NewCSVLoader(newOptions(opts).logger),
+		Output: w,
+	}
+}
+
+// JSONLines writes each row to w as a line of NDJSON, for pipelines
+// transforming records into rows of their own type.
+func JSONLines[T any](w io.Writer, opts ...Option) etl.SinkOf[T] {
+	return etl.SinkOf[T]{
+		Loader: loader.NewJSONLinesLoader[T](newOptions(opts).logger),
+		Output: w,
+	}
+}
+
+// Rejects writes each record failing the pipeline to w as a line of NDJSON,
+// which sources.Rejects replays. It is set with etl.Builder.Reject or a
+// processor's WithRejecter.
+func Rejects(w io.Writer, opts ...Option) etl.Rejecter {
+	return loader.NewRejectWriter(w, newOptions(opts).logger)
+}
@@ -0,0 +1,40 @@
+//go:build unit
+
+package sources_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/ralucas/centipede/pkg/sources"
+)
+
+func ExampleJSON() {
+	si := sources.JSON(strings.NewReader(`[{"title": "Air Quality"}, {"title": "Water Use"}]`))
+
+	for si.HasNext() {
+		record, err := si.Next()
+		if errors.Is(err, etl.Done) {
+			break
+		}
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Println(record["title"])
+	}
+	// Output:
+	// Air Quality
+	// Water Use
+}
+
+func ExampleWithValidation() {
+	si := sources.JSON(strings.NewReader(`[{"title": "Air Quality"}]`), sources.WithValidation())
+
+	_, err := si.Next()
+	fmt.Println(errors.Is(err, sources.ErrInvalidDataset))
+	// Output:
+	// true
+}
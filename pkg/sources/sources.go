@@ -0,0 +1,121 @@
+// Package sources reads the records of a pipeline from DCAT-US data.json
+// documents and reject files.
+package sources
+
+import (
+	"io"
+
+	"github.com/ralucas/centipede/internal/schema"
+	"github.com/ralucas/centipede/internal/streamreader"
+	"github.com/ralucas/centipede/internal/streamreader/custom"
+	"github.com/ralucas/centipede/pkg/etl"
+	"go.uber.org/zap"
+
+	// register the map extractor and rows transformer, e.g. for etl.TransformerStep("rows", nil)
+	_ "github.com/ralucas/centipede/internal/extractor"
+	_ "github.com/ralucas/centipede/internal/transformer"
+)
+
+// Option configures a source.
+type Option func(*options)
+
+type options struct {
+	logger    *zap.Logger
+	validate  bool
+	chunkSize int
+}
+
+// WithLogger sets the source's logger, which defaults to a no-op logger.
+func WithLogger(log *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = log
+	}
+}
+
+// WithValidation checks every record against the DCAT-US dataset schema.
+// Invalid records fail with an error matching ErrInvalidDataset.
+func WithValidation() Option {
+	return func(o *options) {
+		o.validate = true
+	}
+}
+
+// WithChunkSize sets the number of bytes Custom reads at a time.
+func WithChunkSize(size int) Option {
+	return func(o *options) {
+		o.chunkSize = size
+	}
+}
+
+var (
+	// ErrInvalidDataset matches the errors of records failing validation.
+	ErrInvalidDataset = streamreader.ErrInvalidDatasetJSON
+	// ErrInvalidRecord matches the errors of objects Decode can't decode
+	// into its record type.
+	ErrInvalidRecord = streamreader.ErrInvalidRecord
+)
+
+// Dataset is a DCAT-US dataset. Decoding into it checks the fields the
+// schema requires.
+type Dataset = schema.DatasetJson
+
+func newOptions(opts []Option) *options {
+	o := &options{logger: zap.NewNop()}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// JSON reads the datasets of a JSON array with the standard decoder.
+func JSON(r io.Reader, opts ...Option) etl.StreamIterator {
+	o := newOptions(opts)
+
+	var jopts []streamreader.JSONStreamIteratorOption
+	if o.validate {
+		jopts = append(jopts, streamreader.WithDatasetValidation())
+	}
+
+	return streamreader.NewJSONStreamIterator(r, o.logger, jopts...)
+}
+
+// Decode reads the objects of a JSON array into T. Objects that don't
+// decode into T fail with ErrInvalidRecord, the stream carrying on past
+// them. WithValidation only applies to JSON.
+func Decode[T any](r io.Reader, opts ...Option) etl.StreamIteratorOf[T] {
+	return streamreader.NewJSONIterator[T](r, newOptions(opts).logger)
+}
+
+// Datasets reads the objects of a JSON array as Datasets.
+func Datasets(r io.Reader, opts ...Option) etl.StreamIteratorOf[Dataset] {
+	return Decode[Dataset](r, opts...)
+}
+
+// Custom reads the datasets of a JSON array with the hand-written chunked
+// parser.
+func Custom(r io.Reader, opts ...Option) etl.StreamIterator {
+	o := newOptions(opts)
+
+	var copts []custom.JSONStreamReadIteratorOption
+	if o.validate {
+		copts = append(copts, custom.WithDatasetValidation())
+	}
+	if o.chunkSize > 0 {
+		copts = append(copts, custom.WithChunkSize(o.chunkSize))
+	}
+
+	return custom.NewCustomJSONStreamReadIterator(r, o.logger, copts...)
+}
+
+// Rejects replays the records of a reject file written by sinks.Rejects.
+func Rejects(r io.Reader, opts ...Option) etl.StreamIterator {
+	o := newOptions(opts)
+
+	var ropts []streamreader.RejectIteratorOption
+	if o.validate {
+		ropts = append(ropts, streamreader.WithRejectValidation())
+	}
+
+	return streamreader.NewRejectIterator(r, o.logger, ropts...)
+}
@@ -0,0 +1,44 @@
+//go:build unit
+
+package transforms_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ralucas/centipede/pkg/etl"
+	"github.com/ralucas/centipede/pkg/sinks"
+	"github.com/ralucas/centipede/pkg/sources"
+	"github.com/ralucas/centipede/pkg/transforms"
+)
+
+func ExampleAggregate() {
+	groupBy := []string{"publisher.name"}
+	aggregations := []string{"count", "max(modified)"}
+
+	fields, err := transforms.AggregateFields(groupBy, aggregations)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	_, err = etl.New().
+		From(sources.JSON(strings.NewReader(`[
+			{"publisher": {"name": "EPA"}, "modified": "2019-06-12"},
+			{"publisher": {"name": "EPA"}, "modified": "2021-03-30"},
+			{"publisher": {"name": "USGS"}, "modified": "2020-01-01"}
+		]`))).
+		Select(fields...).
+		Transform(transforms.Aggregate(groupBy, aggregations)).
+		To(sinks.CSV(os.Stdout)).
+		Run(context.Background())
+	if err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// publisher.name,count,max(modified)
+	// EPA,2,2021-03-30
+	// USGS,1,2020-01-01
+}
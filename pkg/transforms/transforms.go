@@ -0,0 +1,131 @@
+// Package transforms provides the steps of a transformer chain, turning
+// extracted records into output rows.
+package transforms
+
+import (
+	"github.com/ralucas/centipede/internal/aggregate"
+	"github.com/ralucas/centipede/internal/transformer"
+	"github.com/ralucas/centipede/pkg/etl"
+)
+
+// Option configures a transform. Each transform uses the options relevant
+// to it and ignores the others.
+type Option func(*options)
+
+type options struct {
+	layout    string
+	timezone  string
+	memoryMiB int
+	dir       string
+	maxGroups int
+}
+
+// WithLayout sets the time layout NormalizeDates formats dates with, which
+// defaults to RFC 3339.
+func WithLayout(layout string) Option {
+	return func(o *options) {
+		o.layout = layout
+	}
+}
+
+// WithTimezone sets the IANA time zone NormalizeDates converts dates to,
+// which defaults to UTC.
+func WithTimezone(name string) Option {
+	return func(o *options) {
+		o.timezone = name
+	}
+}
+
+// WithMemoryMiB sets the memory Sort buffers rows in before spilling them
+// to disk, which defaults to 256 MiB.
+func WithMemoryMiB(n int) Option {
+	return func(o *options) {
+		o.memoryMiB = n
+	}
+}
+
+// WithSpillDir sets the directory Sort and Aggregate spill to, which
+// defaults to the system temporary directory.
+func WithSpillDir(dir string) Option {
+	return func(o *options) {
+		o.dir = dir
+	}
+}
+
+// WithMaxGroups sets the number of groups Aggregate holds in memory before
+// spilling partial results, which defaults to 100000.
+func WithMaxGroups(n int) Option {
+	return func(o *options) {
+		o.maxGroups = n
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// Rows expands each record into rows, one per combination of the values
+// of its array fields. It starts a chain, and is the first step of any
+// chain not starting with Rows or Aggregate.
+func Rows() etl.Step {
+	return etl.TransformerStep("rows", nil)
+}
+
+// NormalizeDates rewrites the dates, intervals and periodicities of
+// DCAT-US fields into a single layout and time zone.
+func NormalizeDates(opts ...Option) etl.Step {
+	o := newOptions(opts)
+
+	return etl.TransformerStep("normalize_dates", transformer.DateConfig{
+		Layout:   o.layout,
+		Timezone: o.timezone,
+	})
+}
+
+// Sort orders all rows by the columns of by, such as modified:desc or
+// title:asc, spilling to disk when they don't fit in memory. Rows are
+// written once the source is read.
+func Sort(by []string, opts ...Option) etl.Step {
+	o := newOptions(opts)
+
+	return etl.TransformerStep("sort", transformer.SortConfig{
+		By:        by,
+		MemoryMiB: o.memoryMiB,
+		Dir:       o.dir,
+	})
+}
+
+// Aggregate groups records by the groupBy fields and computes aggregations
+// such as count, count_distinct(keyword) or max(modified), which default
+// to count. It must start a chain, and the pipeline must select the fields
+// AggregateFields returns.
+func Aggregate(groupBy, aggregations []string, opts ...Option) etl.Step {
+	o := newOptions(opts)
+
+	return etl.TransformerStep("aggregate", aggregate.Config{
+		GroupBy:      groupBy,
+		Aggregations: aggregations,
+		MaxGroups:    o.maxGroups,
+		Dir:          o.dir,
+	})
+}
+
+// AggregateFields returns the fields Aggregate reads for groupBy and
+// aggregations.
+func AggregateFields(groupBy, aggregations []string) ([]string, error) {
+	if len(aggregations) == 0 {
+		aggregations = []string{"count"}
+	}
+
+	aggs, err := aggregate.ParseAggregations(aggregations)
+	if err != nil {
+		return nil, err
+	}
+
+	return aggregate.Fields(groupBy, aggs), nil
+}